@@ -0,0 +1,40 @@
+package project
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// auditMu serializes the writes to the AuditLog writers, which may be
+// shared by concurrent resolutions.
+var auditMu sync.Mutex
+
+// auditEntry is the JSON line written to the AuditLog for a resolution.
+type auditEntry struct {
+	Time     time.Time `json:"ts"`
+	ID       string    `json:"id"`
+	Source   string    `json:"source"`
+	Duration string    `json:"duration"`
+}
+
+// writeAudit writes the audit line of r, a resolution started at start
+// that took d, to w.
+func writeAudit(w io.Writer, start time.Time, d time.Duration, r Result) {
+	e := auditEntry{
+		Time:     start.UTC(),
+		ID:       r.ID,
+		Source:   r.Source,
+		Duration: d.String(),
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,113 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestID_AuditLog(t *testing.T) {
+	t.Run("JSON line", func(t *testing.T) {
+		clock := useCache(t)
+		start := *clock
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{&clockSearcherMock{
+				source: "env", id: "gcp-id-test", clock: clock, d: 1200 * time.Microsecond,
+			}}
+		})
+		var log bytes.Buffer
+
+		ID(Options{Timeout: time.Second, AuditLog: &log})
+
+		ts, err := start.UTC().MarshalJSON()
+		require.NoError(t, err)
+		want := `{"ts":` + string(ts) + `,"id":"gcp-id-test",` +
+			`"source":"env","duration":"1.2ms"}` + "\n"
+		assert.Equal(t, want, log.String())
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		useCache(t)
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("credentials", "gcp-id-test")}
+		})
+		var log bytes.Buffer
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute, AuditLog: &log}
+
+		ID(opts)
+		ID(opts)
+
+		lines := auditLines(t, log.String())
+		require.Len(t, lines, 2)
+		for _, e := range lines {
+			assert.Equal(t, "gcp-id-test", e.ID)
+			assert.Equal(t, "credentials", e.Source)
+		}
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "")}
+		})
+		var log bytes.Buffer
+
+		ID(Options{Timeout: time.Second, AuditLog: &log})
+
+		assert.Empty(t, log.String())
+	})
+
+	t.Run("Search error", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newSearcherMock(false, true)}
+		})
+		var log bytes.Buffer
+
+		_, err := Resolve(context.Background(), Options{Timeout: time.Second, AuditLog: &log})
+
+		require.Error(t, err)
+		assert.Empty(t, log.String())
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "gcp-id-test")}
+		})
+		var log bytes.Buffer // Not safe for concurrent use on its own.
+		opts := Options{Timeout: time.Second, AuditLog: &log}
+
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ID(opts)
+			}()
+		}
+		wg.Wait()
+
+		lines := auditLines(t, log.String())
+		assert.Len(t, lines, 20)
+	})
+}
+
+// auditLines decodes the JSON lines of the audit log.
+func auditLines(t *testing.T, log string) []auditEntry {
+	t.Helper()
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSuffix(log, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var e auditEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &e), "line %q", line)
+		entries = append(entries, e)
+	}
+	return entries
+}
@@ -0,0 +1,72 @@
+package project
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2/google"
+)
+
+// envHitAllocBudget is the allocation target for ID when an environment
+// variable answers: the timeout context, the searcher chain and the
+// returned string. Raise it only deliberately.
+const envHitAllocBudget = 10
+
+func BenchmarkIDEnvHit(b *testing.B) {
+	unsetEnv(b, gcloudProjectPropertyKey)
+	b.Setenv("GCP_PROJECT", "gcp-id-test")
+	opts := Options{Timeout: time.Second}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ID(opts)
+	}
+}
+
+func BenchmarkIDColdGCloud(b *testing.B) {
+	unsetEnv(b, gcloudProjectPropertyKey)
+	unsetEnv(b, defaultEnvKeys...)
+	useSearchers(b, func(o Options) []Searcher {
+		return []Searcher{
+			newEnvironmentSearcher(envKeys(o)...),
+			newCredentialsSearcher(func(context.Context, ...string) (
+				*google.Credentials, error,
+			) {
+				return &google.Credentials{}, nil
+			}, ""),
+			&gcloudSearcher{
+				executables: []string{"gcloud"},
+				output: func(*exec.Cmd, int) ([]byte, error) {
+					time.Sleep(time.Millisecond)
+					return []byte("gcp-id-test\n"), nil
+				},
+			},
+		}
+	})
+	opts := Options{Timeout: time.Second}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ID(opts)
+	}
+}
+
+func TestID_EnvHitAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds allocations")
+	}
+	unsetEnv(t, gcloudProjectPropertyKey)
+	t.Setenv("GCP_PROJECT", "gcp-id-test")
+	opts := Options{Timeout: time.Second}
+
+	allocs := testing.AllocsPerRun(100, func() { _ = ID(opts) })
+
+	if allocs > envHitAllocBudget {
+		t.Errorf("ID allocates %v times on an env hit, budget is %v",
+			allocs, envHitAllocBudget)
+	}
+}
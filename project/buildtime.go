@@ -0,0 +1,27 @@
+package project
+
+import "context"
+
+// BuildTimeProjectID is the project ID baked into the binary at build time,
+// searched when the UseBuildTimeID option is set. It's meant to be set with
+// the linker:
+//
+//	go build -ldflags "-X github.com/lucmq/gcp-project-id/project.BuildTimeProjectID=my-project"
+//
+// It must not be changed at run time.
+var BuildTimeProjectID string
+
+// Build Time Searcher
+
+// buildTimeSearcher returns BuildTimeProjectID.
+type buildTimeSearcher struct{}
+
+var _ Searcher = (*buildTimeSearcher)(nil)
+
+func newBuildTimeSearcher() *buildTimeSearcher { return &buildTimeSearcher{} }
+
+func (*buildTimeSearcher) Source() string { return "build-time" }
+
+func (*buildTimeSearcher) ProjectID(context.Context, ...string) (string, error) {
+	return sanitizeValue(BuildTimeProjectID), nil
+}
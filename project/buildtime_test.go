@@ -0,0 +1,78 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_buildTimeSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Set", value: "gcp-id-test", want: "gcp-id-test"},
+		{name: "Unset", value: "", want: ""},
+		{name: "Not a project ID", value: "gcp id test", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &BuildTimeProjectID, tt.value)
+
+			got, err := newBuildTimeSearcher().ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve_UseBuildTimeID(t *testing.T) {
+	tests := []struct {
+		name       string
+		useOption  bool
+		wantSource string
+	}{
+		{name: "Opted in", useOption: true, wantSource: "build-time"},
+		{name: "Option disabled", useOption: false, wantSource: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &BuildTimeProjectID, "gcp-id-test")
+			opts := Options{Timeout: time.Second, UseBuildTimeID: tt.useOption}
+
+			var sources []string
+			for _, s := range DefaultSearchers(opts) {
+				sources = append(sources, sourceOf(s))
+			}
+
+			if tt.wantSource == "" {
+				assert.NotContains(t, sources, "build-time")
+				return
+			}
+			// After the environment, so deployments can still override it.
+			assert.Equal(t, []string{
+				"gcloud-property", "env", "cloud-build", "build-time",
+			}, sources[:4])
+		})
+	}
+
+	t.Run("Resolved", func(t *testing.T) {
+		unsetEnv(t, gcloudProjectPropertyKey)
+		unsetEnv(t, defaultEnvKeys...)
+		replace(t, &BuildTimeProjectID, "gcp-id-test")
+
+		r, err := Resolve(context.Background(), Options{
+			Timeout:        time.Second,
+			UseBuildTimeID: true,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", r.ID)
+		assert.Equal(t, "build-time", r.Source)
+	})
+}
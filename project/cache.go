@@ -0,0 +1,178 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Seams for the cache.
+var (
+	now   = time.Now
+	stat  = os.Stat
+	lstat = os.Lstat
+)
+
+// cache holds the last project ID found when the CacheTTL option is set. It
+// is shared by all calls in the process.
+var cache resolutionCache
+
+type resolutionCache struct {
+	mu    sync.Mutex
+	entry *cacheEntry
+}
+
+type cacheEntry struct {
+	// key identifies the options the entry was resolved with.
+	key     string
+	id      string
+	number  string
+	source  string
+	expires time.Time
+
+	// file is the file backing the source, if any, and modTime its
+	// modification time when the entry was stored.
+	file    string
+	modTime time.Time
+
+	// env is the envSnapshot when the entry was stored.
+	env string
+}
+
+// fileSource is implemented by searchers whose source is backed by a file.
+type fileSource interface {
+	// backingFile returns the path of the file the searcher reads the
+	// project ID from, or an empty string if it's not file based.
+	backingFile() string
+}
+
+// cacheKey identifies the options that change the project ID found, so
+// calls resolving differently, like with other EnvKeys, Searchers or
+// validation, don't share a cache entry. The Searchers are told by
+// instance, and the functions by their address.
+func cacheKey(o Options) string {
+	searchers := make([]string, len(o.Searchers))
+	for i, s := range o.Searchers {
+		searchers[i] = searcherKey(s)
+	}
+	return strings.Join([]string{
+		strings.Join(o.Scopes, " "),
+		strings.Join(envKeys(o), " "),
+		fmt.Sprint(o.EnvChain),
+		strings.Join(searchers, " "),
+		o.Explicit,
+		o.GCloudConfiguration,
+		o.GCloudAccount,
+		o.GCloudFormat,
+		o.YAMLConfigFile,
+		o.YAMLProjectPath,
+		o.DeployConfigFile,
+		o.DeployProjectPath,
+		o.EncryptedConfigFile,
+		o.CredentialName,
+		o.K8sTokenFile,
+		o.KCCAnnotationsFile,
+		o.CredentialsBase64Env,
+		o.UniverseDomain,
+		o.MetadataAttribute,
+		o.MetadataCacheFile,
+		o.SystemEnvFile,
+		strings.Join(o.CredentialHelper, " "),
+		strings.Join(o.Order, " "),
+		strings.Join(o.RejectValues, " "),
+		fmt.Sprint(o.Aliases),
+		fmt.Sprintf("%p %p %p %p %p %p %p", o.FindCredentials, o.PostResolve,
+			o.GCloudParse, o.RemoteConfig, o.Validator, o.YAMLUnmarshal,
+			o.Decryptor),
+		fmt.Sprint(o.Policy, o.UseBoto, o.UseBuildTimeID, o.NoSubprocess,
+			o.UseGCloudConfigHelper, o.UseSystemEnvFile, o.SecureFilesOnly,
+			o.VerifyAgainstCredentials, o.CredentialsAuthoritative,
+			o.UseMetadataCacheFile, o.StripDomain, o.StripResourcePrefix,
+			o.GCloudStrictParse, o.Validate),
+	}, "\x00")
+}
+
+// searcherKey identifies the searcher s: pointers by their address, as two
+// instances of a type may search differently, and values by their content.
+func searcherKey(s Searcher) string {
+	if reflect.ValueOf(s).Kind() == reflect.Pointer {
+		return fmt.Sprintf("%T@%p", s, s)
+	}
+	return fmt.Sprintf("%#v", s)
+}
+
+// get returns the cached result for the options, if there's a valid one.
+func (c *resolutionCache) get(o Options) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.entry
+	if e == nil || e.key != cacheKey(o) || !now().Before(e.expires) {
+		return Result{}, false
+	}
+	if o.InvalidateOnFileChange && e.file != "" && fileChanged(e) {
+		c.entry = nil
+		return Result{}, false
+	}
+	if o.WatchEnv && e.env != envSnapshot(o) {
+		c.entry = nil
+		return Result{}, false
+	}
+	return Result{ID: e.id, Number: e.number, Source: e.source, Found: true}, true
+}
+
+// put caches the result found by the searcher s.
+func (c *resolutionCache) put(o Options, r Result, s Searcher) {
+	e := cacheEntry{
+		key:     cacheKey(o),
+		id:      r.ID,
+		number:  r.Number,
+		source:  r.Source,
+		expires: now().Add(o.CacheTTL),
+		env:     envSnapshot(o),
+	}
+	if f, ok := s.(fileSource); ok {
+		if e.file = f.backingFile(); e.file != "" {
+			if info, err := stat(e.file); err == nil {
+				e.modTime = info.ModTime()
+			} else {
+				e.file = ""
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entry = &e
+}
+
+func (c *resolutionCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entry = nil
+}
+
+func fileChanged(e *cacheEntry) bool {
+	info, err := stat(e.file)
+	return err != nil || !info.ModTime().Equal(e.modTime)
+}
+
+// cloudBuildEnvKeys are the environment variables read by the Cloud Build
+// searcher.
+var cloudBuildEnvKeys = []string{"PROJECT_ID", "BUILD_ID", "PROJECT_NUMBER"}
+
+// envSnapshot returns the values of the environment variables searched for
+// the options, to tell when they change.
+func envSnapshot(o Options) string {
+	var b strings.Builder
+	for _, keys := range [][]string{gcloudPropertyKeys, envKeys(o), cloudBuildEnvKeys} {
+		for _, key := range keys {
+			b.WriteString(lookupEnv(key))
+			b.WriteByte(0)
+		}
+	}
+	return b.String()
+}
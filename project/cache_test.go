@@ -0,0 +1,272 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestID_Cache(t *testing.T) {
+	t.Run("Cached until the TTL expires", func(t *testing.T) {
+		clock := useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		assert.Equal(t, 1, s.calls)
+
+		*clock = clock.Add(time.Minute)
+
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		assert.Equal(t, 2, s.calls)
+	})
+
+	t.Run("Not cached without a TTL", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := Options{Timeout: time.Second}
+
+		ID(opts)
+		ID(opts)
+
+		assert.Equal(t, 2, s.calls)
+	})
+
+	t.Run("Different scopes", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+		ID(opts)
+		opts.Scopes = []string{"scope-a"}
+		ID(opts)
+
+		assert.Equal(t, 2, s.calls)
+	})
+
+	t.Run("Different env keys", func(t *testing.T) {
+		useCache(t)
+		unsetEnv(t, gcloudProjectPropertyKey)
+		t.Setenv("__GCP_PROJECT_ID_TEST_A__", "gcp-id-a")
+		t.Setenv("__GCP_PROJECT_ID_TEST_B__", "gcp-id-b")
+		useSearchers(t, func(o Options) []Searcher {
+			return []Searcher{envSearcher(o)}
+		})
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+		opts.EnvKeys = []string{"__GCP_PROJECT_ID_TEST_A__"}
+		assert.Equal(t, "gcp-id-a", ID(opts))
+		opts.EnvKeys = []string{"__GCP_PROJECT_ID_TEST_B__"}
+		assert.Equal(t, "gcp-id-b", ID(opts))
+		opts.EnvKeys = []string{"__GCP_PROJECT_ID_TEST_A__"}
+		assert.Equal(t, "gcp-id-a", ID(opts))
+	})
+
+	t.Run("Different searchers", func(t *testing.T) {
+		useCache(t)
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+		opts.Searchers = []Searcher{newNamedSearcherMock("a", "gcp-id-a")}
+		assert.Equal(t, "gcp-id-a", ID(opts))
+		opts.Searchers = []Searcher{newNamedSearcherMock("b", "gcp-id-b")}
+		assert.Equal(t, "gcp-id-b", ID(opts))
+	})
+
+	t.Run("Different searchers of a source", func(t *testing.T) {
+		useCache(t)
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+		opts.Searchers = []Searcher{newNamedSearcherMock("a", "gcp-id-a")}
+		assert.Equal(t, "gcp-id-a", ID(opts))
+		opts.Searchers = []Searcher{newNamedSearcherMock("a", "gcp-id-b")}
+		assert.Equal(t, "gcp-id-b", ID(opts))
+	})
+
+	t.Run("Same searchers", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		opts := Options{
+			Timeout:   time.Second,
+			CacheTTL:  time.Minute,
+			Searchers: []Searcher{s},
+		}
+
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		assert.Equal(t, 1, s.calls)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		useCache(t)
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("a", "Bad_ID")}
+		})
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+		assert.Equal(t, "Bad_ID", ID(opts))
+
+		validate := opts
+		validate.Validate = true
+		_, err := IDContext(context.Background(), validate)
+		assert.Error(t, err)
+
+		validator := opts
+		validator.Validator = func(string) error { return errors.New("test error") }
+		_, err = IDContext(context.Background(), validator)
+		assert.Error(t, err)
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{err: errors.New("test error")}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+		_, err := IDContext(context.Background(), opts)
+		require.Error(t, err)
+		_, err = IDContext(context.Background(), opts)
+		require.Error(t, err)
+
+		assert.Equal(t, 2, s.calls)
+	})
+
+	t.Run("Source is kept", func(t *testing.T) {
+		useCache(t)
+		t.Setenv("__GCP_PROJECT_ID_TEST__", "gcp-id-test")
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__")}
+		})
+		opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+		<-IDAsync(opts)
+		got := <-IDAsync(opts)
+
+		assert.Equal(t, Result{ID: "gcp-id-test", Source: "env", Found: true}, got)
+	})
+}
+
+func TestID_CacheInvalidateOnFileChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		option    bool
+		change    bool
+		wantCalls int
+	}{
+		{name: "File changed", option: true, change: true, wantCalls: 2},
+		{name: "File unchanged", option: true, change: false, wantCalls: 1},
+		{name: "Option disabled", option: false, change: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCache(t)
+			file := filepath.Join(t.TempDir(), "credentials.json")
+			require.NoError(t, os.WriteFile(file, []byte("{}"), 0o600))
+			s := &countingSearcherMock{projectID: "gcp-id-test", file: file}
+			useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+			opts := Options{
+				Timeout:                time.Second,
+				CacheTTL:               time.Hour,
+				InvalidateOnFileChange: tt.option,
+			}
+
+			ID(opts)
+			if tt.change {
+				mtime := time.Now().Add(time.Minute)
+				require.NoError(t, os.Chtimes(file, mtime, mtime))
+			}
+			ID(opts)
+
+			assert.Equal(t, tt.wantCalls, s.calls)
+		})
+	}
+}
+
+func TestID_CacheWatchEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		watchEnv  bool
+		key       string
+		wantCalls int
+	}{
+		{name: "Env changed", watchEnv: true, key: "GOOGLE_CLOUD_PROJECT", wantCalls: 2},
+		{name: "Custom key changed", watchEnv: true, key: "__GCP_PROJECT_ID_TEST__", wantCalls: 2},
+		{name: "Other env changed", watchEnv: true, key: "__NOT_SEARCHED__", wantCalls: 1},
+		{name: "Option disabled", watchEnv: false, key: "GOOGLE_CLOUD_PROJECT", wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCache(t)
+			unsetEnv(t, tt.key)
+			s := &countingSearcherMock{projectID: "gcp-id-test"}
+			useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+			opts := Options{
+				Timeout:  time.Second,
+				CacheTTL: time.Minute,
+				EnvKeys:  []string{"GOOGLE_CLOUD_PROJECT", "__GCP_PROJECT_ID_TEST__"},
+				WatchEnv: tt.watchEnv,
+			}
+
+			ID(opts)
+			ID(opts)
+			t.Setenv(tt.key, "gcp-id-changed")
+			ID(opts)
+			ID(opts)
+
+			assert.Equal(t, tt.wantCalls, s.calls)
+		})
+	}
+}
+
+func Test_credentialsFile(t *testing.T) {
+	t.Run("GOOGLE_APPLICATION_CREDENTIALS", func(t *testing.T) {
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/secrets/sa.json")
+
+		assert.Equal(t, "/secrets/sa.json", credentialsFile())
+	})
+
+	t.Run("gcloud config directory", func(t *testing.T) {
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+		t.Setenv("CLOUDSDK_CONFIG", "/gcloud")
+
+		want := filepath.Join("/gcloud", "application_default_credentials.json")
+		assert.Equal(t, want, credentialsFile())
+	})
+}
+
+// useCache clears the cache for the duration of the test and makes it use
+// the returned clock.
+func useCache(t *testing.T) *time.Time {
+	t.Helper()
+	clock := time.Now()
+	replace(t, &now, func() time.Time { return clock })
+	cache.clear()
+	t.Cleanup(cache.clear)
+	return &clock
+}
+
+type countingSearcherMock struct {
+	projectID string
+	err       error
+	file      string
+	calls     int
+}
+
+var _ Searcher = (*countingSearcherMock)(nil)
+
+func (s *countingSearcherMock) ProjectID(context.Context, ...string) (
+	string, error,
+) {
+	s.calls++
+	return s.projectID, s.err
+}
+
+func (s *countingSearcherMock) backingFile() string { return s.file }
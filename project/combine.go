@@ -0,0 +1,88 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// FirstOf returns a Searcher that runs the searchers in order and returns
+// the first non-empty project ID. It stops at the first error, without
+// running the remaining searchers.
+func FirstOf(searchers ...Searcher) Searcher {
+	s := firstOfSearcher{
+		searchers: searchers,
+	}
+	return &s
+}
+
+type firstOfSearcher struct {
+	searchers []Searcher
+}
+
+var _ Searcher = (*firstOfSearcher)(nil)
+
+func (s *firstOfSearcher) Source() string {
+	return compositeSource("first-of", s.searchers...)
+}
+
+func (s *firstOfSearcher) ProjectID(
+	ctx context.Context, scopes ...string,
+) (string, error) {
+	for _, ss := range s.searchers {
+		id, err := ss.ProjectID(ctx, scopes...)
+		if err != nil {
+			return "", err
+		}
+		if id != "" {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// Fallback returns a Searcher that runs primary and, if it fails or finds
+// nothing, secondary. Errors of primary are only returned, joined with the
+// error of secondary, when secondary also fails.
+func Fallback(primary, secondary Searcher) Searcher {
+	s := fallbackSearcher{
+		primary:   primary,
+		secondary: secondary,
+	}
+	return &s
+}
+
+type fallbackSearcher struct {
+	primary   Searcher
+	secondary Searcher
+}
+
+var _ Searcher = (*fallbackSearcher)(nil)
+
+func (s *fallbackSearcher) Source() string {
+	return compositeSource("fallback", s.primary, s.secondary)
+}
+
+func (s *fallbackSearcher) ProjectID(
+	ctx context.Context, scopes ...string,
+) (string, error) {
+	id, primaryErr := s.primary.ProjectID(ctx, scopes...)
+	if primaryErr == nil && id != "" {
+		return id, nil
+	}
+	id, err := s.secondary.ProjectID(ctx, scopes...)
+	if err != nil {
+		return "", errors.Join(primaryErr, err)
+	}
+	return id, nil
+}
+
+// compositeSource names a combinator after the sources it combines, like
+// "first-of(env, gcloud)".
+func compositeSource(name string, searchers ...Searcher) string {
+	sources := make([]string, len(searchers))
+	for i, s := range searchers {
+		sources[i] = sourceOf(s)
+	}
+	return name + "(" + strings.Join(sources, ", ") + ")"
+}
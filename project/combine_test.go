@@ -0,0 +1,172 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirstOf(t *testing.T) {
+	errOther := errors.New("other error")
+	tests := []struct {
+		name      string
+		searchers []*countingSearcherMock
+		want      string
+		wantErr   error
+		wantCalls []int
+	}{
+		{
+			name: "First non-empty wins",
+			searchers: []*countingSearcherMock{
+				{projectID: ""},
+				{projectID: "gcp-id-test"},
+				{projectID: "gcp-id-other"},
+			},
+			want:      "gcp-id-test",
+			wantCalls: []int{1, 1, 0},
+		},
+		{
+			name: "Nothing found",
+			searchers: []*countingSearcherMock{
+				{projectID: ""},
+				{projectID: ""},
+			},
+			want:      "",
+			wantCalls: []int{1, 1},
+		},
+		{
+			name: "Stops at the first error",
+			searchers: []*countingSearcherMock{
+				{err: errTest},
+				{err: errOther},
+				{projectID: "gcp-id-test"},
+			},
+			wantErr:   errTest,
+			wantCalls: []int{1, 0, 0},
+		},
+		{
+			name:      "Empty",
+			searchers: nil,
+			want:      "",
+			wantCalls: []int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := make([]Searcher, len(tt.searchers))
+			for i, s := range tt.searchers {
+				ss[i] = s
+			}
+
+			got, err := FirstOf(ss...).ProjectID(context.Background())
+
+			calls := make([]int, len(tt.searchers))
+			for i, s := range tt.searchers {
+				calls[i] = s.calls
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.NotErrorIs(t, err, errOther)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFallback(t *testing.T) {
+	errOther := errors.New("other error")
+	tests := []struct {
+		name          string
+		primary       *countingSearcherMock
+		secondary     *countingSearcherMock
+		want          string
+		wantErrs      []error
+		wantSecondary int
+	}{
+		{
+			name:          "Primary found",
+			primary:       &countingSearcherMock{projectID: "gcp-id-test"},
+			secondary:     &countingSearcherMock{projectID: "gcp-id-other"},
+			want:          "gcp-id-test",
+			wantSecondary: 0,
+		},
+		{
+			name:          "Primary empty",
+			primary:       &countingSearcherMock{projectID: ""},
+			secondary:     &countingSearcherMock{projectID: "gcp-id-other"},
+			want:          "gcp-id-other",
+			wantSecondary: 1,
+		},
+		{
+			name:          "Primary error is recovered",
+			primary:       &countingSearcherMock{err: errTest},
+			secondary:     &countingSearcherMock{projectID: "gcp-id-other"},
+			want:          "gcp-id-other",
+			wantSecondary: 1,
+		},
+		{
+			name:          "Both fail",
+			primary:       &countingSearcherMock{err: errTest},
+			secondary:     &countingSearcherMock{err: errOther},
+			wantErrs:      []error{errTest, errOther},
+			wantSecondary: 1,
+		},
+		{
+			name:          "Secondary error",
+			primary:       &countingSearcherMock{projectID: ""},
+			secondary:     &countingSearcherMock{err: errOther},
+			wantErrs:      []error{errOther},
+			wantSecondary: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Fallback(tt.primary, tt.secondary)
+
+			got, err := s.ProjectID(context.Background())
+
+			assert.Equal(t, 1, tt.primary.calls)
+			assert.Equal(t, tt.wantSecondary, tt.secondary.calls)
+			if tt.wantErrs != nil {
+				for _, want := range tt.wantErrs {
+					require.ErrorIs(t, err, want)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestID_Searchers(t *testing.T) {
+	useSearchers(t, func(Options) []Searcher {
+		t.Error("default searchers used")
+		return nil
+	})
+	opts := Options{
+		Timeout: time.Second,
+		Searchers: []Searcher{
+			Fallback(
+				&searcherMock{wantError: true},
+				FirstOf(
+					newNamedSearcherMock("env", ""),
+					newNamedSearcherMock("gcloud", "gcp-id-test"),
+				),
+			),
+		},
+	}
+
+	r := <-IDAsync(opts)
+
+	require.NoError(t, r.Err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "fallback(*project.searcherMock, first-of(env, gcloud))", r.Source)
+}
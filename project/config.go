@@ -0,0 +1,403 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Seams for file based searchers.
+var (
+	readFile = os.ReadFile
+	readDir  = os.ReadDir
+)
+
+// defaultYAMLProjectPath is the dotted path used when the YAMLProjectPath
+// option is empty.
+const defaultYAMLProjectPath = "gcp.project"
+
+// YAML Config Searcher
+
+type yamlSearcher struct {
+	file        string
+	projectPath string
+	unmarshal   func([]byte, any) error
+}
+
+var _ Searcher = (*yamlSearcher)(nil)
+
+func newYAMLSearcher(
+	file, projectPath string, unmarshal func([]byte, any) error,
+) *yamlSearcher {
+	if projectPath == "" {
+		projectPath = defaultYAMLProjectPath
+	}
+	s := yamlSearcher{
+		file:        file,
+		projectPath: projectPath,
+		unmarshal:   unmarshal,
+	}
+	return &s
+}
+
+func (*yamlSearcher) Source() string { return "yaml" }
+
+func (s *yamlSearcher) backingFile() string { return s.file }
+
+func (s *yamlSearcher) ProjectID(context.Context, ...string) (string, error) {
+	if s.unmarshal == nil {
+		return "", errors.New("read yaml config: YAMLUnmarshal not set")
+	}
+	b, err := readFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read yaml config: %w", err)
+	}
+	var doc any
+	if err = s.unmarshal(b, &doc); err != nil {
+		return "", fmt.Errorf("parse yaml config %s: %w", s.file, err)
+	}
+	id, err := lookupPath(doc, s.projectPath)
+	if err != nil {
+		return "", fmt.Errorf("yaml config %s: %w", s.file, err)
+	}
+	return id, nil
+}
+
+// Systemd Credentials Searcher
+
+// systemdSearcher reads the project ID from a systemd credential, exposed
+// to services as the file $CREDENTIALS_DIRECTORY/<name>.
+type systemdSearcher struct {
+	name string
+}
+
+var _ Searcher = (*systemdSearcher)(nil)
+
+func newSystemdSearcher(name string) *systemdSearcher {
+	s := systemdSearcher{
+		name: name,
+	}
+	return &s
+}
+
+func (*systemdSearcher) Source() string { return "systemd" }
+
+func (s *systemdSearcher) backingFile() string {
+	dir := getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, s.name)
+}
+
+func (s *systemdSearcher) ProjectID(context.Context, ...string) (string, error) {
+	file := s.backingFile()
+	if file == "" {
+		return "", nil
+	}
+	b, err := readFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read systemd credential: %w", err)
+	}
+	id := sanitizeValue(strings.TrimSpace(string(b)))
+	return id, nil
+}
+
+// Boto Config Searcher
+
+// botoSearcher reads the project ID from the `default_project_id` setting
+// of the [GSUtil] section of the boto configuration files, used by gsutil.
+type botoSearcher struct{}
+
+var _ Searcher = (*botoSearcher)(nil)
+
+func newBotoSearcher() *botoSearcher { return &botoSearcher{} }
+
+func (*botoSearcher) Source() string { return "boto" }
+
+// backingFile returns the last boto file, whose setting wins.
+func (*botoSearcher) backingFile() string {
+	files := botoFiles()
+	if len(files) == 0 {
+		return ""
+	}
+	return files[len(files)-1]
+}
+
+func (*botoSearcher) backingFiles() []string { return botoFiles() }
+
+func (*botoSearcher) ProjectID(context.Context, ...string) (string, error) {
+	// Like boto, read all files in order and let later ones override.
+	var id string
+	for _, file := range botoFiles() {
+		b, err := readFile(file)
+		if err != nil {
+			// Missing or unreadable files are skipped, as boto does.
+			continue
+		}
+		ini := parseINI(b)
+		if v := ini["gsutil"]["default_project_id"]; v != "" {
+			// Quotes are kept by the INI parser, but not meant as part of
+			// the value.
+			id = normalizeEnvValue(v)
+		}
+	}
+	return id, nil
+}
+
+// botoFiles returns the boto configuration files, honoring the BOTO_CONFIG
+// and BOTO_PATH overrides.
+func botoFiles() []string {
+	if f := getenv("BOTO_CONFIG"); f != "" {
+		return []string{f}
+	}
+	if p := getenv("BOTO_PATH"); p != "" {
+		return filepath.SplitList(p)
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".boto")}
+}
+
+// GCloud Config Searcher
+
+// gcloudConfigSearcher reads the `project` property of the [core] section
+// of a gcloud configuration file, as `gcloud config get-value project`
+// would, without running the CLI.
+type gcloudConfigSearcher struct {
+	configuration string
+}
+
+var _ Searcher = (*gcloudConfigSearcher)(nil)
+
+// defaultGCloudConfigSearcher is shared by the searches without a gcloud
+// configuration.
+var defaultGCloudConfigSearcher = &gcloudConfigSearcher{}
+
+func newGCloudConfigSearcher(configuration string) *gcloudConfigSearcher {
+	if configuration == "" {
+		return defaultGCloudConfigSearcher
+	}
+	s := gcloudConfigSearcher{
+		configuration: configuration,
+	}
+	return &s
+}
+
+func (*gcloudConfigSearcher) Source() string { return "gcloud-config" }
+
+func (s *gcloudConfigSearcher) backingFile() string {
+	dir := gcloudConfigDir()
+	name := gcloudConfigName(s.configuration)
+	if dir == "" || name == "" {
+		return ""
+	}
+	return filepath.Join(dir, "configurations", "config_"+name)
+}
+
+func (s *gcloudConfigSearcher) ProjectID(context.Context, ...string) (string, error) {
+	if getenv(impersonateServiceAccountKey) != "" {
+		// Leave it to the gcloud CLI, which runs impersonating.
+		return "", nil
+	}
+	file := s.backingFile()
+	if file == "" {
+		return "", nil
+	}
+	b, err := readFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read gcloud config: %w", err)
+	}
+	id := sanitizeValue(parseINI(b)["core"]["project"])
+	return id, nil
+}
+
+// GCloud Config Scan Searcher
+
+// gcloudConfigScanSearcher infers the gcloud configuration when none is
+// selected, neither with CLOUDSDK_ACTIVE_CONFIG_NAME nor with the
+// active_config file, and the default one has no project, as for users who
+// created a configuration without activating it. When exactly one of the
+// configurations has a project, it's used. When several do, it doesn't
+// guess. Its source, "gcloud-config:inferred", flags the inference.
+type gcloudConfigScanSearcher struct{}
+
+var _ Searcher = (*gcloudConfigScanSearcher)(nil)
+
+func newGCloudConfigScanSearcher() *gcloudConfigScanSearcher {
+	return &gcloudConfigScanSearcher{}
+}
+
+func (*gcloudConfigScanSearcher) Source() string { return "gcloud-config:inferred" }
+
+// backingFile returns the active_config file, which selecting a
+// configuration writes, ending the inference.
+func (*gcloudConfigScanSearcher) backingFile() string {
+	dir := gcloudConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "active_config")
+}
+
+// backingFiles returns the active_config file and the configuration files
+// scanned.
+func (s *gcloudConfigScanSearcher) backingFiles() []string {
+	dir := gcloudConfigDir()
+	if dir == "" {
+		return nil
+	}
+	files := []string{s.backingFile()}
+	entries, _ := readDir(filepath.Join(dir, "configurations"))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "config_") {
+			files = append(files, filepath.Join(dir, "configurations", e.Name()))
+		}
+	}
+	return files
+}
+
+func (*gcloudConfigScanSearcher) ProjectID(context.Context, ...string) (string, error) {
+	dir := gcloudConfigDir()
+	if dir == "" || getenv(impersonateServiceAccountKey) != "" ||
+		strings.TrimSpace(getenv("CLOUDSDK_ACTIVE_CONFIG_NAME")) != "" {
+		return "", nil
+	}
+	b, err := readFile(filepath.Join(dir, "active_config"))
+	if err == nil && strings.TrimSpace(string(b)) != "" {
+		// A configuration is selected: the gcloud config searcher read it.
+		return "", nil
+	}
+	b, err = readFile(filepath.Join(dir, "configurations", "config_default"))
+	if err == nil && parseINI(b)["core"]["project"] != "" {
+		// The default configuration has a project, so it's not a guess.
+		return "", nil
+	}
+
+	entries, err := readDir(filepath.Join(dir, "configurations"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read gcloud configurations: %w", err)
+	}
+	var id string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), "config_") {
+			continue
+		}
+		b, err := readFile(filepath.Join(dir, "configurations", e.Name()))
+		if err != nil {
+			continue
+		}
+		project := sanitizeValue(parseINI(b)["core"]["project"])
+		if project == "" {
+			continue
+		}
+		if id != "" {
+			// Several configurations have a project.
+			return "", nil
+		}
+		id = project
+	}
+	return id, nil
+}
+
+// gcloudConfigName returns the name of the gcloud configuration to use. In
+// order of precedence: the given configuration, the CLOUDSDK_ACTIVE_CONFIG_NAME
+// environment variable, the active_config file of the gcloud configuration
+// directory and, as gcloud defaults to, "default". Names that aren't plain
+// file names are ignored.
+func gcloudConfigName(configuration string) string {
+	name := configuration
+	if name == "" {
+		name = getenv("CLOUDSDK_ACTIVE_CONFIG_NAME")
+	}
+	if name == "" {
+		if dir := gcloudConfigDir(); dir != "" {
+			b, _ := readFile(filepath.Join(dir, "active_config"))
+			name = string(b)
+		}
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "default"
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// parseINI parses an INI document into its sections and keys, both
+// lowercased. Keys before any section belong to the "" section. Lines that
+// can't be parsed are ignored. Values are trimmed, and comments, starting
+// with '#' or ';', are skipped.
+func parseINI(b []byte) map[string]map[string]string {
+	ini := map[string]map[string]string{}
+	section := ""
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", line[0] == '#', line[0] == ';':
+			continue
+		case line[0] == '[':
+			if end := strings.IndexByte(line, ']'); end > 0 {
+				section = strings.ToLower(strings.TrimSpace(line[1:end]))
+			}
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		if ini[section] == nil {
+			ini[section] = map[string]string{}
+		}
+		ini[section][key] = value
+	}
+	return ini
+}
+
+// lookupPath walks the dotted path, like "gcp.project", on a decoded
+// document and returns the string found at its end, sanitized like the
+// values of the other sources. Missing keys yield an empty string. Both map[string]any and map[any]any nodes are supported, as
+// produced by the common JSON and YAML decoders.
+func lookupPath(doc any, path string) (string, error) {
+	node := doc
+	for _, key := range strings.Split(path, ".") {
+		switch m := node.(type) {
+		case map[string]any:
+			node = m[key]
+		case map[any]any:
+			node = m[key]
+		default:
+			return "", nil
+		}
+	}
+	switch v := node.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return sanitizeValue(strings.TrimSpace(v)), nil
+	default:
+		return "", fmt.Errorf("%s: expected a string, got %T", path, v)
+	}
+}
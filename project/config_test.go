@@ -0,0 +1,716 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// YAML Config Searcher
+
+func Test_yamlSearcher_ProjectID(t *testing.T) {
+	// JSON is a subset of YAML, so json.Unmarshal stands in for a YAML
+	// decoder.
+	tests := []struct {
+		name        string
+		content     string
+		projectPath string
+		unmarshal   func([]byte, any) error
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:      "Default path",
+			content:   `{"gcp": {"project": "gcp-id-test"}}`,
+			unmarshal: json.Unmarshal,
+			want:      "gcp-id-test",
+		},
+		{
+			name:        "Custom path",
+			content:     `{"app": {"cloud": {"project_id": "gcp-id-test"}}}`,
+			projectPath: "app.cloud.project_id",
+			unmarshal:   json.Unmarshal,
+			want:        "gcp-id-test",
+		},
+		{
+			name:    "map[any]any nodes",
+			content: "-",
+			unmarshal: func(_ []byte, v any) error {
+				*v.(*any) = map[any]any{
+					"gcp": map[any]any{"project": "gcp-id-test"},
+				}
+				return nil
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name:      "Missing key",
+			content:   `{"gcp": {"region": "us-east1"}}`,
+			unmarshal: json.Unmarshal,
+			want:      "",
+		},
+		{
+			name:      "Commented value",
+			content:   `{"gcp": {"project": "gcp-id-test # production"}}`,
+			unmarshal: json.Unmarshal,
+			want:      "",
+		},
+		{
+			name:      "Path through a scalar",
+			content:   `{"gcp": "gcp-id-test"}`,
+			unmarshal: json.Unmarshal,
+			want:      "",
+		},
+		{
+			name:      "Not a string",
+			content:   `{"gcp": {"project": 123}}`,
+			unmarshal: json.Unmarshal,
+			wantErr:   true,
+		},
+		{
+			name:      "Malformed",
+			content:   `{"gcp":`,
+			unmarshal: json.Unmarshal,
+			wantErr:   true,
+		},
+		{
+			name:    "Unmarshal not set",
+			content: `{"gcp": {"project": "gcp-id-test"}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0o600))
+			s := newYAMLSearcher(file, tt.projectPath, tt.unmarshal)
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_yamlSearcher_ProjectID_MissingFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "missing.yaml")
+	s := newYAMLSearcher(file, "", json.Unmarshal)
+
+	got, err := s.ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func Test_yamlSearcher_ProjectID_ReadError(t *testing.T) {
+	replace(t, &readFile, func(string) ([]byte, error) {
+		return nil, errors.New("test error")
+	})
+	s := newYAMLSearcher("config.yaml", "", json.Unmarshal)
+
+	_, err := s.ProjectID(context.Background())
+
+	require.Error(t, err)
+}
+
+func TestID_YAMLConfigFile(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := `{"gcp": {"project": "gcp-id-test"}}`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+
+	opts := Options{
+		Timeout:        time.Second,
+		YAMLConfigFile: file,
+		YAMLUnmarshal:  json.Unmarshal,
+	}
+	r := <-IDAsync(opts)
+
+	require.NoError(t, r.Err)
+	assert.Equal(t, Result{ID: "gcp-id-test", Source: "yaml", Found: true}, r)
+}
+
+// Systemd Credentials Searcher
+
+func Test_systemdSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		readFile func(string) ([]byte, error)
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "Credential found",
+			dir:  "/run/credentials/app.service",
+			readFile: func(file string) ([]byte, error) {
+				want := filepath.Join("/run/credentials/app.service", "gcp-project")
+				assert.Equal(t, want, file)
+				return []byte("gcp-id-test\n"), nil
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name: "Directory not set",
+			dir:  "",
+			readFile: func(string) ([]byte, error) {
+				t.Error("file read")
+				return nil, nil
+			},
+			want: "",
+		},
+		{
+			name: "File missing",
+			dir:  "/run/credentials/app.service",
+			readFile: func(string) ([]byte, error) {
+				return nil, fs.ErrNotExist
+			},
+			want: "",
+		},
+		{
+			name: "I/O error",
+			dir:  "/run/credentials/app.service",
+			readFile: func(string) ([]byte, error) {
+				return nil, fs.ErrPermission
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &getenv, func(key string) string {
+				if key == "CREDENTIALS_DIRECTORY" {
+					return tt.dir
+				}
+				return ""
+			})
+			replace(t, &readFile, tt.readFile)
+			s := newSystemdSearcher("gcp-project")
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, fs.ErrPermission)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Boto Config Searcher
+
+func Test_botoSearcher_ProjectID(t *testing.T) {
+	const boto = `
+# Written by gsutil config.
+[Credentials]
+gs_oauth2_refresh_token = token
+
+[GSUtil]
+; The default project.
+default_project_id = gcp-id-test
+content_language = en
+`
+	tests := []struct {
+		name  string
+		files map[string]string
+		env   func(dir string) map[string]string
+		want  string
+	}{
+		{
+			name:  "Home directory",
+			files: map[string]string{".boto": boto},
+			env: func(dir string) map[string]string {
+				return map[string]string{"HOME": dir, "USERPROFILE": dir}
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name:  "BOTO_CONFIG",
+			files: map[string]string{"custom.boto": boto},
+			env: func(dir string) map[string]string {
+				return map[string]string{
+					"BOTO_CONFIG": filepath.Join(dir, "custom.boto"),
+				}
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name: "BOTO_PATH, later files override",
+			files: map[string]string{
+				"a.boto": boto,
+				"b.boto": "[GSUtil]\ndefault_project_id: gcp-id-override\n",
+				"c.boto": "[Boto]\nhttps_validate_certificates = True\n",
+			},
+			env: func(dir string) map[string]string {
+				files := []string{
+					filepath.Join(dir, "a.boto"),
+					filepath.Join(dir, "b.boto"),
+					filepath.Join(dir, "c.boto"),
+					filepath.Join(dir, "missing.boto"),
+				}
+				return map[string]string{
+					"BOTO_PATH": strings.Join(files, string(filepath.ListSeparator)),
+				}
+			},
+			want: "gcp-id-override",
+		},
+		{
+			name:  "Quoted value",
+			files: map[string]string{".boto": "[GSUtil]\ndefault_project_id = \"gcp-id-test\"\n"},
+			env: func(dir string) map[string]string {
+				return map[string]string{"HOME": dir, "USERPROFILE": dir}
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name:  "Trailing comment",
+			files: map[string]string{".boto": "[GSUtil]\ndefault_project_id = gcp-id-test # mine\n"},
+			env: func(dir string) map[string]string {
+				return map[string]string{"HOME": dir, "USERPROFILE": dir}
+			},
+			want: "",
+		},
+		{
+			name:  "Not in the GSUtil section",
+			files: map[string]string{".boto": "default_project_id = gcp-id-test\n"},
+			env: func(dir string) map[string]string {
+				return map[string]string{"HOME": dir, "USERPROFILE": dir}
+			},
+			want: "",
+		},
+		{
+			name:  "Missing file",
+			files: nil,
+			env: func(dir string) map[string]string {
+				return map[string]string{"HOME": dir, "USERPROFILE": dir}
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			}
+			unsetEnv(t, "BOTO_CONFIG", "BOTO_PATH")
+			for k, v := range tt.env(dir) {
+				t.Setenv(k, v)
+			}
+			s := newBotoSearcher()
+
+			got, err := s.ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// GCloud Config Searcher
+
+func Test_gcloudConfigSearcher_ProjectID(t *testing.T) {
+	configs := map[string]string{
+		"config_default": "[core]\nproject = gcp-id-default\n",
+		"config_active":  "[core]\naccount = user@example.com\nproject = gcp-id-active\n",
+		"config_env":     "[core]\nproject = gcp-id-env\n",
+		"config_option":  "[core]\nproject = gcp-id-option\n",
+		"config_empty":   "[compute]\nregion = us-east1\n",
+	}
+	tests := []struct {
+		name          string
+		configuration string
+		envName       string
+		activeConfig  string
+		want          string
+	}{
+		{
+			name:          "Option over env and active_config",
+			configuration: "option",
+			envName:       "env",
+			activeConfig:  "active",
+			want:          "gcp-id-option",
+		},
+		{
+			name:         "Env over active_config",
+			envName:      "env",
+			activeConfig: "active",
+			want:         "gcp-id-env",
+		},
+		{
+			name:         "active_config",
+			activeConfig: "active\n",
+			want:         "gcp-id-active",
+		},
+		{
+			name: "Default configuration",
+			want: "gcp-id-default",
+		},
+		{
+			name:          "No project in the configuration",
+			configuration: "empty",
+			want:          "",
+		},
+		{
+			name:          "Missing configuration",
+			configuration: "missing",
+			want:          "",
+		},
+		{
+			name:          "Not a file name",
+			configuration: "../config_option",
+			want:          "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "configurations"), 0o700))
+			for name, content := range configs {
+				path := filepath.Join(dir, "configurations", name)
+				require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			}
+			if tt.activeConfig != "" {
+				path := filepath.Join(dir, "active_config")
+				require.NoError(t, os.WriteFile(path, []byte(tt.activeConfig), 0o600))
+			}
+			t.Setenv("CLOUDSDK_CONFIG", dir)
+			t.Setenv("CLOUDSDK_ACTIVE_CONFIG_NAME", tt.envName)
+			unsetEnv(t, impersonateServiceAccountKey)
+			s := newGCloudConfigSearcher(tt.configuration)
+
+			got, err := s.ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_gcloudConfigSearcher_ProjectID_Impersonation(t *testing.T) {
+	replace(t, &readFile, func(string) ([]byte, error) {
+		t.Error("file read")
+		return nil, nil
+	})
+	t.Setenv(impersonateServiceAccountKey, "robot@gcp-id-test.iam.gserviceaccount.com")
+	s := newGCloudConfigSearcher("default")
+
+	got, err := s.ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestResolve_GCloudConfigFiles(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey, "CLOUDSDK_ACTIVE_CONFIG_NAME",
+		impersonateServiceAccountKey)
+	useGCloudConfigDir(t, "[core]\nproject = gcp-id-file\n")
+	tests := []struct {
+		name       string
+		useFiles   bool
+		wantID     string
+		wantSource string
+	}{
+		{name: "Default", wantID: "gcp-id-test", wantSource: "gcloud"},
+		{name: "Opted in", useFiles: true, wantID: "gcp-id-file", wantSource: "gcloud-config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := Options{Timeout: time.Second, UseGCloudConfigFiles: tt.useFiles}
+			o.Searchers = gcloudSearchers(o, func(*exec.Cmd, int) ([]byte, error) {
+				return []byte("gcp-id-test\n"), nil
+			})
+
+			r, err := Resolve(context.Background(), o)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, r.ID)
+			assert.Equal(t, tt.wantSource, r.Source)
+		})
+	}
+}
+
+func TestResolve_GCloudConfigAccount(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey, "CLOUDSDK_ACTIVE_CONFIG_NAME",
+		impersonateServiceAccountKey)
+	useGCloudConfigDir(t, "[core]\nproject = gcp-id-file\n")
+	var args []string
+	o := Options{
+		Timeout:              time.Second,
+		GCloudAccount:        "dev@example.com",
+		UseGCloudConfigFiles: true,
+	}
+	o.Searchers = gcloudSearchers(o, func(cmd *exec.Cmd, _ int) ([]byte, error) {
+		args = cmd.Args
+		return []byte("gcp-id-test\n"), nil
+	})
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "gcloud", r.Source)
+	assert.Contains(t, args, "--account=dev@example.com")
+}
+
+func TestResolve_GCloudConfigAccount_NoSubprocess(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey, "CLOUDSDK_ACTIVE_CONFIG_NAME",
+		impersonateServiceAccountKey)
+	useGCloudConfigDir(t, "[core]\nproject = gcp-id-test\n")
+	o := Options{
+		Timeout:              time.Second,
+		GCloudAccount:        "dev@example.com",
+		NoSubprocess:         true,
+		UseGCloudConfigFiles: true,
+	}
+	o.Searchers = gcloudSearchers(o, nil)
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "gcloud-config", r.Source)
+}
+
+func TestResolve_GCloudConfigParse(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey, "CLOUDSDK_ACTIVE_CONFIG_NAME",
+		impersonateServiceAccountKey)
+	useGCloudConfigDir(t, "[core]\nproject = gcp-id-file\n")
+	o := Options{
+		Timeout:              time.Second,
+		GCloudFormat:         "json",
+		UseGCloudConfigFiles: true,
+		GCloudParse: func(b []byte) (string, error) {
+			var id string
+			err := json.Unmarshal(b, &id)
+			return id, err
+		},
+	}
+	o.Searchers = gcloudSearchers(o, func(*exec.Cmd, int) ([]byte, error) {
+		return []byte(`"gcp-id-test"`), nil
+	})
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "gcloud", r.Source)
+}
+
+// useGCloudConfigDir sets CLOUDSDK_CONFIG to a new gcloud configuration
+// directory, whose default configuration has the content.
+func useGCloudConfigDir(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "configurations"), 0o700))
+	path := filepath.Join(dir, "configurations", "config_default")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+}
+
+// gcloudSearchers returns the gcloud searchers of the DefaultSearchers for
+// the options, with the CLI faked by output.
+func gcloudSearchers(
+	o Options, output func(cmd *exec.Cmd, limit int) ([]byte, error),
+) []Searcher {
+	var ss []Searcher
+	for _, s := range DefaultSearchers(o) {
+		if !strings.HasPrefix(sourceOf(s), "gcloud") {
+			continue
+		}
+		g, ok := s.(*gcloudSearcher)
+		if h, isHelper := s.(*gcloudConfigHelperSearcher); isHelper {
+			g, ok = h.gcloud, true
+		}
+		if ok {
+			g.discover, g.executables, g.output = nil, []string{"gcloud"}, output
+		}
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+func Test_gcloudConfigSearcher_ProjectID_ReadError(t *testing.T) {
+	t.Setenv("CLOUDSDK_CONFIG", "/gcloud")
+	replace(t, &readFile, func(string) ([]byte, error) {
+		return nil, fs.ErrPermission
+	})
+	unsetEnv(t, impersonateServiceAccountKey)
+	s := newGCloudConfigSearcher("default")
+
+	_, err := s.ProjectID(context.Background())
+
+	require.ErrorIs(t, err, fs.ErrPermission)
+}
+
+func Test_gcloudConfigScanSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name         string
+		configs      map[string]string
+		envName      string
+		activeConfig string
+		want         string
+	}{
+		{
+			name: "Single configuration with a project",
+			configs: map[string]string{
+				"config_dev":   "[core]\nproject = gcp-id-test\n",
+				"config_empty": "[compute]\nregion = us-east1\n",
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name: "Empty active_config",
+			configs: map[string]string{
+				"config_dev": "[core]\nproject = gcp-id-test\n",
+			},
+			activeConfig: "\n",
+			want:         "gcp-id-test",
+		},
+		{
+			name: "Several configurations with a project",
+			configs: map[string]string{
+				"config_dev":  "[core]\nproject = gcp-id-test\n",
+				"config_prod": "[core]\nproject = gcp-id-prod\n",
+			},
+			want: "",
+		},
+		{
+			name: "No configuration with a project",
+			configs: map[string]string{
+				"config_empty": "[compute]\nregion = us-east1\n",
+			},
+			want: "",
+		},
+		{
+			name: "Default configuration with a project",
+			configs: map[string]string{
+				"config_default": "[core]\nproject = gcp-id-default\n",
+			},
+			want: "",
+		},
+		{
+			name: "Default configuration without a project",
+			configs: map[string]string{
+				"config_default": "[core]\naccount = user@example.com\n",
+				"config_dev":     "[core]\nproject = gcp-id-test\n",
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name: "Active configuration",
+			configs: map[string]string{
+				"config_dev": "[core]\nproject = gcp-id-test\n",
+			},
+			activeConfig: "other",
+			want:         "",
+		},
+		{
+			name: "Configuration in the environment",
+			configs: map[string]string{
+				"config_dev": "[core]\nproject = gcp-id-test\n",
+			},
+			envName: "other",
+			want:    "",
+		},
+		{
+			name: "Not a configuration",
+			configs: map[string]string{
+				"credentials.db": "[core]\nproject = gcp-id-test\n",
+			},
+			want: "",
+		},
+		{
+			name:    "No configurations directory",
+			configs: nil,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.configs != nil {
+				require.NoError(t, os.Mkdir(filepath.Join(dir, "configurations"), 0o700))
+			}
+			for name, content := range tt.configs {
+				path := filepath.Join(dir, "configurations", name)
+				require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			}
+			if tt.activeConfig != "" {
+				path := filepath.Join(dir, "active_config")
+				require.NoError(t, os.WriteFile(path, []byte(tt.activeConfig), 0o600))
+			}
+			t.Setenv("CLOUDSDK_CONFIG", dir)
+			t.Setenv("CLOUDSDK_ACTIVE_CONFIG_NAME", tt.envName)
+			unsetEnv(t, impersonateServiceAccountKey)
+
+			got, err := newGCloudConfigScanSearcher().ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve_GCloudConfigInferred(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey, "CLOUDSDK_ACTIVE_CONFIG_NAME")
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "configurations"), 0o700))
+	path := filepath.Join(dir, "configurations", "config_dev")
+	require.NoError(t, os.WriteFile(path, []byte("[core]\nproject = gcp-id-test\n"), 0o600))
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+	useSearchers(t, func(o Options) []Searcher {
+		return []Searcher{newGCloudConfigSearcher(""), newGCloudConfigScanSearcher()}
+	})
+
+	r, err := Resolve(context.Background(), Options{Timeout: time.Second})
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "gcloud-config:inferred", r.Source)
+}
+
+func Test_parseINI(t *testing.T) {
+	content := `
+top = level
+[Core]
+Project = gcp-id-test
+account: user@example.com
+  # comment
+; comment
+not a setting
+= no key
+[broken
+[ Auth ]
+disable_credentials=True
+`
+	got := parseINI([]byte(content))
+
+	assert.Equal(t, map[string]map[string]string{
+		"": {"top": "level"},
+		"core": {
+			"project": "gcp-id-test",
+			"account": "user@example.com",
+		},
+		"auth": {"disable_credentials": "True"},
+	}, got)
+}
@@ -0,0 +1,72 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GCloud Config Helper Searcher
+
+// gcloudConfigHelperSearcher reads the project of the configuration printed
+// by `gcloud config config-helper`, which is the one gcloud resolves from
+// its configuration files, flags and environment. It runs gcloud like the
+// gcloud searcher, whose commands and seams it shares.
+type gcloudConfigHelperSearcher struct {
+	gcloud *gcloudSearcher
+}
+
+var _ Searcher = (*gcloudConfigHelperSearcher)(nil)
+
+func newGCloudConfigHelperSearcher(o Options) *gcloudConfigHelperSearcher {
+	s := gcloudConfigHelperSearcher{
+		gcloud: newGCloudSearcher(o),
+	}
+	return &s
+}
+
+func (*gcloudConfigHelperSearcher) Source() string { return "gcloud-config-helper" }
+
+func (s *gcloudConfigHelperSearcher) ProjectID(ctx context.Context, _ ...string) (
+	string, error,
+) {
+	g := s.gcloud
+	args := append([]string{"config", "config-helper", "--format=json"},
+		gcloudFlags(g.configuration, g.account)...)
+	step := stepFromContext(ctx)
+	commands, ok := g.commands(ctx)
+	if !ok {
+		return "", g.undiscovered(ctx, step)
+	}
+	for _, command := range commands {
+		id, ok := g.query(ctx, step, command, args, parseConfigHelperOutput)
+		if ok && ctx.Err() == nil {
+			// gcloud ran: there's no other configuration to look at.
+			return id, nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("run gcloud config-helper: %w", err)
+	}
+	return "", nil
+}
+
+// parseConfigHelperOutput extracts the project ID from the JSON output of
+// `gcloud config config-helper`. A missing project is not an error.
+func parseConfigHelperOutput(b []byte) (string, error) {
+	var doc struct {
+		Configuration struct {
+			Properties struct {
+				Core struct {
+					Project string `json:"project"`
+				} `json:"core"`
+			} `json:"properties"`
+		} `json:"configuration"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", err
+	}
+	project := doc.Configuration.Properties.Core.Project
+	return sanitizeValue(strings.TrimSpace(project)), nil
+}
@@ -0,0 +1,186 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configHelperOutput is the output of `gcloud config config-helper
+// --format=json`, as recorded with gcloud 470.0.0, with the tokens redacted.
+const configHelperOutput = `{
+  "configuration": {
+    "active_configuration": "default",
+    "properties": {
+      "core": {
+        "account": "dev@example.com",
+        "disable_usage_reporting": "True",
+        "project": "gcp-id-test"
+      },
+      "compute": {
+        "region": "us-central1"
+      }
+    }
+  },
+  "credential": {
+    "access_token": "REDACTED",
+    "id_token": "REDACTED",
+    "token_expiry": "2024-04-02T12:00:00Z"
+  },
+  "sentinels": {
+    "config_sentinel": "/home/dev/.config/gcloud/config_sentinel"
+  }
+}
+`
+
+func Test_gcloudConfigHelperSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		err       error
+		want      string
+		wantCalls int
+	}{
+		{
+			name:      "Project ID",
+			output:    configHelperOutput,
+			want:      "gcp-id-test",
+			wantCalls: 1,
+		},
+		{
+			name:      "Missing project",
+			output:    `{"configuration": {"properties": {"core": {}}}}`,
+			want:      "",
+			wantCalls: 1,
+		},
+		{
+			name:      "Missing configuration",
+			output:    `{}`,
+			want:      "",
+			wantCalls: 1,
+		},
+		{
+			name:      "Not JSON",
+			output:    "gcp-id-test",
+			want:      "",
+			wantCalls: 2,
+		},
+		{
+			name:      "Not logged in",
+			err:       &exec.ExitError{Stderr: []byte("ERROR: not logged in")},
+			want:      "",
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(impersonateServiceAccountKey, "")
+			var gotArgs [][]string
+			s := newGCloudConfigHelperSearcher(Options{GCloudConfiguration: "dev"})
+			s.gcloud.discover = func() ([]string, [][]string) {
+				return []string{"gcloud", "/opt/gcloud"}, nil
+			}
+			s.gcloud.output = func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = append(gotArgs, cmd.Args[1:])
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return []byte(tt.output), nil
+			}
+			var step SearchStep
+
+			got, err := s.ProjectID(withStep(context.Background(), &step))
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			require.Len(t, gotArgs, tt.wantCalls)
+			want := []string{
+				"config", "config-helper", "--format=json", "--configuration=dev",
+			}
+			assert.Equal(t, want, gotArgs[0])
+			assert.Len(t, step.Attempts, tt.wantCalls)
+		})
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s := newGCloudConfigHelperSearcher(Options{})
+		s.gcloud.discover = func() ([]string, [][]string) {
+			return []string{"gcloud"}, nil
+		}
+		s.gcloud.output = func(*exec.Cmd, int) ([]byte, error) {
+			return nil, errors.New("unexpected run")
+		}
+
+		_, err := s.ProjectID(ctx)
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestDefaultSearchers_GCloudConfigHelper(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want bool
+	}{
+		{
+			name: "Enabled",
+			opts: Options{UseGCloudConfigHelper: true},
+			want: true,
+		},
+		{
+			name: "Disabled",
+			opts: Options{},
+			want: false,
+		},
+		{
+			name: "No subprocess",
+			opts: Options{UseGCloudConfigHelper: true, NoSubprocess: true},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Timeout = time.Second
+			var sources []string
+			for _, s := range DefaultSearchers(tt.opts) {
+				sources = append(sources, sourceOf(s))
+			}
+
+			if !tt.want {
+				assert.NotContains(t, sources, "gcloud-config-helper")
+				return
+			}
+			require.GreaterOrEqual(t, len(sources), 2)
+			assert.Equal(t, []string{"gcloud-config-helper", "gcloud"},
+				sources[len(sources)-2:])
+		})
+	}
+}
+
+func TestResolve_GCloudConfigHelper_ConfigFile(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey, "CLOUDSDK_ACTIVE_CONFIG_NAME",
+		impersonateServiceAccountKey)
+	useGCloudConfigDir(t, "[core]\nproject = gcp-id-file\n")
+	o := Options{
+		Timeout:               time.Second,
+		UseGCloudConfigHelper: true,
+		UseGCloudConfigFiles:  true,
+	}
+	o.Searchers = gcloudSearchers(o, func(*exec.Cmd, int) ([]byte, error) {
+		return []byte(configHelperOutput), nil
+	})
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "gcloud-config-helper", r.Source)
+}
@@ -0,0 +1,21 @@
+package project
+
+import "context"
+
+type projectIDKey struct{}
+
+// WithProjectID returns a copy of ctx carrying the project ID id. IDContext
+// returns this ID immediately, with the "context" source, without running
+// any search. The context override takes precedence over all the sources,
+// except the Explicit option. An empty id overrides nothing: the search
+// runs as without it.
+func WithProjectID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, projectIDKey{}, id)
+}
+
+// ProjectIDFromContext returns the project ID carried by ctx, as set by
+// WithProjectID, and whether it was set to a non-empty ID.
+func ProjectIDFromContext(ctx context.Context) (string, bool) {
+	id, _ := ctx.Value(projectIDKey{}).(string)
+	return id, id != ""
+}
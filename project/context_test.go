@@ -0,0 +1,49 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectIDFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := ProjectIDFromContext(ctx)
+	assert.False(t, ok)
+
+	id, ok := ProjectIDFromContext(WithProjectID(ctx, "gcp-id-test"))
+	assert.True(t, ok)
+	assert.Equal(t, "gcp-id-test", id)
+
+	_, ok = ProjectIDFromContext(WithProjectID(ctx, ""))
+	assert.False(t, ok)
+}
+
+func TestIDContext_WithProjectID(t *testing.T) {
+	s := &countingSearcherMock{projectID: "gcp-id-searched"}
+	useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+	ctx := WithProjectID(context.Background(), "gcp-id-test")
+
+	got, err := IDContext(ctx, Options{Timeout: time.Second})
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", got)
+	assert.Zero(t, s.calls)
+	assert.Equal(t, Result{ID: "gcp-id-test", Source: "context", Found: true},
+		resolve(ctx, Options{Timeout: time.Second}))
+}
+
+func TestIDContext_WithProjectID_Empty(t *testing.T) {
+	s := &countingSearcherMock{}
+	useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+	ctx := WithProjectID(context.Background(), "")
+
+	_, err := IDContext(ctx, Options{Timeout: time.Second, Strict: true})
+
+	require.ErrorIs(t, err, ErrProjectIDNotFound)
+	assert.Equal(t, 1, s.calls)
+}
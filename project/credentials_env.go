@@ -0,0 +1,69 @@
+package project
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Credentials Env Searcher
+
+// credentialsEnvSearcher reads the `project_id` field of a service account
+// key held, base64-encoded, in an environment variable, as CI systems
+// often provide it to avoid mounting a file.
+type credentialsEnvSearcher struct {
+	key string
+
+	// universeDomain, if set, is the universe the key must be in.
+	universeDomain string
+}
+
+var _ Searcher = (*credentialsEnvSearcher)(nil)
+
+func newCredentialsEnvSearcher(key, universeDomain string) *credentialsEnvSearcher {
+	s := credentialsEnvSearcher{
+		key:            key,
+		universeDomain: universeDomain,
+	}
+	return &s
+}
+
+func (*credentialsEnvSearcher) Source() string { return "credentials-env" }
+
+func (s *credentialsEnvSearcher) ProjectID(context.Context, ...string) (
+	string, error,
+) {
+	v := strings.TrimSpace(getenv(s.key))
+	if v == "" {
+		return "", nil
+	}
+	b, err := decodeBase64(v)
+	if err != nil {
+		return "", fmt.Errorf("decode %s: %w", s.key, err)
+	}
+	var f struct {
+		ProjectID      string `json:"project_id"`
+		UniverseDomain string `json:"universe_domain"`
+	}
+	if err = json.Unmarshal(b, &f); err != nil {
+		return "", fmt.Errorf("parse credentials in %s: %w", s.key, err)
+	}
+	id := sanitizeValue(strings.TrimSpace(f.ProjectID))
+	if id == "" {
+		return "", nil
+	}
+	err = checkUniverseDomain(s.universeDomain, f.UniverseDomain, s.key)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// decodeBase64 decodes s in the standard encoding, padded or not, as the
+// output of `base64` and of most CI secret tooling.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
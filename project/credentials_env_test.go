@@ -0,0 +1,91 @@
+package project
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_credentialsEnvSearcher_ProjectID(t *testing.T) {
+	encode := base64.StdEncoding.EncodeToString
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Service account key",
+			value: encode([]byte(`{"type":"service_account","project_id":"gcp-id-test"}`)),
+			want:  "gcp-id-test",
+		},
+		{
+			name:  "Unpadded and wrapped",
+			value: "eyJwcm9qZWN0X2lkIjoi\nZ2NwLWlkLXRlc3QifQ",
+			want:  "gcp-id-test",
+		},
+		{
+			name:  "No project ID",
+			value: encode([]byte(`{"type":"authorized_user"}`)),
+			want:  "",
+		},
+		{
+			name:  "Not a project ID",
+			value: encode([]byte(`{"type":"service_account","project_id":"gcp id\ttest"}`)),
+			want:  "",
+		},
+		{
+			name:  "Unset",
+			value: "",
+			want:  "",
+		},
+		{
+			name:    "Invalid base64",
+			value:   "not base64!",
+			wantErr: true,
+		},
+		{
+			name:    "Not JSON",
+			value:   encode([]byte("not json")),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("__GCP_CREDENTIALS_TEST__", tt.value)
+			s := newCredentialsEnvSearcher("__GCP_CREDENTIALS_TEST__", "")
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "__GCP_CREDENTIALS_TEST__")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve_CredentialsBase64Env(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	t.Setenv("GOOGLE_CREDENTIALS_BASE64", base64.StdEncoding.EncodeToString(
+		[]byte(`{"type":"service_account","project_id":"gcp-id-test"}`),
+	))
+	opts := Options{
+		Timeout:              time.Second,
+		CredentialsBase64Env: "GOOGLE_CREDENTIALS_BASE64",
+	}
+
+	r, err := Resolve(context.Background(), opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "credentials-env", r.Source)
+}
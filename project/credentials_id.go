@@ -0,0 +1,83 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/oauth2/google"
+)
+
+// IDAndCredentials retrieves the default Google Cloud project ID like
+// IDContext, along with the application default credentials for the
+// Scopes option, so callers building API clients don't find them again.
+//
+// When the credentials searcher ran, the credentials it found are
+// returned as is. Otherwise, as when the project ID came from the
+// environment or gcloud, they're found with the FindCredentials option, or
+// google.FindDefaultCredentials, bounded by the Timeout option. If that
+// fails, the project ID is still returned, with nil credentials and the
+// error.
+func IDAndCredentials(ctx context.Context, opts ...Options) (
+	string, *google.Credentials, error,
+) {
+	o := getOptions(opts...)
+	var found foundCredentials
+	r := resolve(withFoundCredentials(ctx, &found), o)
+	if r.Err != nil {
+		return "", nil, r.Err
+	}
+	if c := found.get(); c != nil {
+		return r.ID, c, nil
+	}
+
+	ctx, cancel := withDeadline(ctx, o)
+	defer cancel()
+	find := o.FindCredentials
+	if find == nil {
+		find = google.FindDefaultCredentials
+	}
+	c, err := find(ctx, o.Scopes...)
+	if err != nil {
+		return r.ID, nil, fmt.Errorf("find credentials: %w", err)
+	}
+	return r.ID, c, nil
+}
+
+// foundCredentials records the credentials found by the credentials
+// searcher. It's safe for concurrent use, as searchers may race.
+type foundCredentials struct {
+	mu          sync.Mutex
+	credentials *google.Credentials
+}
+
+func (f *foundCredentials) get() *google.Credentials {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.credentials
+}
+
+// set records the credentials, if f is not nil.
+func (f *foundCredentials) set(c *google.Credentials) {
+	if f == nil || c == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.credentials = c
+}
+
+type foundCredentialsKey struct{}
+
+// withFoundCredentials returns a copy of ctx carrying f, so the credentials
+// searcher records the credentials it finds in it.
+func withFoundCredentials(ctx context.Context, f *foundCredentials) context.Context {
+	return context.WithValue(ctx, foundCredentialsKey{}, f)
+}
+
+// foundCredentialsFromContext returns the record of the credentials found,
+// or nil if they're not being recorded.
+func foundCredentialsFromContext(ctx context.Context) *foundCredentials {
+	f, _ := ctx.Value(foundCredentialsKey{}).(*foundCredentials)
+	return f
+}
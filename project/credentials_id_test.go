@@ -0,0 +1,93 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func TestIDAndCredentials(t *testing.T) {
+	errFind := errors.New("find error")
+	tests := []struct {
+		name      string
+		env       string
+		findErr   error
+		want      string
+		wantCreds bool
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "From the credentials",
+			env:       "",
+			want:      "gcp-id-credentials",
+			wantCreds: true,
+			wantCalls: 1,
+		},
+		{
+			name:      "From the environment",
+			env:       "gcp-id-env",
+			want:      "gcp-id-env",
+			wantCreds: true,
+			wantCalls: 1,
+		},
+		{
+			name:      "From the environment, no credentials",
+			env:       "gcp-id-env",
+			findErr:   errFind,
+			want:      "gcp-id-env",
+			wantErr:   errFind,
+			wantCalls: 1,
+		},
+		{
+			name:      "Search error",
+			env:       "",
+			findErr:   errFind,
+			wantErr:   errFind,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("__GCP_PROJECT_ID_TEST__", tt.env)
+			credentials := &google.Credentials{ProjectID: "gcp-id-credentials"}
+			var calls int
+			find := func(_ context.Context, scopes ...string) (*google.Credentials, error) {
+				calls++
+				assert.Equal(t, []string{"scope-a"}, scopes)
+				return credentials, tt.findErr
+			}
+			useSearchers(t, func(o Options) []Searcher {
+				return []Searcher{
+					newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__"),
+					newCredentialsSearcher(o.FindCredentials, ""),
+				}
+			})
+
+			id, c, err := IDAndCredentials(context.Background(), Options{
+				Timeout:         time.Second,
+				Scopes:          []string{"scope-a"},
+				FindCredentials: find,
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, id)
+			if tt.wantCreds {
+				assert.Same(t, credentials, c)
+			} else {
+				assert.Nil(t, c)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+
+}
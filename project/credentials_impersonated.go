@@ -0,0 +1,57 @@
+package project
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// serviceAccountDomain ends the email of the service accounts created in a
+// project, NAME@PROJECT_ID.iam.gserviceaccount.com.
+const serviceAccountDomain = ".iam.gserviceaccount.com"
+
+// impersonatedProjectID returns the project of the service account
+// impersonated by the application default credentials in b, of the
+// impersonated_service_account type, as written by `gcloud auth
+// application-default login --impersonate-service-account`. Such
+// credentials have no project ID of their own. It's empty for other
+// credentials, or when the project can't be told.
+func impersonatedProjectID(b []byte) string {
+	var f struct {
+		Type string `json:"type"`
+		URL  string `json:"service_account_impersonation_url"`
+	}
+	if json.Unmarshal(b, &f) != nil || f.Type != "impersonated_service_account" {
+		return ""
+	}
+	return impersonationURLProject(f.URL)
+}
+
+// impersonationURLProject returns the project of the service account in an
+// impersonation URL, like
+// https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/NAME@PROJECT_ID.iam.gserviceaccount.com:generateAccessToken.
+// The project is taken from the service account email or, for accounts
+// named otherwise, from the projects/ segment, unless it's the "-"
+// wildcard.
+func impersonationURLProject(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+3 < len(segments); i++ {
+		if segments[i] != "projects" || segments[i+2] != "serviceAccounts" {
+			continue
+		}
+		email, _, _ := strings.Cut(segments[i+3], ":")
+		_, domain, _ := strings.Cut(email, "@")
+		if id, ok := strings.CutSuffix(domain, serviceAccountDomain); ok {
+			return sanitizeValue(id)
+		}
+		if id := segments[i+1]; id != "-" {
+			return sanitizeValue(id)
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func Test_impersonationURLProject(t *testing.T) {
+	const base = "https://iamcredentials.googleapis.com/v1/"
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "Wildcard project",
+			url:  base + "projects/-/serviceAccounts/app@gcp-id-test.iam.gserviceaccount.com:generateAccessToken",
+			want: "gcp-id-test",
+		},
+		{
+			name: "Explicit project",
+			url:  base + "projects/gcp-id-other/serviceAccounts/app@gcp-id-test.iam.gserviceaccount.com:generateAccessToken",
+			want: "gcp-id-test",
+		},
+		{
+			name: "Escaped email",
+			url:  base + "projects/-/serviceAccounts/app%40gcp-id-test.iam.gserviceaccount.com:generateAccessToken",
+			want: "gcp-id-test",
+		},
+		{
+			name: "Default service account with a project",
+			url:  base + "projects/gcp-id-test/serviceAccounts/123-compute@developer.gserviceaccount.com:generateAccessToken",
+			want: "gcp-id-test",
+		},
+		{
+			name: "Default service account with the wildcard",
+			url:  base + "projects/-/serviceAccounts/123-compute@developer.gserviceaccount.com:generateAccessToken",
+			want: "",
+		},
+		{
+			name: "Unique ID with the wildcard",
+			url:  base + "projects/-/serviceAccounts/123456789012345678901:generateAccessToken",
+			want: "",
+		},
+		{
+			name: "Not an impersonation URL",
+			url:  "https://example.com/token",
+			want: "",
+		},
+		{
+			name: "Malformed",
+			url:  "://",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := impersonationURLProject(tt.url)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_impersonatedProjectID(t *testing.T) {
+	assert.Equal(t, "gcp-id-test", impersonatedProjectID([]byte(impersonatedADC)))
+	assert.Empty(t, impersonatedProjectID([]byte(`{"type":"authorized_user"}`)))
+	assert.Empty(t, impersonatedProjectID([]byte(`not json`)))
+	assert.Empty(t, impersonatedProjectID(nil))
+}
+
+func Test_credentialsSearcher_ProjectID_Impersonated(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "application_default_credentials.json")
+	require.NoError(t, os.WriteFile(file, []byte(impersonatedADC), 0o600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+
+	got, err := newCredentialsSearcher(nil, "").ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", got)
+
+	t.Run("Own project first", func(t *testing.T) {
+		find := func(context.Context, ...string) (*google.Credentials, error) {
+			c := google.Credentials{ProjectID: "gcp-id-own", JSON: []byte(impersonatedADC)}
+			return &c, nil
+		}
+
+		got, err := newCredentialsSearcher(find, "").ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-own", got)
+	})
+}
+
+// impersonatedADC is application default credentials impersonating a
+// service account of the gcp-id-test project, as written by `gcloud auth
+// application-default login --impersonate-service-account`.
+const impersonatedADC = `{
+  "delegates": [],
+  "service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/app@gcp-id-test.iam.gserviceaccount.com:generateAccessToken",
+  "source_credentials": {
+    "client_id": "client-id.apps.googleusercontent.com",
+    "client_secret": "client-secret",
+    "refresh_token": "refresh-token",
+    "type": "authorized_user"
+  },
+  "type": "impersonated_service_account"
+}`
@@ -0,0 +1,60 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Credentials Quota Project Searcher
+
+// credentialsQuotaSearcher reads the `quota_project_id` field of the
+// application default credentials file, when it holds user credentials, as
+// written by `gcloud auth application-default login` and
+// `gcloud auth application-default set-quota-project`. User credentials
+// have no project ID of their own, so on developer machines the quota
+// project is often the only project they carry. It's searched after the
+// credentials, whose project ID is preferred.
+type credentialsQuotaSearcher struct{}
+
+var _ Searcher = (*credentialsQuotaSearcher)(nil)
+
+func newCredentialsQuotaSearcher() *credentialsQuotaSearcher {
+	return &credentialsQuotaSearcher{}
+}
+
+func (*credentialsQuotaSearcher) Source() string { return "credentials:quota_project" }
+
+func (*credentialsQuotaSearcher) backingFile() string { return credentialsFile() }
+
+func (s *credentialsQuotaSearcher) ProjectID(context.Context, ...string) (
+	string, error,
+) {
+	file := s.backingFile()
+	if file == "" {
+		return "", nil
+	}
+	b, err := readFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read credentials file: %w", err)
+	}
+	var f struct {
+		Type           string `json:"type"`
+		ProjectID      string `json:"project_id"`
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if err = json.Unmarshal(b, &f); err != nil {
+		// Leave the error to report to the credentials searcher.
+		return "", nil
+	}
+	if f.Type != "authorized_user" || strings.TrimSpace(f.ProjectID) != "" {
+		return "", nil
+	}
+	return sanitizeValue(strings.TrimSpace(f.QuotaProjectID)), nil
+}
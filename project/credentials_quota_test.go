@@ -0,0 +1,145 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func Test_credentialsQuotaSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		want    string
+	}{
+		{
+			name: "User credentials with a quota project",
+			content: ptr(`{"type":"authorized_user","client_id":"id",` +
+				`"quota_project_id":"gcp-id-test"}`),
+			want: "gcp-id-test",
+		},
+		{
+			name:    "User credentials without a quota project",
+			content: ptr(`{"type":"authorized_user","client_id":"id"}`),
+			want:    "",
+		},
+		{
+			name: "User credentials with a project ID",
+			content: ptr(`{"type":"authorized_user","project_id":"gcp-id-other",` +
+				`"quota_project_id":"gcp-id-test"}`),
+			want: "",
+		},
+		{
+			name: "Service account",
+			content: ptr(`{"type":"service_account",` +
+				`"quota_project_id":"gcp-id-test"}`),
+			want: "",
+		},
+		{
+			name:    "Not JSON",
+			content: ptr("not json"),
+			want:    "",
+		},
+		{
+			name:    "Missing file",
+			content: nil,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "adc.json")
+			if tt.content != nil {
+				require.NoError(t, os.WriteFile(file, []byte(*tt.content), 0o600))
+			}
+			t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+
+			got, err := newCredentialsQuotaSearcher().ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Well-known file", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+		t.Setenv("CLOUDSDK_CONFIG", dir)
+		content := `{"type":"authorized_user","quota_project_id":"gcp-id-test"}`
+		file := filepath.Join(dir, "application_default_credentials.json")
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+
+		got, err := newCredentialsQuotaSearcher().ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	})
+}
+
+func TestResolve_CredentialsQuotaProject(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		want       string
+		wantSource string
+	}{
+		{
+			name: "Quota project",
+			content: `{"type":"authorized_user","client_id":"id",` +
+				`"client_secret":"secret","refresh_token":"token",` +
+				`"quota_project_id":"gcp-id-test"}`,
+			want:       "gcp-id-test",
+			wantSource: "credentials:quota_project",
+		},
+		{
+			name: "Project ID preferred",
+			content: `{"type":"service_account","project_id":"gcp-id-test",` +
+				`"quota_project_id":"gcp-id-other"}`,
+			want:       "gcp-id-test",
+			wantSource: "credentials-file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, gcloudProjectPropertyKey)
+			unsetEnv(t, defaultEnvKeys...)
+			file := filepath.Join(t.TempDir(), "adc.json")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0o600))
+			t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+			useSearchers(t, func(o Options) []Searcher {
+				// Skip the gcloud CLI of the machine running the tests.
+				ss := DefaultSearchers(o)
+				return ss[:len(ss)-1]
+			})
+
+			r, err := Resolve(context.Background(), Options{Timeout: time.Second})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, r.ID)
+			assert.Equal(t, tt.wantSource, r.Source)
+		})
+	}
+}
+
+func TestDefaultSearchers_CredentialsQuotaProject(t *testing.T) {
+	has := func(o Options) bool {
+		for _, s := range DefaultSearchers(o) {
+			if sourceOf(s) == "credentials:quota_project" {
+				return true
+			}
+		}
+		return false
+	}
+	findCredentials := func(context.Context, ...string) (*google.Credentials, error) {
+		return &google.Credentials{}, nil
+	}
+
+	assert.True(t, has(Options{}))
+	assert.False(t, has(Options{FindCredentials: findCredentials}))
+}
@@ -0,0 +1,108 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// raceSearcher runs two searchers concurrently and returns the first
+// project ID found, canceling the other search. When both have found one by
+// the time the result is taken, the preferred searcher wins.
+type raceSearcher struct {
+	preferred Searcher
+	other     Searcher
+
+	// otherTimeout, if positive, bounds the search of the other searcher,
+	// so the race waits no longer for it than for a probe.
+	otherTimeout time.Duration
+}
+
+var _ Searcher = (*raceSearcher)(nil)
+
+func newRaceSearcher(preferred, other Searcher) *raceSearcher {
+	s := raceSearcher{
+		preferred: preferred,
+		other:     other,
+	}
+	return &s
+}
+
+// Source reports the source of the preferred searcher, as the race stands
+// in for it.
+func (s *raceSearcher) Source() string { return sourceOf(s.preferred) }
+
+func (s *raceSearcher) backingFile() string {
+	if f, ok := s.preferred.(fileSource); ok {
+		return f.backingFile()
+	}
+	return ""
+}
+
+type raceResult struct {
+	id  string
+	err error
+}
+
+func (s *raceSearcher) ProjectID(ctx context.Context, scopes ...string) (
+	string, error,
+) {
+	ctx, cancel := context.WithCancel(ctx)
+	// Cancels the search that lost. The channels are buffered, so its
+	// goroutine exits as soon as the searcher returns.
+	defer cancel()
+
+	run := func(searcher Searcher, timeout time.Duration) <-chan raceResult {
+		ch := make(chan raceResult, 1)
+		go func() {
+			ctx := ctx
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			id, err := searcher.ProjectID(ctx, scopes...)
+			ch <- raceResult{id: id, err: err}
+		}()
+		return ch
+	}
+	return firstResult(run(s.preferred, 0), run(s.other, s.otherTimeout))
+}
+
+// firstResult returns the first project ID received from the channels,
+// which receive one result each. If both have one when it's taken, the
+// preferred channel wins. When neither finds a project ID, the errors
+// received, if any, are returned joined.
+func firstResult(preferred, other <-chan raceResult) (string, error) {
+	var preferredErr, otherErr error
+	for preferred != nil || other != nil {
+		select {
+		case r := <-preferred:
+			if r.err == nil && r.id != "" {
+				return r.id, nil
+			}
+			preferred, preferredErr = nil, r.err
+		case r := <-other:
+			if r.err == nil && r.id != "" {
+				// On a tie, the preferred channel wins.
+				if p, ok := tryReceive(preferred); ok && p.err == nil && p.id != "" {
+					return p.id, nil
+				}
+				return r.id, nil
+			}
+			other, otherErr = nil, r.err
+		}
+	}
+	return "", errors.Join(preferredErr, otherErr)
+}
+
+// tryReceive receives from ch without blocking. A nil channel has nothing
+// to receive.
+func tryReceive(ch <-chan raceResult) (raceResult, bool) {
+	select {
+	case r := <-ch:
+		return r, true
+	default:
+		return raceResult{}, false
+	}
+}
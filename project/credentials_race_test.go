@@ -0,0 +1,182 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func Test_raceSearcher_ProjectID(t *testing.T) {
+	t.Run("Preferred answers first", func(t *testing.T) {
+		other := &blockingSearcherMock{done: make(chan struct{})}
+		s := newRaceSearcher(newNamedSearcherMock("credentials", "gcp-id-test"), other)
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		waitDone(t, other.done)
+	})
+
+	t.Run("Other answers first", func(t *testing.T) {
+		preferred := &blockingSearcherMock{done: make(chan struct{})}
+		s := newRaceSearcher(preferred, newNamedSearcherMock("metadata", "gcp-id-test"))
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		waitDone(t, preferred.done)
+	})
+
+	t.Run("Preferred finds nothing", func(t *testing.T) {
+		s := newRaceSearcher(
+			newNamedSearcherMock("credentials", ""),
+			newNamedSearcherMock("metadata", "gcp-id-test"),
+		)
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	})
+
+	t.Run("Other fails", func(t *testing.T) {
+		s := newRaceSearcher(
+			newNamedSearcherMock("credentials", "gcp-id-test"),
+			&searcherMock{wantError: true},
+		)
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	})
+
+	t.Run("Both fail", func(t *testing.T) {
+		errOther := errors.New("other error")
+		s := newRaceSearcher(
+			&searcherMock{wantError: true},
+			&namedSearcherErrMock{source: "metadata", err: errOther},
+		)
+
+		_, err := s.ProjectID(context.Background())
+
+		require.ErrorIs(t, err, errTest)
+		require.ErrorIs(t, err, errOther)
+	})
+
+	t.Run("Nothing found", func(t *testing.T) {
+		s := newRaceSearcher(
+			newNamedSearcherMock("credentials", ""),
+			newNamedSearcherMock("metadata", ""),
+		)
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		s := newRaceSearcher(newCredentialsSearcher(nil, ""), newMetadataSearcher(Options{}))
+
+		assert.Equal(t, "credentials", sourceOf(s))
+		assert.Equal(t, credentialsFile(), s.backingFile())
+	})
+}
+
+func Test_firstResult_Tie(t *testing.T) {
+	for range 100 {
+		preferred := make(chan raceResult, 1)
+		other := make(chan raceResult, 1)
+		preferred <- raceResult{id: "gcp-id-preferred"}
+		other <- raceResult{id: "gcp-id-other"}
+
+		got, err := firstResult(preferred, other)
+
+		require.NoError(t, err)
+		require.Equal(t, "gcp-id-preferred", got)
+	}
+}
+
+func TestID_RaceCredentials(t *testing.T) {
+	useSearchers(t, func(o Options) []Searcher {
+		return []Searcher{credentialsOrRace(o)}
+	})
+	useMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/computeMetadata/v1/project/project-id", r.URL.Path)
+		_, _ = w.Write([]byte("gcp-id-test"))
+	})
+	findCredentials := func(ctx context.Context, _ ...string) (*google.Credentials, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	opts := Options{
+		Timeout:         time.Second,
+		FindCredentials: findCredentials,
+		RaceCredentials: true,
+	}
+
+	r := <-IDAsync(opts)
+
+	require.NoError(t, r.Err)
+	assert.Equal(t, Result{ID: "gcp-id-test", Source: "credentials", Found: true}, r)
+}
+
+// blockingSearcherMock blocks until the context it's called with is done,
+// and then closes done.
+type blockingSearcherMock struct {
+	done chan struct{}
+}
+
+var _ Searcher = (*blockingSearcherMock)(nil)
+
+func (s *blockingSearcherMock) ProjectID(ctx context.Context, _ ...string) (
+	string, error,
+) {
+	defer close(s.done)
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+// waitDone fails the test if done is not closed soon.
+func waitDone(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("search not canceled")
+	}
+}
+
+func TestID_RaceCredentials_MetadataHangs(t *testing.T) {
+	useSearchers(t, func(o Options) []Searcher {
+		return []Searcher{credentialsOrRace(o)}
+	})
+	useMetadataClient(t, func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+	findCredentials := func(context.Context, ...string) (*google.Credentials, error) {
+		return &google.Credentials{}, nil
+	}
+	opts := Options{
+		Timeout:         30 * time.Second,
+		FindCredentials: findCredentials,
+		RaceCredentials: true,
+	}
+
+	start := time.Now()
+	r := <-IDAsync(opts)
+
+	require.NoError(t, r.Err)
+	assert.False(t, r.Found)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,31 @@
+package project
+
+// defaultDeployProjectPath is the dotted path used when the
+// DeployProjectPath option is empty: the project of the Cloud Run deployer
+// of a skaffold.yaml.
+const defaultDeployProjectPath = "deploy.cloudrun.projectid"
+
+// Deploy Config Searcher
+
+// deploySearcher reads the project ID from a Skaffold or Cloud Deploy
+// configuration, like skaffold.yaml or clouddeploy.yaml, the same way the
+// yamlSearcher reads it from a YAML file.
+type deploySearcher struct {
+	*yamlSearcher
+}
+
+var _ Searcher = (*deploySearcher)(nil)
+
+func newDeploySearcher(
+	file, projectPath string, unmarshal func([]byte, any) error,
+) *deploySearcher {
+	if projectPath == "" {
+		projectPath = defaultDeployProjectPath
+	}
+	s := deploySearcher{
+		yamlSearcher: newYAMLSearcher(file, projectPath, unmarshal),
+	}
+	return &s
+}
+
+func (*deploySearcher) Source() string { return "deploy-config" }
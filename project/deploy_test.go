@@ -0,0 +1,117 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// skaffoldConfig is a sample skaffold.yaml, in JSON, which json.Unmarshal
+// decodes as a YAML decoder would.
+const skaffoldConfig = `{
+	"apiVersion": "skaffold/v4beta11",
+	"kind": "Config",
+	"metadata": {"name": "app"},
+	"build": {"artifacts": [{"image": "app"}]},
+	"manifests": {"rawYaml": ["service.yaml"]},
+	"deploy": {"cloudrun": {"projectid": "gcp-id-test", "region": "us-east1"}}
+}`
+
+func Test_deploySearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		projectPath string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:    "Default path",
+			content: skaffoldConfig,
+			want:    "gcp-id-test",
+		},
+		{
+			name:        "Custom path",
+			content:     `{"deploy": {"gke": {"project": "gcp-id-test"}}}`,
+			projectPath: "deploy.gke.project",
+			want:        "gcp-id-test",
+		},
+		{
+			name:        "Missing field",
+			content:     skaffoldConfig,
+			projectPath: "deploy.gke.project",
+			want:        "",
+		},
+		{
+			name:        "Commented value",
+			content:     `{"deploy": {"gke": {"project": "gcp-id-test # production"}}}`,
+			projectPath: "deploy.gke.project",
+			want:        "",
+		},
+		{
+			name:    "No deployer",
+			content: `{"apiVersion": "skaffold/v4beta11", "kind": "Config"}`,
+			want:    "",
+		},
+		{
+			name:        "Not a string",
+			content:     skaffoldConfig,
+			projectPath: "deploy.cloudrun",
+			wantErr:     true,
+		},
+		{
+			name:    "Malformed",
+			content: `{"deploy":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "skaffold.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0o600))
+			s := newDeploySearcher(file, tt.projectPath, json.Unmarshal)
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_deploySearcher_ProjectID_MissingFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "skaffold.yaml")
+	s := newDeploySearcher(file, "", json.Unmarshal)
+
+	got, err := s.ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestID_DeployConfigFile(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	file := filepath.Join(t.TempDir(), "skaffold.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(skaffoldConfig), 0o600))
+
+	opts := Options{
+		Timeout:          time.Second,
+		DeployConfigFile: file,
+		YAMLUnmarshal:    json.Unmarshal,
+	}
+	r := <-IDAsync(opts)
+
+	require.NoError(t, r.Err)
+	assert.Equal(t, Result{ID: "gcp-id-test", Source: "deploy-config", Found: true}, r)
+}
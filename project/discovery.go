@@ -0,0 +1,107 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// discoveryWarning is the SearchStep warning of a searcher skipped because
+// its discovery exceeded the DiscoveryTimeout option.
+func discoveryWarning(d time.Duration) string {
+	return fmt.Sprintf("skipped: discovery exceeded the DiscoveryTimeout of %v", d)
+}
+
+// discoveries are the discoveries in flight, by key, shared by all the
+// searches in the process.
+var discoveries sync.Map
+
+// discovery is a discovery in flight. Its result is set before done is
+// closed.
+type discovery struct {
+	done   chan struct{}
+	result any
+}
+
+// discover runs fn, the discovery identified by key, in the background, or
+// joins the one with the same key already in flight, and waits for it
+// within the budget of d derived from ctx. It returns the result of fn,
+// and whether it finished in time. The filesystem calls can't be
+// interrupted, so, when late, they're left to finish in the background,
+// but at most one discovery runs per key: stalled ones don't pile up
+// across the searches. A finished discovery is forgotten, so the next
+// search discovers again.
+func discover(
+	ctx context.Context, key string, d time.Duration, fn func() any,
+) (
+	any, bool,
+) {
+	dv := &discovery{done: make(chan struct{})}
+	if v, loaded := discoveries.LoadOrStore(key, dv); loaded {
+		dv = v.(*discovery)
+	} else {
+		go func() {
+			dv.result = fn()
+			discoveries.Delete(key)
+			close(dv.done)
+		}()
+	}
+	if !waitDiscovery(ctx, d, dv.done) {
+		return nil, false
+	}
+	return dv.result, true
+}
+
+// waitDiscovery waits for done, closed when a discovery finishes, under
+// its own budget of d derived from ctx, and reports whether it finished in
+// time. When d is not positive, it's bounded by ctx alone.
+func waitDiscovery(ctx context.Context, d time.Duration, done <-chan struct{}) bool {
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// skipUndiscovered reports whether the searcher is to be skipped, with the
+// DiscoveryTimeout option, because finding its files, and statting them,
+// took too long, as on a stalled network filesystem. The step being
+// searched, if any, is warned. The application default credentials are
+// never skipped: they fall back to the metadata server, which is no file
+// lookup.
+func skipUndiscovered(ctx context.Context, o Options, s Searcher) bool {
+	_, file := s.(fileSource)
+	_, files := s.(filesSource)
+	if !file && !files || o.DiscoveryTimeout <= 0 {
+		return false
+	}
+	switch s.(type) {
+	case *credentialsSearcher, *raceSearcher:
+		return false
+	}
+	// The seam is read here, not in the background, where tests may have
+	// replaced it by the time the discovery ends.
+	statFile := stat
+	_, ok := discover(ctx, "file:"+sourceOf(s), o.DiscoveryTimeout, func() any {
+		for _, file := range searcherFiles(s) {
+			if file != "" {
+				_, _ = statFile(file)
+			}
+		}
+		return nil
+	})
+	if ok {
+		return false
+	}
+	if step := stepFromContext(ctx); step != nil {
+		step.Warning = discoveryWarning(o.DiscoveryTimeout)
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package project
+
+import (
+	"context"
+	"io/fs"
+	"os/exec"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func TestResolve_DiscoveryTimeout_File(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	replace(t, &stat, func(string) (fs.FileInfo, error) {
+		// A stalled network filesystem.
+		started <- struct{}{}
+		<-release
+		return nil, fs.ErrNotExist
+	})
+	t.Cleanup(func() { close(release) })
+	file := &countingSearcherMock{projectID: "gcp-id-file", file: "/nfs/home/config"}
+	o := Options{
+		Timeout:          time.Second,
+		DiscoveryTimeout: 10 * time.Millisecond,
+		Searchers:        []Searcher{file, newNamedSearcherMock("remote", "gcp-id-test")},
+	}
+
+	r, err := Resolve(context.Background(), o)
+	<-started
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "remote", r.Source)
+	assert.Zero(t, file.calls)
+	require.Len(t, r.Trace, 2)
+	assert.Contains(t, r.Trace[0].Warning, "DiscoveryTimeout")
+}
+
+func TestResolve_DiscoveryTimeout_Repeated(t *testing.T) {
+	var stalled atomic.Int32
+	release := make(chan struct{})
+	replace(t, &stat, func(string) (fs.FileInfo, error) {
+		stalled.Add(1)
+		<-release
+		return nil, fs.ErrNotExist
+	})
+	t.Cleanup(func() { close(release) })
+	o := Options{
+		Timeout:          time.Second,
+		DiscoveryTimeout: time.Millisecond,
+		Searchers: []Searcher{
+			newYAMLSearcher("/nfs/home/config.yaml", "", nil),
+			newKCCSearcher("/nfs/home/annotations"),
+			newNamedSearcherMock("remote", "gcp-id-test"),
+		},
+	}
+	before := runtime.NumGoroutine()
+
+	for range 20 {
+		r, err := Resolve(context.Background(), o)
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", r.ID)
+	}
+
+	// One discovery per file source, joined by the later searches.
+	assert.EqualValues(t, 2, stalled.Load())
+	assert.LessOrEqual(t, runtime.NumGoroutine()-before, 2)
+}
+
+func TestResolve_DiscoveryTimeout_Credentials(t *testing.T) {
+	release := make(chan struct{})
+	replace(t, &stat, func(string) (fs.FileInfo, error) {
+		<-release
+		return nil, fs.ErrNotExist
+	})
+	t.Cleanup(func() { close(release) })
+	credentials := newCredentialsSearcher(
+		func(context.Context, ...string) (*google.Credentials, error) {
+			return &google.Credentials{ProjectID: "gcp-id-test"}, nil
+		}, "")
+	o := Options{
+		Timeout:          time.Second,
+		DiscoveryTimeout: time.Millisecond,
+		Searchers:        []Searcher{credentials},
+	}
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Empty(t, r.Trace[0].Warning)
+}
+
+func TestResolve_DiscoveryTimeout_FileInTime(t *testing.T) {
+	file := &countingSearcherMock{projectID: "gcp-id-test", file: "/nfs/home/config"}
+	o := Options{
+		Timeout:          time.Second,
+		DiscoveryTimeout: time.Second,
+		Searchers:        []Searcher{file},
+	}
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, 1, file.calls)
+	assert.Empty(t, r.Trace[0].Warning)
+}
+
+func Test_gcloudSearcher_ProjectID_DiscoveryTimeout(t *testing.T) {
+	release := make(chan struct{})
+	s := &gcloudSearcher{
+		discover: func() ([]string, [][]string) {
+			// A stalled PATH lookup.
+			<-release
+			return []string{"gcloud"}, nil
+		},
+		discoveryTimeout: 10 * time.Millisecond,
+		output: func(*exec.Cmd, int) ([]byte, error) {
+			return []byte("gcp-id-test\n"), nil
+		},
+	}
+
+	var step SearchStep
+	got, err := s.ProjectID(withStep(context.Background(), &step))
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Contains(t, step.Warning, "DiscoveryTimeout")
+
+	// The discovery goes on, for the later searches.
+	close(release)
+	got, err = s.ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", got)
+}
+
+func Test_gcloudSearcher_ProjectID_DiscoveryCanceled(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	s := &gcloudSearcher{
+		discover: func() ([]string, [][]string) {
+			<-release
+			return nil, nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := s.ProjectID(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestResolve_DiscoveryTimeout_BotoAndScan(t *testing.T) {
+	release := make(chan struct{})
+	replace(t, &stat, func(string) (fs.FileInfo, error) {
+		<-release
+		return nil, fs.ErrNotExist
+	})
+	t.Cleanup(func() { close(release) })
+	t.Setenv("BOTO_CONFIG", "/nfs/home/.boto")
+	t.Setenv("CLOUDSDK_CONFIG", "/nfs/home/.config/gcloud")
+	boto := newBotoSearcher()
+	scan := newGCloudConfigScanSearcher()
+	o := Options{
+		Timeout:          time.Second,
+		DiscoveryTimeout: 10 * time.Millisecond,
+		Searchers:        []Searcher{boto, scan, newNamedSearcherMock("remote", "gcp-id-test")},
+	}
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	require.Len(t, r.Trace, 3)
+	assert.Contains(t, r.Trace[0].Warning, "DiscoveryTimeout")
+	assert.Contains(t, r.Trace[1].Warning, "DiscoveryTimeout")
+}
@@ -0,0 +1,117 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxDiskCacheSize bounds the size of the disk cache file read.
+const maxDiskCacheSize = 4 << 10
+
+// diskCacheEntry is the content of the disk cache file.
+type diskCacheEntry struct {
+	Key     string    `json:"key"`
+	ID      string    `json:"id"`
+	Number  string    `json:"number,omitempty"`
+	Source  string    `json:"source"`
+	Expires time.Time `json:"expires"`
+}
+
+// diskCachePath returns the disk cache file for the options.
+func diskCachePath(o Options) string {
+	if o.DiskCachePath != "" {
+		return o.DiskCachePath
+	}
+	return filepath.Join(os.TempDir(),
+		fmt.Sprintf("gcp-project-id-%d.json", os.Getuid()))
+}
+
+// diskCacheKey identifies the options, and the environment they're
+// searched with, so a process resolving differently doesn't reuse the
+// entry of another.
+func diskCacheKey(o Options) string {
+	h := sha256.New()
+	for _, v := range []string{cacheKey(o), envSnapshot(o)} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// diskCacheable reports whether a result searched with the options can be
+// shared on disk. The functions and Searchers options are only told apart
+// by their address, which another process can't match, so options setting
+// them are never cached on disk.
+func diskCacheable(o Options) bool {
+	return len(o.Searchers) == 0 && o.Validator == nil &&
+		o.FindCredentials == nil && o.PostResolve == nil &&
+		o.GCloudParse == nil && o.RemoteConfig == nil &&
+		o.YAMLUnmarshal == nil && o.Decryptor == nil
+}
+
+// diskCacheGet returns the result cached on disk for the options, if
+// there's a valid one. Missing, stale, corrupt or untrusted files are a
+// miss.
+func diskCacheGet(o Options) (Result, bool) {
+	file := diskCachePath(o)
+	info, err := stat(file)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxDiskCacheSize ||
+		info.Mode().Perm()&0o022 != 0 || !ownedByUser(info) {
+		return Result{}, false
+	}
+	b, err := readFile(file)
+	if err != nil {
+		return Result{}, false
+	}
+	var e diskCacheEntry
+	if err = json.Unmarshal(b, &e); err != nil {
+		return Result{}, false
+	}
+	if e.Key != diskCacheKey(o) || !now().Before(e.Expires) ||
+		sanitizeValue(e.ID) == "" {
+		return Result{}, false
+	}
+	r := Result{
+		ID:     e.ID,
+		Number: canonicalProjectNumber(e.Number),
+		Source: e.Source,
+		Found:  true,
+	}
+	return r, true
+}
+
+// diskCachePut caches the result on disk for the options. It's best
+// effort: failures are ignored, and only cost a search to a later process.
+// The file is replaced atomically, so concurrent processes never read a
+// partial entry.
+func diskCachePut(o Options, r Result) {
+	b, err := json.Marshal(diskCacheEntry{
+		Key:     diskCacheKey(o),
+		ID:      r.ID,
+		Number:  r.Number,
+		Source:  r.Source,
+		Expires: now().Add(o.CacheTTL),
+	})
+	if err != nil {
+		return
+	}
+	file := diskCachePath(o)
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	_ = os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,245 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestID_DiskCache(t *testing.T) {
+	t.Run("Cached across processes until the TTL expires", func(t *testing.T) {
+		clock := useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := diskCacheOptions(t)
+
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		cache.clear() // A new process.
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		assert.Equal(t, 1, s.calls)
+
+		cache.clear()
+		*clock = clock.Add(time.Minute)
+
+		assert.Equal(t, "gcp-id-test", ID(opts))
+		assert.Equal(t, 2, s.calls)
+	})
+
+	t.Run("Result is kept", func(t *testing.T) {
+		useCache(t)
+		t.Setenv("BUILD_ID", "build-id")
+		t.Setenv("PROJECT_ID", "gcp-id-test")
+		t.Setenv("PROJECT_NUMBER", "0123")
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newCloudBuildSearcher()}
+		})
+		opts := diskCacheOptions(t)
+
+		ID(opts)
+		cache.clear()
+		got := <-IDAsync(opts)
+
+		want := Result{
+			ID:     "gcp-id-test",
+			Number: "123",
+			Source: "cloud-build",
+			Found:  true,
+		}
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("Different scopes", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := diskCacheOptions(t)
+
+		ID(opts)
+		cache.clear()
+		opts.Scopes = []string{"scope-a"}
+		ID(opts)
+
+		assert.Equal(t, 2, s.calls)
+	})
+
+	t.Run("Different environment", func(t *testing.T) {
+		useCache(t)
+		t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := diskCacheOptions(t)
+
+		ID(opts)
+		cache.clear()
+		t.Setenv("GOOGLE_CLOUD_PROJECT", "gcp-id-changed")
+		ID(opts)
+
+		assert.Equal(t, 2, s.calls)
+	})
+
+	t.Run("Not cached without a TTL", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := diskCacheOptions(t)
+		opts.CacheTTL = 0
+
+		ID(opts)
+		ID(opts)
+
+		assert.Equal(t, 2, s.calls)
+		assert.NoFileExists(t, opts.DiskCachePath)
+	})
+
+	t.Run("Not found is not cached", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := diskCacheOptions(t)
+
+		ID(opts)
+
+		assert.NoFileExists(t, opts.DiskCachePath)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		useCache(t)
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("a", "Bad_ID")}
+		})
+		opts := diskCacheOptions(t)
+
+		assert.Equal(t, "Bad_ID", ID(opts))
+		cache.clear()
+		opts.Validate = true
+		_, err := IDContext(context.Background(), opts)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Not cached with function options", func(t *testing.T) {
+		useCache(t)
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := diskCacheOptions(t)
+		opts.Validator = func(string) error { return nil }
+
+		ID(opts)
+
+		assert.NoFileExists(t, opts.DiskCachePath)
+	})
+
+	t.Run("Not cached with searchers", func(t *testing.T) {
+		useCache(t)
+		opts := diskCacheOptions(t)
+		opts.Searchers = []Searcher{newNamedSearcherMock("a", "gcp-id-test")}
+
+		ID(opts)
+
+		assert.NoFileExists(t, opts.DiskCachePath)
+	})
+}
+
+func TestID_DiskCacheInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		entry   func(e *diskCacheEntry)
+		perm    os.FileMode
+	}{
+		{name: "Corrupt", content: `{"key": `, perm: 0o600},
+		{name: "Not JSON", content: "gcp-id-cached", perm: 0o600},
+		{name: "Empty", content: "", perm: 0o600},
+		{name: "Other key", perm: 0o600, entry: func(e *diskCacheEntry) {
+			e.Key = "other"
+		}},
+		{name: "Expired", perm: 0o600, entry: func(e *diskCacheEntry) {
+			e.Expires = now()
+		}},
+		{name: "Invalid ID", perm: 0o600, entry: func(e *diskCacheEntry) {
+			e.ID = "gcp-id\x00cached"
+		}},
+		{name: "Too large", perm: 0o600, entry: func(e *diskCacheEntry) {
+			e.Source = strings.Repeat("x", maxDiskCacheSize)
+		}},
+		{name: "World writable", perm: 0o666},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.perm&0o022 != 0 && runtime.GOOS == "windows" {
+				t.Skip("permissions are not checked on Windows")
+			}
+			useCache(t)
+			s := &countingSearcherMock{projectID: "gcp-id-test"}
+			useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+			opts := diskCacheOptions(t)
+			content := tt.content
+			if content == "" && tt.name != "Empty" {
+				content = diskCacheEntryJSON(t, opts, tt.entry)
+			}
+			require.NoError(t, os.WriteFile(opts.DiskCachePath, []byte(content), tt.perm))
+			require.NoError(t, os.Chmod(opts.DiskCachePath, tt.perm))
+
+			assert.Equal(t, "gcp-id-test", ID(opts))
+			assert.Equal(t, 1, s.calls)
+
+			// The file is replaced with a valid entry.
+			cache.clear()
+			assert.Equal(t, "gcp-id-test", ID(opts))
+			assert.Equal(t, 1, s.calls)
+		})
+	}
+}
+
+func Test_diskCachePath(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		got := diskCachePath(Options{})
+
+		assert.Equal(t, os.TempDir(), filepath.Dir(got))
+		assert.Regexp(t, `^gcp-project-id-\S+\.json$`, filepath.Base(got))
+	})
+
+	t.Run("Option", func(t *testing.T) {
+		got := diskCachePath(Options{DiskCachePath: "/cache/project.json"})
+
+		assert.Equal(t, "/cache/project.json", got)
+	})
+}
+
+// diskCacheOptions returns options caching on disk in a file of the test's
+// temporary directory.
+func diskCacheOptions(t *testing.T) Options {
+	t.Helper()
+	return Options{
+		Timeout:       time.Second,
+		CacheTTL:      time.Minute,
+		DiskCache:     true,
+		DiskCachePath: filepath.Join(t.TempDir(), "project.json"),
+	}
+}
+
+// diskCacheEntryJSON returns a valid disk cache entry for the options,
+// changed with fn when not nil.
+func diskCacheEntryJSON(t *testing.T, o Options, fn func(e *diskCacheEntry)) string {
+	t.Helper()
+	e := diskCacheEntry{
+		Key:     diskCacheKey(o),
+		ID:      "gcp-id-cached",
+		Source:  "env",
+		Expires: now().Add(time.Hour),
+	}
+	if fn != nil {
+		fn(&e)
+	}
+	b, err := json.Marshal(e)
+	require.NoError(t, err)
+	return string(b)
+}
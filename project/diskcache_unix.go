@@ -0,0 +1,16 @@
+//go:build unix
+
+package project
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// ownedByUser reports whether the file is owned by the current user, so an
+// entry planted by another user in a shared directory is not trusted.
+func ownedByUser(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return !ok || int(st.Uid) == os.Getuid()
+}
@@ -0,0 +1,99 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Encrypted Config Searcher
+
+// encryptedSearcher reads the project ID from an encrypted configuration
+// file, like one managed with SOPS or Berglas, decrypted by the caller's
+// decryptor so this package doesn't depend on a crypto library. The
+// plaintext is either a JSON object or KEY=VALUE assignments, as in an env
+// file, where the keys searched are looked up. JSON objects may also carry
+// the project ID as `project_id`, as in the credentials files.
+type encryptedSearcher struct {
+	file    string
+	keys    []string
+	decrypt func(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+var _ Searcher = (*encryptedSearcher)(nil)
+
+func newEncryptedSearcher(
+	file string,
+	decrypt func(context.Context, []byte) ([]byte, error),
+	keys ...string,
+) *encryptedSearcher {
+	s := encryptedSearcher{
+		file:    file,
+		keys:    keys,
+		decrypt: decrypt,
+	}
+	return &s
+}
+
+func (*encryptedSearcher) Source() string { return "encrypted-config" }
+
+func (s *encryptedSearcher) backingFile() string { return s.file }
+
+func (s *encryptedSearcher) ProjectID(ctx context.Context, _ ...string) (string, error) {
+	if s.decrypt == nil {
+		return "", errors.New("read encrypted config: Decryptor not set")
+	}
+	b, err := readFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read encrypted config: %w", err)
+	}
+	plaintext, err := s.decrypt(ctx, b)
+	if err != nil {
+		return "", fmt.Errorf("decrypt encrypted config %s: %w", s.file, err)
+	}
+	id, err := s.parse(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("parse encrypted config %s: %w", s.file, err)
+	}
+	return id, nil
+}
+
+// parse returns the project ID of the plaintext, a JSON object when it
+// starts with '{' and KEY=VALUE assignments otherwise.
+func (s *encryptedSearcher) parse(plaintext []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(plaintext), []byte("{")) {
+		env := parseEnvFile(plaintext)
+		for _, key := range s.keys {
+			if id := env[key]; id != "" {
+				return id, nil
+			}
+		}
+		return "", nil
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(plaintext, &doc); err != nil {
+		return "", err
+	}
+	keys := append(s.keys[:len(s.keys):len(s.keys)], "project_id")
+	for _, key := range keys {
+		switch v := doc[key].(type) {
+		case nil:
+			continue
+		case string:
+			if id := sanitizeValue(strings.TrimSpace(v)); id != "" {
+				return id, nil
+			}
+		default:
+			return "", fmt.Errorf("%s: expected a string, got %T", key, v)
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,158 @@
+package project
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_encryptedSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name      string
+		plaintext string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "Env",
+			plaintext: "PATH=/usr/bin\nGOOGLE_CLOUD_PROJECT=gcp-id-test\n",
+			want:      "gcp-id-test",
+		},
+		{
+			name:      "Env with quotes",
+			plaintext: `export GOOGLE_CLOUD_PROJECT="gcp-id-test"`,
+			want:      "gcp-id-test",
+		},
+		{
+			name:      "Env without the key",
+			plaintext: "PATH=/usr/bin\n",
+			want:      "",
+		},
+		{
+			name:      "JSON",
+			plaintext: `{"GOOGLE_CLOUD_PROJECT": "gcp-id-test", "token": "secret"}`,
+			want:      "gcp-id-test",
+		},
+		{
+			name:      "JSON project_id",
+			plaintext: ` {"project_id": "gcp-id-test"}`,
+			want:      "gcp-id-test",
+		},
+		{
+			name:      "JSON keys first",
+			plaintext: `{"project_id": "gcp-id-other", "GOOGLE_CLOUD_PROJECT": "gcp-id-test"}`,
+			want:      "gcp-id-test",
+		},
+		{
+			name:      "JSON without the key",
+			plaintext: `{"token": "secret"}`,
+			want:      "",
+		},
+		{
+			name:      "JSON not a string",
+			plaintext: `{"project_id": 123}`,
+			wantErr:   true,
+		},
+		{
+			name:      "Malformed JSON",
+			plaintext: `{"project_id": `,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeEncrypted(t, tt.plaintext)
+			s := newEncryptedSearcher(file, fakeDecrypt, "GOOGLE_CLOUD_PROJECT")
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), file)
+				assert.NotContains(t, err.Error(), "secret")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Decrypt error", func(t *testing.T) {
+		errDecrypt := errors.New("no key")
+		file := writeEncrypted(t, "GOOGLE_CLOUD_PROJECT=gcp-id-test")
+		decrypt := func(context.Context, []byte) ([]byte, error) { return nil, errDecrypt }
+		s := newEncryptedSearcher(file, decrypt, "GOOGLE_CLOUD_PROJECT")
+
+		_, err := s.ProjectID(context.Background())
+
+		require.ErrorIs(t, err, errDecrypt)
+		assert.Contains(t, err.Error(), "decrypt")
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "missing.enc")
+		s := newEncryptedSearcher(file, fakeDecrypt, "GOOGLE_CLOUD_PROJECT")
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("No decryptor", func(t *testing.T) {
+		file := writeEncrypted(t, "GOOGLE_CLOUD_PROJECT=gcp-id-test")
+		s := newEncryptedSearcher(file, nil, "GOOGLE_CLOUD_PROJECT")
+
+		_, err := s.ProjectID(context.Background())
+
+		require.ErrorContains(t, err, "Decryptor not set")
+	})
+}
+
+func TestResolve_EncryptedConfigFile(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	file := writeEncrypted(t, "GCP_PROJECT=gcp-id-test")
+	useSearchers(t, func(o Options) []Searcher {
+		var ss []Searcher
+		for _, s := range DefaultSearchers(o) {
+			if src := sourceOf(s); src == "env" || src == "encrypted-config" {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	})
+	opts := Options{
+		Timeout:             time.Second,
+		EncryptedConfigFile: file,
+		Decryptor:           fakeDecrypt,
+	}
+
+	r, err := Resolve(context.Background(), opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "encrypted-config", r.Source)
+}
+
+// fakeDecrypt "decrypts" base64 encoded content.
+func fakeDecrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(string(ciphertext))
+}
+
+// writeEncrypted writes the plaintext, "encrypted" for fakeDecrypt, to a
+// file of the test's temporary directory and returns its path.
+func writeEncrypted(t *testing.T, plaintext string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.enc")
+	ciphertext := base64.StdEncoding.EncodeToString([]byte(plaintext))
+	require.NoError(t, os.WriteFile(file, []byte(ciphertext), 0o600))
+	return file
+}
@@ -0,0 +1,54 @@
+package project
+
+import "context"
+
+// EnvLookup is an environment variable searched with the EnvChain option,
+// with the project ID to use when it's not set.
+type EnvLookup struct {
+	// Key is the name of the environment variable.
+	Key string
+
+	// Default, if set, is the project ID used when Key is not set. The
+	// lookups after it are then never searched.
+	Default string
+}
+
+// Env Chain Searcher
+
+// envChainSearcher searches the environment variables of the EnvChain
+// option, in order, stopping at the first one set or with a default.
+type envChainSearcher struct {
+	chain []EnvLookup
+}
+
+var _ Searcher = (*envChainSearcher)(nil)
+
+func newEnvChainSearcher(chain []EnvLookup) *envChainSearcher {
+	s := envChainSearcher{
+		chain: chain,
+	}
+	return &s
+}
+
+func (*envChainSearcher) Source() string { return "env" }
+
+func (s *envChainSearcher) ProjectID(context.Context, ...string) (string, error) {
+	for _, l := range s.chain {
+		if id := normalizeEnvValue(lookupEnv(l.Key)); id != "" {
+			return id, nil
+		}
+		if id := normalizeEnvValue(l.Default); id != "" {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// envChainKeys returns the keys of the chain, in order.
+func envChainKeys(chain []EnvLookup) []string {
+	keys := make([]string, 0, len(chain))
+	for _, l := range chain {
+		keys = append(keys, l.Key)
+	}
+	return keys
+}
@@ -0,0 +1,133 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_envChainSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		chain []EnvLookup
+		want  string
+	}{
+		{
+			name: "First set",
+			env:  map[string]string{"APP_GCP_PROJECT": "gcp-id-app", "GCP_PROJECT": "gcp-id-test"},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT"},
+				{Key: "GCP_PROJECT"},
+			},
+			want: "gcp-id-app",
+		},
+		{
+			name: "Next set",
+			env:  map[string]string{"GCP_PROJECT": "gcp-id-test"},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT"},
+				{Key: "GCP_PROJECT", Default: "gcp-id-default"},
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name: "Default when not set",
+			env:  map[string]string{},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT"},
+				{Key: "GCP_PROJECT", Default: "gcp-id-default"},
+			},
+			want: "gcp-id-default",
+		},
+		{
+			name: "Default ends the chain",
+			env:  map[string]string{"GCP_PROJECT": "gcp-id-test"},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT", Default: "gcp-id-default"},
+				{Key: "GCP_PROJECT"},
+			},
+			want: "gcp-id-default",
+		},
+		{
+			name: "Set value over its default",
+			env:  map[string]string{"APP_GCP_PROJECT": "gcp-id-app"},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT", Default: "gcp-id-default"},
+			},
+			want: "gcp-id-app",
+		},
+		{
+			name: "Empty value",
+			env:  map[string]string{"APP_GCP_PROJECT": " "},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT"},
+				{Key: "GCP_PROJECT", Default: "gcp-id-default"},
+			},
+			want: "gcp-id-default",
+		},
+		{
+			name: "Normalized default",
+			env:  map[string]string{},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT", Default: ` "gcp-id-default" `},
+			},
+			want: "gcp-id-default",
+		},
+		{
+			name: "Nothing",
+			env:  map[string]string{},
+			chain: []EnvLookup{
+				{Key: "APP_GCP_PROJECT"},
+				{Key: "GCP_PROJECT"},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, "APP_GCP_PROJECT", "GCP_PROJECT")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := newEnvChainSearcher(tt.chain).ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve_EnvChain(t *testing.T) {
+	unsetEnv(t, "APP_GCP_PROJECT", "GCLOUD_PROJECT")
+	t.Setenv("GCP_PROJECT", "gcp-id-ignored")
+	useSearchers(t, func(o Options) []Searcher {
+		return []Searcher{envSearcher(o)}
+	})
+
+	r, err := Resolve(context.Background(), Options{
+		Timeout: time.Second,
+		EnvKeys: []string{"GCP_PROJECT"},
+		EnvChain: []EnvLookup{
+			{Key: "APP_GCP_PROJECT"},
+			{Key: "GCLOUD_PROJECT", Default: "gcp-id-default"},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-default", r.ID)
+	assert.Equal(t, "env", r.Source)
+}
+
+func Test_envKeys_EnvChain(t *testing.T) {
+	o := Options{
+		EnvPrefix: "APP",
+		EnvChain:  []EnvLookup{{Key: "APP_GCP_PROJECT"}, {Key: "GCP_PROJECT", Default: "x"}},
+	}
+
+	assert.Equal(t, []string{"APP_GCP_PROJECT", "GCP_PROJECT"}, envKeys(o))
+}
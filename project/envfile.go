@@ -0,0 +1,101 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// defaultSystemEnvFile is the environment file searched when the
+// SystemEnvFile option is empty.
+const defaultSystemEnvFile = "/etc/environment"
+
+// Env File Searcher
+
+// envFileSearcher reads the environment variables searched from a file of
+// KEY=VALUE assignments, like /etc/environment, for init systems that write
+// the environment to a file rather than export it. Assignments may also be
+// separated by NUL bytes, as in /proc/<pid>/environ.
+type envFileSearcher struct {
+	file string
+	keys []string
+}
+
+var _ Searcher = (*envFileSearcher)(nil)
+
+func newEnvFileSearcher(file string, keys ...string) *envFileSearcher {
+	if file == "" {
+		file = defaultSystemEnvFile
+	}
+	s := envFileSearcher{
+		file: file,
+		keys: keys,
+	}
+	return &s
+}
+
+func (*envFileSearcher) Source() string { return "env-file" }
+
+func (s *envFileSearcher) backingFile() string { return s.file }
+
+func (s *envFileSearcher) ProjectID(context.Context, ...string) (string, error) {
+	b, err := readFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read env file: %w", err)
+	}
+	env := parseEnvFile(b)
+	for _, key := range s.keys {
+		if id := env[key]; id != "" {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// parseEnvFile parses the KEY=VALUE assignments of an environment file,
+// one per line or NUL separated, into normalized values. Empty lines and
+// comments, starting with '#', are skipped, as is an `export` prefix. A
+// comment may also follow an unquoted value after a space. Later
+// assignments override earlier ones.
+func parseEnvFile(b []byte) map[string]string {
+	env := map[string]string{}
+	lines := strings.FieldsFunc(string(b), func(r rune) bool {
+		return r == '\n' || r == '\x00'
+	})
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		env[key] = normalizeEnvValue(envFileValue(value))
+	}
+	return env
+}
+
+// envFileValue returns the value of an assignment without its trailing
+// comment. Quoted values end at the closing quote, which is kept, for
+// normalizeEnvValue to trim, and are empty without one.
+func envFileValue(v string) string {
+	v = strings.TrimSpace(v)
+	if v != "" && (v[0] == '"' || v[0] == '\'') {
+		if end := strings.IndexByte(v[1:], v[0]); end >= 0 {
+			return v[:end+2]
+		}
+		return ""
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = v[:i]
+	}
+	return v
+}
@@ -0,0 +1,192 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "Plain",
+			content: "PATH=/usr/bin\nGCP_PROJECT=gcp-id-test\n",
+			want:    map[string]string{"PATH": "/usr/bin", "GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Double quotes",
+			content: `GCP_PROJECT="gcp-id-test"`,
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Single quotes",
+			content: `GCP_PROJECT='gcp-id-test'`,
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Quoted with a comment",
+			content: `GCP_PROJECT="gcp-id-test" # the project`,
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Unquoted with a comment",
+			content: "GCP_PROJECT=gcp-id-test # the project",
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Hash in the value",
+			content: "GCP_PROJECT=gcp-id#test",
+			want:    map[string]string{"GCP_PROJECT": "gcp-id#test"},
+		},
+		{
+			name:    "Comments and blank lines",
+			content: "# GCP_PROJECT=gcp-id-commented\n\n   \nGCP_PROJECT=gcp-id-test\n",
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Export",
+			content: "export GCP_PROJECT=gcp-id-test",
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Spaces around",
+			content: "  GCP_PROJECT = gcp-id-test  \r\n",
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Later overrides",
+			content: "GCP_PROJECT=gcp-id-old\nGCP_PROJECT=gcp-id-test",
+			want:    map[string]string{"GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "NUL separated",
+			content: "HOME=/root\x00GCP_PROJECT=gcp-id-test\x00",
+			want:    map[string]string{"HOME": "/root", "GCP_PROJECT": "gcp-id-test"},
+		},
+		{
+			name:    "Unterminated quote",
+			content: `GCP_PROJECT="gcp-id-test`,
+			want:    map[string]string{"GCP_PROJECT": ""},
+		},
+		{
+			name:    "Not an assignment",
+			content: "GCP_PROJECT\n=gcp-id-test\n",
+			want:    map[string]string{},
+		},
+		{
+			name:    "Empty",
+			content: "",
+			want:    map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseEnvFile([]byte(tt.content))
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_envFileSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		keys    []string
+		want    string
+	}{
+		{
+			name:    "First key set wins",
+			content: ptr("GOOGLE_CLOUD_PROJECT=gcp-id-other\nGCP_PROJECT=gcp-id-test\n"),
+			keys:    []string{"GCP_PROJECT", "GOOGLE_CLOUD_PROJECT"},
+			want:    "gcp-id-test",
+		},
+		{
+			name:    "Empty value is skipped",
+			content: ptr("GCP_PROJECT=\nGOOGLE_CLOUD_PROJECT=gcp-id-test\n"),
+			keys:    []string{"GCP_PROJECT", "GOOGLE_CLOUD_PROJECT"},
+			want:    "gcp-id-test",
+		},
+		{
+			name:    "Other keys only",
+			content: ptr("PROJECT=gcp-id-test\n"),
+			keys:    []string{"GCP_PROJECT"},
+			want:    "",
+		},
+		{
+			name:    "Missing file",
+			content: nil,
+			keys:    []string{"GCP_PROJECT"},
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "environment")
+			if tt.content != nil {
+				require.NoError(t, os.WriteFile(file, []byte(*tt.content), 0o600))
+			}
+			s := newEnvFileSearcher(file, tt.keys...)
+
+			got, err := s.ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Unreadable", func(t *testing.T) {
+		s := newEnvFileSearcher(t.TempDir(), "GCP_PROJECT")
+
+		_, err := s.ProjectID(context.Background())
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Default file", func(t *testing.T) {
+		s := newEnvFileSearcher("", "GCP_PROJECT")
+
+		assert.Equal(t, "/etc/environment", s.backingFile())
+	})
+}
+
+func TestResolve_SystemEnvFile(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	file := filepath.Join(t.TempDir(), "environment")
+	require.NoError(t, os.WriteFile(file, []byte("GCP_PROJECT=gcp-id-test\n"), 0o600))
+	opts := Options{
+		Timeout:          time.Second,
+		UseSystemEnvFile: true,
+		SystemEnvFile:    file,
+	}
+
+	r, err := Resolve(context.Background(), opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "env-file", r.Source)
+}
+
+func TestDefaultSearchers_SystemEnvFile(t *testing.T) {
+	has := func(o Options) bool {
+		for _, s := range DefaultSearchers(o) {
+			if sourceOf(s) == "env-file" {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.True(t, has(Options{UseSystemEnvFile: true}))
+	assert.False(t, has(Options{SystemEnvFile: "/etc/environment"}))
+}
@@ -0,0 +1,38 @@
+package project
+
+import "sync/atomic"
+
+// frozenID is the project ID pinned with Freeze, if any.
+var frozenID atomic.Pointer[string]
+
+// Freeze pins the project ID returned by ID, IDContext, IDAsync, TryID and
+// Resolve to id for the rest of the process lifetime, or until Unfreeze. It
+// takes precedence over all the sources, the Explicit option and the cache,
+// and the value is returned as is, with the "frozen" source, without
+// searching or applying the options. Freezing an empty id is the same as
+// calling Unfreeze.
+//
+// It's meant to make long-running services deterministic after a one-time
+// resolution, or to apply an admin override. It's safe for concurrent use.
+func Freeze(id string) {
+	if id == "" {
+		Unfreeze()
+		return
+	}
+	frozenID.Store(&id)
+}
+
+// Frozen returns the project ID pinned with Freeze, and whether there's
+// one.
+func Frozen() (string, bool) {
+	if p := frozenID.Load(); p != nil {
+		return *p, true
+	}
+	return "", false
+}
+
+// Unfreeze removes the project ID pinned with Freeze, so the searches run
+// again. It's mostly meant for tests. Shutdown doesn't unfreeze.
+func Unfreeze() {
+	frozenID.Store(nil)
+}
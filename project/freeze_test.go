@@ -0,0 +1,74 @@
+package project
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze(t *testing.T) {
+	t.Cleanup(Unfreeze)
+	useCache(t)
+	s := &countingSearcherMock{projectID: "gcp-id-test"}
+	useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+	opts := Options{Timeout: time.Second, CacheTTL: time.Minute}
+
+	assert.Equal(t, "gcp-id-test", ID(opts))
+	Freeze("gcp-id-frozen")
+
+	got, ok := Frozen()
+	assert.True(t, ok)
+	assert.Equal(t, "gcp-id-frozen", got)
+	assert.Equal(t, "gcp-id-frozen", ID(opts), "cache took precedence")
+	assert.Equal(t, "gcp-id-frozen", ID(Options{Explicit: "gcp-id-explicit"}))
+	id, found := TryID(opts)
+	assert.True(t, found)
+	assert.Equal(t, "gcp-id-frozen", id)
+	r, err := Resolve(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, Result{ID: "gcp-id-frozen", Source: "frozen", Found: true}, r)
+	assert.Equal(t, 1, s.calls)
+
+	Unfreeze()
+
+	_, ok = Frozen()
+	assert.False(t, ok)
+	assert.Equal(t, "gcp-id-test", ID(opts))
+}
+
+func TestFreeze_Empty(t *testing.T) {
+	t.Cleanup(Unfreeze)
+	Freeze("gcp-id-frozen")
+
+	Freeze("")
+
+	_, ok := Frozen()
+	assert.False(t, ok)
+}
+
+func TestFreeze_Concurrent(t *testing.T) {
+	t.Cleanup(Unfreeze)
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{newSearcherMock(true, false)}
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			Freeze("gcp-id-frozen")
+			Unfreeze()
+		}()
+		go func() {
+			defer wg.Done()
+			id := ID(Options{Timeout: time.Second})
+			assert.Contains(t, []string{"gcp-id-frozen", "gcp-project-id"}, id)
+		}()
+	}
+	wg.Wait()
+}
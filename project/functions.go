@@ -0,0 +1,72 @@
+package project
+
+// functionsGen2Order is the source order on Cloud Functions (2nd gen),
+// which runs on Cloud Run and doesn't set the project ID in the
+// environment: GOOGLE_CLOUD_PROJECT, when set by the deployment, then the
+// metadata server, which always has it.
+var functionsGen2Order = []string{"gcloud-property", "env", "metadata"}
+
+// functionsGen1Order is the source order on Cloud Functions (1st gen),
+// which sets GCP_PROJECT.
+var functionsGen1Order = []string{"env"}
+
+// functionsGeneration returns the Cloud Functions generation the process
+// runs on, 1 or 2, or 0 if it's not a function: 1st gen sets FUNCTION_NAME
+// and GCP_PROJECT, while 2nd gen, as Cloud Run services, sets
+// FUNCTION_TARGET and K_SERVICE.
+func functionsGeneration() int {
+	switch {
+	case getenv("FUNCTION_NAME") != "" && getenv("GCP_PROJECT") != "":
+		return 1
+	case getenv("FUNCTION_TARGET") != "" && getenv("K_SERVICE") != "":
+		return 2
+	}
+	return 0
+}
+
+// searchOrder returns the sources to search first, in order, for the
+// given options: the Order option, if set, or the most reliable order for
+// the Cloud Functions generation detected. It's nil otherwise, to keep the
+// default order.
+func searchOrder(o Options) []string {
+	if o.Order != nil {
+		return o.Order
+	}
+	switch functionsGeneration() {
+	case 1:
+		return functionsGen1Order
+	case 2:
+		return functionsGen2Order
+	}
+	return nil
+}
+
+// reorder returns the searchers of ss with the sources in order first, in
+// that order, followed by the others in their original order. The
+// metadata server, which is not searched on its own by default, is added
+// when it's in order, configured with the options.
+func reorder(ss []Searcher, order []string, o Options) []Searcher {
+	if len(order) == 0 {
+		return ss
+	}
+	s := make([]Searcher, 0, len(ss)+1)
+	picked := make([]bool, len(ss))
+	for _, source := range order {
+		found := false
+		for i, searcher := range ss {
+			if !picked[i] && sourceOf(searcher) == source {
+				s = append(s, searcher)
+				picked[i], found = true, true
+			}
+		}
+		if !found && source == "metadata" {
+			s = append(s, newMetadataSearcher(o))
+		}
+	}
+	for i, searcher := range ss {
+		if !picked[i] {
+			s = append(s, searcher)
+		}
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_defaultSearchers_Functions(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		order []string
+		want  []string
+	}{
+		{
+			name: "Not a function",
+			env:  map[string]string{},
+			want: []string{"gcloud-property", "env", "cloud-build"},
+		},
+		{
+			name: "1st gen",
+			env: map[string]string{
+				"FUNCTION_NAME": "fn",
+				"GCP_PROJECT":   "gcp-id-test",
+			},
+			want: []string{"env", "gcloud-property", "cloud-build"},
+		},
+		{
+			name: "2nd gen",
+			env: map[string]string{
+				"FUNCTION_TARGET": "Handler",
+				"K_SERVICE":       "fn",
+			},
+			want: []string{"gcloud-property", "env", "metadata", "cloud-build"},
+		},
+		{
+			name: "2nd gen, explicit order",
+			env: map[string]string{
+				"FUNCTION_TARGET": "Handler",
+				"K_SERVICE":       "fn",
+			},
+			order: []string{"credentials", "env"},
+			want:  []string{"credentials", "env", "gcloud-property", "cloud-build"},
+		},
+		{
+			name:  "Explicit metadata",
+			env:   map[string]string{},
+			order: []string{"metadata"},
+			want:  []string{"metadata", "gcloud-property", "env", "cloud-build"},
+		},
+		{
+			name:  "Unknown source",
+			env:   map[string]string{},
+			order: []string{"unknown"},
+			want:  []string{"gcloud-property", "env", "cloud-build"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &getenv, func(key string) string { return tt.env[key] })
+
+			ss := DefaultSearchers(Options{Order: tt.order})
+
+			var got []string
+			for _, s := range ss[:len(tt.want)] {
+				got = append(got, sourceOf(s))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_reorder(t *testing.T) {
+	a := newNamedSearcherMock("a", "")
+	b := newNamedSearcherMock("b", "")
+	c := newNamedSearcherMock("c", "")
+	ss := []Searcher{a, b, c}
+
+	assert.Equal(t, []Searcher{c, a, b}, reorder(ss, []string{"c"}, Options{}))
+	assert.Equal(t, []Searcher{b, a, c}, reorder(ss, []string{"b", "a"}, Options{}))
+	assert.Equal(t, []Searcher{a, b, c}, reorder(ss, nil, Options{}))
+	assert.Equal(t, []Searcher{a, b, c}, ss, "the input is not modified")
+}
@@ -0,0 +1,32 @@
+package project
+
+import "context"
+
+// Group runs functions concurrently and collects their errors, like
+// *errgroup.Group from golang.org/x/sync/errgroup.
+type Group interface {
+	Go(f func() error)
+}
+
+// ResolveInto enqueues the resolution of the default Google Cloud project
+// ID onto g, like Resolve, and stores the Result in out when it's done. The
+// error, if any, is returned to g, so startup code can fan out several
+// lookups, sharing one context and error handling:
+//
+//	g, ctx := errgroup.WithContext(ctx)
+//	var id project.Result
+//	project.ResolveInto(ctx, g, &id)
+//	g.Go(func() error { return lookupRegion(ctx, &region) })
+//	if err := g.Wait(); err != nil {
+//		// ...
+//	}
+//
+// out must not be read until g is done. Use the Strict option to fail the
+// group when no project ID is found.
+func ResolveInto(ctx context.Context, g Group, out *Result, opts ...Options) {
+	g.Go(func() error {
+		r, err := Resolve(ctx, opts...)
+		*out = r
+		return err
+	})
+}
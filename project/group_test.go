@@ -0,0 +1,111 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ExampleResolveInto() {
+	var (
+		g       waitGroup
+		ctx     = context.Background()
+		project Result
+		region  string
+	)
+	ResolveInto(ctx, &g, &project, Options{Explicit: "gcp-id-test"})
+	g.Go(func() error {
+		region = "us-central1"
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(project.ID, region)
+	// Output: gcp-id-test us-central1
+}
+
+func TestResolveInto(t *testing.T) {
+	t.Run("Found", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "gcp-id-test")}
+		})
+		var (
+			g   waitGroup
+			out Result
+		)
+
+		ResolveInto(context.Background(), &g, &out, Options{Timeout: time.Second})
+
+		require.NoError(t, g.Wait())
+		assert.Equal(t, "gcp-id-test", out.ID)
+		assert.Equal(t, "env", out.Source)
+		assert.Len(t, out.Trace, 1)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{&searcherMock{}}
+		})
+		var (
+			g   waitGroup
+			out Result
+		)
+
+		ResolveInto(context.Background(), &g, &out, Options{
+			Timeout: time.Second,
+			Strict:  true,
+		})
+
+		require.ErrorIs(t, g.Wait(), ErrProjectIDNotFound)
+		assert.ErrorIs(t, out.Err, ErrProjectIDNotFound)
+		assert.False(t, out.Found)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{&searcherMock{projectID: "gcp-id-test"}}
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var (
+			g   waitGroup
+			out Result
+		)
+
+		ResolveInto(ctx, &g, &out)
+
+		assert.ErrorIs(t, g.Wait(), context.Canceled)
+	})
+}
+
+// waitGroup is a minimal Group, like errgroup.Group, that returns the first
+// error of its functions.
+type waitGroup struct {
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+var _ Group = (*waitGroup)(nil)
+
+func (g *waitGroup) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.once.Do(func() { g.err = err })
+		}
+	}()
+}
+
+func (g *waitGroup) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
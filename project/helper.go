@@ -0,0 +1,62 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Credential Helper Searcher
+
+// credentialHelperSearcher runs a credential helper, a command that prints
+// its configuration as JSON, and reads its `project_id` field.
+type credentialHelperSearcher struct {
+	command []string
+	output  func(cmd *exec.Cmd, limit int) ([]byte, error)
+}
+
+var _ Searcher = (*credentialHelperSearcher)(nil)
+
+func newCredentialHelperSearcher(command []string) *credentialHelperSearcher {
+	s := credentialHelperSearcher{
+		command: command,
+		output:  cmdOutput,
+	}
+	return &s
+}
+
+func (*credentialHelperSearcher) Source() string { return "credential-helper" }
+
+func (s *credentialHelperSearcher) ProjectID(ctx context.Context, _ ...string) (
+	string, error,
+) {
+	if len(s.command) == 0 {
+		return "", nil
+	}
+	c := exec.CommandContext(ctx, s.command[0], s.command[1:]...)
+	b, err := s.output(c, defaultMaxOutput)
+	if err == nil {
+		err = checkOutputSize(b, defaultMaxOutput)
+	}
+	step := stepFromContext(ctx)
+	step.addAttempt(s.command[:1], s.command[1:], err)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The helper ran but has nothing for us, as when logged out.
+		step.addStderr(s.command[0], exitErr.Stderr)
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("run credential helper: %w", err)
+	}
+	var f struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err = json.Unmarshal(b, &f); err != nil {
+		return "", fmt.Errorf("parse credential helper output: %w", err)
+	}
+	return sanitizeValue(strings.TrimSpace(f.ProjectID)), nil
+}
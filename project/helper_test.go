@@ -0,0 +1,110 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_credentialHelperSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Project ID",
+			output: `{"project_id":"gcp-id-test","token":"secret"}`,
+			want:   "gcp-id-test",
+		},
+		{
+			name:   "Missing field",
+			output: `{"token":"secret"}`,
+			want:   "",
+		},
+		{
+			name: "Non-zero exit",
+			err:  &exec.ExitError{Stderr: []byte("not logged in")},
+			want: "",
+		},
+		{
+			name:    "Not JSON",
+			output:  "gcp-id-test",
+			wantErr: true,
+		},
+		{
+			name:    "Failed to run",
+			err:     exec.ErrNotFound,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotArgs []string
+			s := newCredentialHelperSearcher([]string{"helper", "get", "--json"})
+			s.output = func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = cmd.Args
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return []byte(tt.output), nil
+			}
+
+			got, err := s.ProjectID(context.Background())
+
+			assert.Equal(t, []string{"helper", "get", "--json"}, gotArgs)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Unset", func(t *testing.T) {
+		s := newCredentialHelperSearcher(nil)
+		s.output = func(*exec.Cmd, int) ([]byte, error) {
+			return nil, errors.New("unexpected run")
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Stderr", func(t *testing.T) {
+		s := newCredentialHelperSearcher([]string{"helper"})
+		s.output = func(*exec.Cmd, int) ([]byte, error) {
+			return nil, &exec.ExitError{Stderr: []byte("not logged in\n")}
+		}
+		var step SearchStep
+
+		_, err := s.ProjectID(withStep(context.Background(), &step))
+
+		require.NoError(t, err)
+		assert.Equal(t, "helper: not logged in", step.Stderr)
+		require.Len(t, step.Attempts, 1)
+		assert.Equal(t, "helper", step.Attempts[0].Command)
+	})
+}
+
+func TestDefaultSearchers_CredentialHelper(t *testing.T) {
+	var sources []string
+	for _, s := range DefaultSearchers(Options{
+		Timeout:          time.Second,
+		CredentialHelper: []string{"helper"},
+	}) {
+		sources = append(sources, sourceOf(s))
+	}
+
+	assert.Contains(t, sources, "credential-helper")
+}
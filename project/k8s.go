@@ -0,0 +1,100 @@
+package project
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// defaultK8sTokenFile is the projected service account token searched when
+// the K8sTokenFile option is empty.
+const defaultK8sTokenFile = "/var/run/secrets/tokens/gcp-ksa/token"
+
+// workloadIdentitySuffix ends the workload identity pool of a GKE cluster,
+// PROJECT_ID.svc.id.goog, used as the audience of its projected tokens.
+const workloadIdentitySuffix = ".svc.id.goog"
+
+// K8s Token Searcher
+
+// k8sTokenSearcher reads the project ID from the audience of a Kubernetes
+// projected service account token, as mounted for GKE Workload Identity.
+// The token is decoded, not verified: it only hints at the project.
+type k8sTokenSearcher struct {
+	file string
+}
+
+var _ Searcher = (*k8sTokenSearcher)(nil)
+
+// defaultK8sTokenSearcher is shared by the searches with the default file.
+// Searchers like it keep nothing between searches, so the default ones are
+// built once instead of on each search.
+var defaultK8sTokenSearcher = &k8sTokenSearcher{file: defaultK8sTokenFile}
+
+func newK8sTokenSearcher(file string) *k8sTokenSearcher {
+	if file == "" || file == defaultK8sTokenFile {
+		return defaultK8sTokenSearcher
+	}
+	s := k8sTokenSearcher{
+		file: file,
+	}
+	return &s
+}
+
+func (*k8sTokenSearcher) Source() string { return "k8s-token" }
+
+func (s *k8sTokenSearcher) backingFile() string { return s.file }
+
+func (s *k8sTokenSearcher) ProjectID(context.Context, ...string) (string, error) {
+	b, err := readFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read k8s token: %w", err)
+	}
+	audiences, err := tokenAudiences(strings.TrimSpace(string(b)))
+	if err != nil {
+		return "", fmt.Errorf("k8s token %s: %w", s.file, err)
+	}
+	for _, aud := range audiences {
+		if id, ok := strings.CutSuffix(aud, workloadIdentitySuffix); ok {
+			return sanitizeValue(id), nil
+		}
+	}
+	return "", nil
+}
+
+// tokenAudiences returns the `aud` claim of the JWT, which may be a single
+// string or a list. The signature is not verified.
+func tokenAudiences(token string) ([]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode token payload: %w", err)
+	}
+	var claims struct {
+		Aud json.RawMessage `json:"aud"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("parse token payload: %w", err)
+	}
+	if len(claims.Aud) == 0 {
+		return nil, nil
+	}
+	var aud string
+	if json.Unmarshal(claims.Aud, &aud) == nil {
+		return []string{aud}, nil
+	}
+	var audiences []string
+	if err = json.Unmarshal(claims.Aud, &audiences); err != nil {
+		return nil, fmt.Errorf("parse token audience: %w", err)
+	}
+	return audiences, nil
+}
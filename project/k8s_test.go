@@ -0,0 +1,113 @@
+package project
+
+import (
+	"context"
+	"encoding/base64"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_k8sTokenSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Audience",
+			token: syntheticToken(`{"aud":"gcp-id-test.svc.id.goog","sub":"system:serviceaccount:default:app"}`),
+			want:  "gcp-id-test",
+		},
+		{
+			name:  "Audience list",
+			token: syntheticToken(`{"aud":["https://kubernetes.default.svc","gcp-id-test.svc.id.goog"]}`),
+			want:  "gcp-id-test",
+		},
+		{
+			name:  "Trailing newline",
+			token: syntheticToken(`{"aud":"gcp-id-test.svc.id.goog"}`) + "\n",
+			want:  "gcp-id-test",
+		},
+		{
+			name:  "Other audience",
+			token: syntheticToken(`{"aud":"https://kubernetes.default.svc"}`),
+			want:  "",
+		},
+		{
+			name:  "No audience",
+			token: syntheticToken(`{"sub":"system:serviceaccount:default:app"}`),
+			want:  "",
+		},
+		{
+			name:    "Not a JWT",
+			token:   "not a token",
+			wantErr: true,
+		},
+		{
+			name:    "Payload not base64",
+			token:   "header.!!!.signature",
+			wantErr: true,
+		},
+		{
+			name:    "Payload not JSON",
+			token:   syntheticToken(`not json`),
+			wantErr: true,
+		},
+		{
+			name:    "Audience not a string",
+			token:   syntheticToken(`{"aud":123}`),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "token")
+			require.NoError(t, os.WriteFile(file, []byte(tt.token), 0o600))
+			s := newK8sTokenSearcher(file)
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_k8sTokenSearcher_ProjectID_MissingFile(t *testing.T) {
+	s := newK8sTokenSearcher(filepath.Join(t.TempDir(), "token"))
+
+	got, err := s.ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func Test_k8sTokenSearcher_ProjectID_DefaultFile(t *testing.T) {
+	var gotFile string
+	replace(t, &readFile, func(file string) ([]byte, error) {
+		gotFile = file
+		return nil, fs.ErrNotExist
+	})
+
+	_, err := newK8sTokenSearcher("").ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, defaultK8sTokenFile, gotFile)
+}
+
+// syntheticToken returns an unsigned JWT with the given payload.
+func syntheticToken(payload string) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	return header + "." + enc.EncodeToString([]byte(payload)) + ".signature"
+}
@@ -0,0 +1,87 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// kccProjectAnnotation is the Config Connector annotation naming the
+// project that manages a resource or namespace.
+const kccProjectAnnotation = "cnrm.cloud.google.com/project-id"
+
+// KCC Annotations Searcher
+
+// kccSearcher reads the project ID from the Config Connector annotation
+// cnrm.cloud.google.com/project-id, in a file of KEY="VALUE" lines, as the
+// Kubernetes downward API writes the annotations, or in a file holding just
+// the value, as when that annotation alone is projected.
+type kccSearcher struct {
+	file string
+}
+
+var _ Searcher = (*kccSearcher)(nil)
+
+func newKCCSearcher(file string) *kccSearcher {
+	s := kccSearcher{
+		file: file,
+	}
+	return &s
+}
+
+func (*kccSearcher) Source() string { return "kcc" }
+
+func (s *kccSearcher) backingFile() string { return s.file }
+
+func (s *kccSearcher) ProjectID(context.Context, ...string) (string, error) {
+	b, err := readFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read kcc annotations: %w", err)
+	}
+	id, err := parseKCCAnnotations(string(b))
+	if err != nil {
+		return "", fmt.Errorf("kcc annotations %s: %w", s.file, err)
+	}
+	return sanitizeValue(id), nil
+}
+
+// parseKCCAnnotations returns the project ID annotation of the content,
+// either KEY="VALUE" lines, with the values quoted as in Go, or a single
+// line with the value.
+func parseKCCAnnotations(content string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 1 && !strings.Contains(lines[0], "=") {
+		return lines[0], nil
+	}
+
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return "", fmt.Errorf("malformed line %q", truncate(line))
+		}
+		if strings.TrimSpace(key) != kccProjectAnnotation {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if !strings.HasPrefix(value, `"`) {
+			return value, nil
+		}
+		v, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: malformed value: %w", kccProjectAnnotation, err)
+		}
+		return strings.TrimSpace(v), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,115 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_kccSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Downward API annotations",
+			content: "kubernetes.io/config.seen=\"2024-01-01T00:00:00Z\"\n" +
+				"cnrm.cloud.google.com/project-id=\"gcp-id-test\"\n",
+			want: "gcp-id-test",
+		},
+		{
+			name:    "Unquoted",
+			content: "cnrm.cloud.google.com/project-id=gcp-id-test\n",
+			want:    "gcp-id-test",
+		},
+		{
+			name:    "Single value",
+			content: "gcp-id-test\n",
+			want:    "gcp-id-test",
+		},
+		{
+			name:    "No project annotation",
+			content: "kubernetes.io/config.seen=\"2024-01-01T00:00:00Z\"\n",
+			want:    "",
+		},
+		{
+			name:    "Empty",
+			content: "",
+			want:    "",
+		},
+		{
+			name:    "Malformed value",
+			content: "cnrm.cloud.google.com/project-id=\"gcp-id-test\n",
+			wantErr: true,
+		},
+		{
+			name:    "Malformed line",
+			content: "not an annotation\ncnrm.cloud.google.com/project-id=\"gcp-id-test\"\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "annotations")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0o600))
+
+			got, err := newKCCSearcher(file).ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), file)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Missing file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "annotations")
+
+		got, err := newKCCSearcher(file).ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
+
+func TestDefaultSearchers_KCCAnnotationsFile(t *testing.T) {
+	hasKCC := func(o Options) bool {
+		for _, s := range DefaultSearchers(o) {
+			if sourceOf(s) == "kcc" {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.False(t, hasKCC(Options{}))
+	assert.True(t, hasKCC(Options{KCCAnnotationsFile: "/etc/podinfo/annotations"}))
+}
+
+func TestResolve_KCCAnnotationsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "annotations")
+	content := "cnrm.cloud.google.com/project-id=\"gcp-id-test\"\n"
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	useSearchers(t, func(o Options) []Searcher {
+		return []Searcher{newKCCSearcher(o.KCCAnnotationsFile)}
+	})
+
+	r, err := Resolve(context.Background(), Options{
+		Timeout:            time.Second,
+		KCCAnnotationsFile: file,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "kcc", r.Source)
+}
@@ -0,0 +1,44 @@
+package project
+
+import (
+	"context"
+	"sync"
+)
+
+// LazyID is a project ID resolved on first access, and shared by all the
+// accesses. Create it with Lazy.
+type LazyID struct {
+	opts []Options
+	once sync.Once
+	id   string
+	err  error
+}
+
+// Lazy returns a project ID resolved with the given options, like
+// IDContext, on the first call to its Get method. It's meant for package
+// variables, so the resolution happens once, when first needed, instead of
+// panicking at init:
+//
+//	var projectID = project.Lazy(project.Options{Strict: true})
+//
+//	func handler() error {
+//		id, err := projectID.Get()
+//		// ...
+//	}
+func Lazy(opts ...Options) *LazyID {
+	l := LazyID{
+		opts: opts,
+	}
+	return &l
+}
+
+// Get returns the project ID, resolving it on the first call. The project
+// ID and the error, if any, are memoized: later calls return them without
+// searching again, even if the resolution failed. It's safe for concurrent
+// use; concurrent first calls wait for the same resolution.
+func (l *LazyID) Get() (string, error) {
+	l.once.Do(func() {
+		l.id, l.err = IDContext(context.Background(), l.opts...)
+	})
+	return l.id, l.err
+}
@@ -0,0 +1,48 @@
+package project
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazy(t *testing.T) {
+	t.Run("Resolved once", func(t *testing.T) {
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		l := Lazy(Options{Timeout: time.Second})
+		assert.Equal(t, 0, s.calls, "not resolved until needed")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id, err := l.Get()
+				assert.NoError(t, err)
+				assert.Equal(t, "gcp-id-test", id)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, s.calls)
+	})
+
+	t.Run("Error memoized", func(t *testing.T) {
+		s := &countingSearcherMock{}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		l := Lazy(Options{Timeout: time.Second, Strict: true})
+
+		_, err := l.Get()
+		require.ErrorIs(t, err, ErrProjectIDNotFound)
+		s.projectID = "gcp-id-test"
+		id, err := l.Get()
+
+		require.ErrorIs(t, err, ErrProjectIDNotFound)
+		assert.Empty(t, id)
+		assert.Equal(t, 1, s.calls)
+	})
+}
@@ -0,0 +1,58 @@
+package project
+
+import (
+	"context"
+	"sync"
+)
+
+// gcloudSlots limits how many gcloud subprocesses run at once, across all
+// goroutines, when the MaxConcurrentGCloud option is set.
+var gcloudSlots limiter
+
+// limiter is a counting semaphore whose size is given on each acquisition,
+// so calls with different limits can share it.
+type limiter struct {
+	mu     sync.Mutex
+	active int
+
+	// freed is closed, and reset, when a slot is released.
+	freed chan struct{}
+}
+
+// acquire blocks until fewer than limit slots are taken, or the context is
+// done, and returns the function that releases the slot taken. A limit of
+// zero or less means unlimited.
+func (l *limiter) acquire(ctx context.Context, limit int) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	for {
+		l.mu.Lock()
+		if l.active < limit {
+			l.active++
+			l.mu.Unlock()
+			return l.release, nil
+		}
+		if l.freed == nil {
+			l.freed = make(chan struct{})
+		}
+		freed := l.freed
+		l.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if l.freed != nil {
+		close(l.freed)
+		l.freed = nil
+	}
+}
@@ -0,0 +1,116 @@
+package project
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_limiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		wantMax int32
+	}{
+		{name: "Limited", limit: 2, wantMax: 2},
+		{name: "Single", limit: 1, wantMax: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				l               limiter
+				wg              sync.WaitGroup
+				active, maxSeen atomic.Int32
+				start           = make(chan struct{})
+			)
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					release, err := l.acquire(context.Background(), tt.limit)
+					assert.NoError(t, err)
+					n := active.Add(1)
+					for {
+						m := maxSeen.Load()
+						if n <= m || maxSeen.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					active.Add(-1)
+					release()
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			assert.LessOrEqual(t, maxSeen.Load(), tt.wantMax)
+			assert.Positive(t, maxSeen.Load())
+		})
+	}
+}
+
+func Test_limiter_Unlimited(t *testing.T) {
+	var l limiter
+	for i := 0; i < 8; i++ {
+		_, err := l.acquire(context.Background(), 0)
+		require.NoError(t, err)
+	}
+	assert.Zero(t, l.active)
+}
+
+func Test_limiter_ContextDone(t *testing.T) {
+	var l limiter
+	release, err := l.acquire(context.Background(), 1)
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, 1)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_gcloudSearcher_MaxConcurrent(t *testing.T) {
+	var (
+		wg              sync.WaitGroup
+		active, maxSeen atomic.Int32
+	)
+	output := func(*exec.Cmd, int) ([]byte, error) {
+		n := active.Add(1)
+		for {
+			m := maxSeen.Load()
+			if n <= m || maxSeen.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		active.Add(-1)
+		return []byte("gcp-id-test"), nil
+	}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := &gcloudSearcher{
+				executables:   []string{"gcloud"},
+				maxConcurrent: 2,
+				output:        output,
+			}
+			got, err := s.ProjectID(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "gcp-id-test", got)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxSeen.Load(), int32(2))
+}
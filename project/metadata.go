@@ -0,0 +1,250 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// onGCPTimeout bounds the metadata server probe of OnGCP. Off Google Cloud
+// the metadata server address is usually unroutable, so the probe would
+// otherwise hang for as long as the context allows.
+const onGCPTimeout = 500 * time.Millisecond
+
+// httpDoer is the part of *http.Client used by this package.
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Seams for the metadata server.
+var (
+	metadataClient httpDoer = &http.Client{}
+	metadataHost            = "169.254.169.254"
+)
+
+// metadataAddr returns the host of the metadata server: the one in
+// GCE_METADATA_HOST, if set, as the Google Cloud client libraries honor,
+// or metadataHost.
+func metadataAddr() string {
+	if host := strings.TrimSpace(getenv("GCE_METADATA_HOST")); host != "" {
+		return host
+	}
+	return metadataHost
+}
+
+// noGCECheck reports whether NO_GCE_CHECK is true, which tells the Google
+// Cloud auth libraries never to probe the metadata server, as off Google
+// Cloud where the probe only costs time.
+func noGCECheck() bool {
+	v, _ := strconv.ParseBool(strings.TrimSpace(getenv("NO_GCE_CHECK")))
+	return v
+}
+
+// onGCP caches the verdict of OnGCP for the process.
+var onGCP struct {
+	mu      sync.Mutex
+	checked bool
+	verdict bool
+}
+
+// OnGCP reports whether the code is running on Google Cloud, by checking if
+// the metadata server is reachable. The probe is short and its verdict is
+// cached for the lifetime of the process. If ctx is done before the probe
+// completes, OnGCP returns false without caching it. It's false, without
+// probing, when NO_GCE_CHECK is true.
+func OnGCP(ctx context.Context) bool {
+	if noGCECheck() {
+		return false
+	}
+	onGCP.mu.Lock()
+	defer onGCP.mu.Unlock()
+	if onGCP.checked {
+		return onGCP.verdict
+	}
+
+	verdict := probeMetadata(ctx)
+	if ctx.Err() != nil {
+		return false
+	}
+	onGCP.checked = true
+	onGCP.verdict = verdict
+	return verdict
+}
+
+func probeMetadata(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, onGCPTimeout)
+	defer cancel()
+
+	url := "http://" + metadataAddr() + "/computeMetadata/v1/"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.Header.Get("Metadata-Flavor") == "Google"
+}
+
+func resetOnGCP() {
+	onGCP.mu.Lock()
+	defer onGCP.mu.Unlock()
+	onGCP.checked = false
+	onGCP.verdict = false
+}
+
+// resetMetadata forgets the verdicts cached about the metadata server.
+func resetMetadata() {
+	resetOnGCP()
+	metadataNoProject.Store(false)
+}
+
+// Metadata Searcher
+
+// metadataNoProject records that the metadata server answered the project
+// ID request with 404 Not Found. The verdict is kept for the process, so the
+// request isn't repeated in vain.
+var metadataNoProject atomic.Bool
+
+// metadataSearcher reads the project ID from the metadata server, which is
+// only reachable on Google Cloud. A 404 Not Found answer is cached, while
+// connection errors and other answers are deemed transient, so the next
+// search asks again. As in the Google Cloud client libraries, the server is
+// at GCE_METADATA_HOST, when set, and never asked when NO_GCE_CHECK is
+// true.
+type metadataSearcher struct {
+	// universeDomain, if set, is the universe the instance must be in.
+	universeDomain string
+
+	// attribute, if set, is the custom project metadata attribute read
+	// before the project ID of the instance.
+	attribute string
+}
+
+var _ Searcher = (*metadataSearcher)(nil)
+
+// defaultMetadataSearcher is shared by the searches without metadata
+// options.
+var defaultMetadataSearcher = &metadataSearcher{}
+
+func newMetadataSearcher(o Options) *metadataSearcher {
+	if o.UniverseDomain == "" && o.MetadataAttribute == "" {
+		return defaultMetadataSearcher
+	}
+	s := metadataSearcher{
+		universeDomain: o.UniverseDomain,
+		attribute:      o.MetadataAttribute,
+	}
+	return &s
+}
+
+func (*metadataSearcher) Source() string { return "metadata" }
+
+func (s *metadataSearcher) ProjectID(ctx context.Context, _ ...string) (
+	string, error,
+) {
+	if noGCECheck() {
+		return "", nil
+	}
+	id, err := s.attributeProjectID(ctx)
+	if err != nil || id != "" {
+		return id, err
+	}
+	if metadataNoProject.Load() {
+		return "", nil
+	}
+	status, id, err := getMetadata(ctx, "project/project-id")
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusNotFound {
+		metadataNoProject.Store(true)
+		return "", nil
+	}
+	if status != http.StatusOK || id == "" {
+		return "", nil
+	}
+	return s.checkUniverseDomain(ctx, id)
+}
+
+// attributeProjectID returns the project ID in the custom project metadata
+// attribute, if set. It's empty when the attribute is missing or empty,
+// for the project ID of the instance to be used instead.
+func (s *metadataSearcher) attributeProjectID(ctx context.Context) (
+	string, error,
+) {
+	if s.attribute == "" {
+		return "", nil
+	}
+	path := "project/attributes/" + url.PathEscape(s.attribute)
+	status, id, err := getMetadata(ctx, path)
+	if err != nil || status != http.StatusOK || id == "" {
+		return "", err
+	}
+	return s.checkUniverseDomain(ctx, id)
+}
+
+// checkUniverseDomain returns id if the instance is in the universeDomain,
+// when set, as told by the metadata server.
+func (s *metadataSearcher) checkUniverseDomain(ctx context.Context, id string) (
+	string, error,
+) {
+	if s.universeDomain == "" {
+		return id, nil
+	}
+	status, ud, err := getMetadata(ctx, "universe/universe-domain")
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case http.StatusOK, http.StatusNotFound:
+		// Older metadata servers, without the entry, are in the default
+		// universe.
+	default:
+		return "", fmt.Errorf("metadata universe domain: status %d", status)
+	}
+	if err = checkUniverseDomain(s.universeDomain, ud, "metadata server"); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// getMetadata requests the metadata server entry at path, relative to
+// /computeMetadata/v1/, and returns the status and the sanitized value of
+// the answer. The value is empty unless the status is 200 OK. A status of
+// zero means the server didn't answer, as off Google Cloud or when the
+// context is done.
+func getMetadata(ctx context.Context, path string) (
+	status int, value string, err error,
+) {
+	url := "http://" + metadataAddr() + "/computeMetadata/v1/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("metadata request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		// Not on Google Cloud, or the context is done.
+		return 0, "", nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, "", nil
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxValueLen+1))
+	if err != nil {
+		return 0, "", fmt.Errorf("read metadata response: %w", err)
+	}
+	return resp.StatusCode, sanitizeValue(strings.TrimSpace(string(b))), nil
+}
@@ -0,0 +1,81 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// defaultMetadataCacheFile is the metadata cache file searched when the
+// MetadataCacheFile option is empty.
+const defaultMetadataCacheFile = "/var/lib/google/metadata/project-id"
+
+// defaultMetadataCacheMaxAge is how long the metadata cache file is fresh
+// when the MetadataCacheMaxAge option is zero.
+const defaultMetadataCacheMaxAge = time.Hour
+
+// maxMetadataCacheSize bounds the size of the metadata cache file read, as
+// it only holds a project ID.
+const maxMetadataCacheSize = 4 << 10
+
+// Metadata Cache Searcher
+
+// metadataCacheSearcher reads the project ID cached from the metadata
+// server by an agent on the instance, to spare the HTTP request. The file
+// holds the project ID alone, and is a miss when it's older than maxAge.
+type metadataCacheSearcher struct {
+	file   string
+	maxAge time.Duration
+}
+
+var _ Searcher = (*metadataCacheSearcher)(nil)
+
+func newMetadataCacheSearcher(
+	file string, maxAge time.Duration,
+) *metadataCacheSearcher {
+	if file == "" {
+		file = defaultMetadataCacheFile
+	}
+	if maxAge <= 0 {
+		maxAge = defaultMetadataCacheMaxAge
+	}
+	s := metadataCacheSearcher{
+		file:   file,
+		maxAge: maxAge,
+	}
+	return &s
+}
+
+func (*metadataCacheSearcher) Source() string { return "metadata-cache" }
+
+func (s *metadataCacheSearcher) backingFile() string { return s.file }
+
+func (s *metadataCacheSearcher) ProjectID(context.Context, ...string) (
+	string, error,
+) {
+	info, err := stat(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("stat metadata cache: %w", err)
+	}
+	if now().Sub(info.ModTime()) > s.maxAge {
+		// Stale: leave it to the metadata server.
+		return "", nil
+	}
+	if !info.Mode().IsRegular() || info.Size() > maxMetadataCacheSize {
+		return "", fmt.Errorf("metadata cache %s: not a project ID file", s.file)
+	}
+	b, err := readFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read metadata cache: %w", err)
+	}
+	return sanitizeValue(strings.TrimSpace(string(b))), nil
+}
@@ -0,0 +1,108 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_metadataCacheSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		age     time.Duration
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Fresh",
+			content: ptr("gcp-id-test\n"),
+			age:     time.Minute,
+			want:    "gcp-id-test",
+		},
+		{
+			name:    "Stale",
+			content: ptr("gcp-id-test\n"),
+			age:     2 * time.Hour,
+			want:    "",
+		},
+		{
+			name:    "Missing",
+			content: nil,
+			want:    "",
+		},
+		{
+			name:    "Empty",
+			content: ptr(""),
+			want:    "",
+		},
+		{
+			name:    "Not a project ID",
+			content: ptr("gcp id test"),
+			want:    "",
+		},
+		{
+			name:    "Too large",
+			content: ptr(strings.Repeat("a", maxMetadataCacheSize+1)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "project-id")
+			if tt.content != nil {
+				require.NoError(t, os.WriteFile(file, []byte(*tt.content), 0o600))
+				mtime := time.Now().Add(-tt.age)
+				require.NoError(t, os.Chtimes(file, mtime, mtime))
+			}
+			s := newMetadataCacheSearcher(file, time.Hour)
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Directory", func(t *testing.T) {
+		s := newMetadataCacheSearcher(t.TempDir(), time.Hour)
+
+		_, err := s.ProjectID(context.Background())
+
+		require.Error(t, err)
+	})
+}
+
+func Test_newMetadataCacheSearcher(t *testing.T) {
+	s := newMetadataCacheSearcher("", 0)
+
+	assert.Equal(t, defaultMetadataCacheFile, s.backingFile())
+	assert.Equal(t, defaultMetadataCacheMaxAge, s.maxAge)
+}
+
+func TestResolve_UseMetadataCacheFile(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	file := filepath.Join(t.TempDir(), "project-id")
+	require.NoError(t, os.WriteFile(file, []byte("gcp-id-test\n"), 0o600))
+
+	r, err := Resolve(context.Background(), Options{
+		Timeout:              time.Second,
+		UseMetadataCacheFile: true,
+		MetadataCacheFile:    file,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "metadata-cache", r.Source)
+}
@@ -0,0 +1,442 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnGCP(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    bool
+	}{
+		{
+			name: "On GCP",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+				w.Header().Set("Metadata-Flavor", "Google")
+			},
+			want: true,
+		},
+		{
+			name: "Not a metadata server",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMetadataServer(t, tt.handler)
+
+			assert.Equal(t, tt.want, OnGCP(context.Background()))
+		})
+	}
+}
+
+func TestOnGCP_Unreachable(t *testing.T) {
+	useMetadataClient(t, func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	assert.False(t, OnGCP(context.Background()))
+}
+
+func TestOnGCP_Cached(t *testing.T) {
+	var calls int
+	useMetadataServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Metadata-Flavor", "Google")
+	})
+
+	assert.True(t, OnGCP(context.Background()))
+	assert.True(t, OnGCP(context.Background()))
+	assert.Equal(t, 1, calls)
+}
+
+func TestOnGCP_ContextDone(t *testing.T) {
+	useMetadataServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Metadata-Flavor", "Google")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(t, OnGCP(ctx))
+	assert.True(t, OnGCP(context.Background()), "canceled probe was cached")
+}
+
+func Test_metadataSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name: "Project ID",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+				assert.Equal(t, "/computeMetadata/v1/project/project-id", r.URL.Path)
+				_, _ = w.Write([]byte("gcp-id-test\n"))
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name: "Not found",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				http.NotFound(w, nil)
+			},
+			want: "",
+		},
+		{
+			name: "Not a project ID",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("<html>captive portal</html>"))
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMetadataServer(t, tt.handler)
+
+			got, err := newMetadataSearcher(Options{}).ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_metadataSearcher_ProjectID_Verdicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		do        func(*http.Request) (*http.Response, error)
+		wantCalls int
+	}{
+		{
+			name: "Not found is cached",
+			do: func(*http.Request) (*http.Response, error) {
+				return metadataResponse(http.StatusNotFound), nil
+			},
+			wantCalls: 1,
+		},
+		{
+			name: "Connection error is transient",
+			do: func(*http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			},
+			wantCalls: 3,
+		},
+		{
+			name: "Server error is transient",
+			do: func(*http.Request) (*http.Response, error) {
+				return metadataResponse(http.StatusServiceUnavailable), nil
+			},
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			useMetadataClient(t, func(req *http.Request) (*http.Response, error) {
+				calls++
+				return tt.do(req)
+			})
+			s := newMetadataSearcher(Options{})
+
+			for range 3 {
+				got, err := s.ProjectID(context.Background())
+				require.NoError(t, err)
+				assert.Empty(t, got)
+			}
+
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func Test_metadataSearcher_ProjectID_VerdictCleared(t *testing.T) {
+	var calls int
+	useMetadataClient(t, func(*http.Request) (*http.Response, error) {
+		calls++
+		return metadataResponse(http.StatusNotFound), nil
+	})
+	s := newMetadataSearcher(Options{})
+
+	_, _ = s.ProjectID(context.Background())
+	require.NoError(t, Shutdown(context.Background()))
+	_, _ = s.ProjectID(context.Background())
+
+	assert.Equal(t, 2, calls)
+}
+
+// metadataResponse returns an empty response with the status code.
+func metadataResponse(code int) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Header:     http.Header{"Metadata-Flavor": {"Google"}},
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+}
+
+func Test_metadataSearcher_ProjectID_Unreachable(t *testing.T) {
+	useMetadataClient(t, func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	got, err := newMetadataSearcher(Options{}).ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+// useMetadataServer serves the metadata server requests with the handler
+// for the duration of the test.
+func useMetadataServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	replace(t, &metadataHost, u.Host)
+	replace[httpDoer](t, &metadataClient, server.Client())
+	resetMetadata()
+	t.Cleanup(resetMetadata)
+}
+
+// useMetadataClient makes the metadata server requests with the function
+// for the duration of the test.
+func useMetadataClient(
+	t *testing.T, do func(*http.Request) (*http.Response, error),
+) {
+	t.Helper()
+	replace[httpDoer](t, &metadataClient, httpDoerFunc(do))
+	resetMetadata()
+	t.Cleanup(resetMetadata)
+}
+
+type httpDoerFunc func(*http.Request) (*http.Response, error)
+
+func (f httpDoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func Test_metadataSearcher_ProjectID_Attribute(t *testing.T) {
+	tests := []struct {
+		name      string
+		attribute string
+		handler   http.HandlerFunc
+		want      string
+	}{
+		{
+			name:      "Attribute set",
+			attribute: "app-project",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/computeMetadata/v1/project/attributes/app-project":
+					_, _ = w.Write([]byte("gcp-id-app\n"))
+				case "/computeMetadata/v1/project/project-id":
+					_, _ = w.Write([]byte("gcp-id-infra"))
+				}
+			},
+			want: "gcp-id-app",
+		},
+		{
+			name:      "Attribute missing",
+			attribute: "app-project",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/computeMetadata/v1/project/project-id" {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte("gcp-id-infra"))
+			},
+			want: "gcp-id-infra",
+		},
+		{
+			name:      "Attribute empty",
+			attribute: "app-project",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/computeMetadata/v1/project/project-id" {
+					return
+				}
+				_, _ = w.Write([]byte("gcp-id-infra"))
+			},
+			want: "gcp-id-infra",
+		},
+		{
+			name:      "Nothing found",
+			attribute: "app-project",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			want: "",
+		},
+		{
+			name:      "Not set",
+			attribute: "",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/computeMetadata/v1/project/project-id" {
+					t.Errorf("unexpected request: %s", r.URL.Path)
+				}
+				_, _ = w.Write([]byte("gcp-id-infra"))
+			},
+			want: "gcp-id-infra",
+		},
+		{
+			name:      "Escaped",
+			attribute: "../project-id",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.RawPath == "/computeMetadata/v1/project/attributes/..%2Fproject-id" {
+					_, _ = w.Write([]byte("gcp-id-app"))
+				}
+			},
+			want: "gcp-id-app",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMetadataServer(t, tt.handler)
+			s := newMetadataSearcher(Options{MetadataAttribute: tt.attribute})
+
+			got, err := s.ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Attribute after a 404 project ID", func(t *testing.T) {
+		var calls int
+		useMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if r.URL.Path == "/computeMetadata/v1/project/attributes/app-project" &&
+				calls > 2 {
+				_, _ = w.Write([]byte("gcp-id-app"))
+				return
+			}
+			http.NotFound(w, r)
+		})
+		s := newMetadataSearcher(Options{MetadataAttribute: "app-project"})
+
+		first, err := s.ProjectID(context.Background())
+		require.NoError(t, err)
+		second, err := s.ProjectID(context.Background())
+		require.NoError(t, err)
+
+		assert.Empty(t, first)
+		assert.Equal(t, "gcp-id-app", second)
+	})
+}
+
+func TestResolve_MetadataAttribute(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	useMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/computeMetadata/v1/project/attributes/app-project" {
+			_, _ = w.Write([]byte("gcp-id-app"))
+			return
+		}
+		_, _ = w.Write([]byte("gcp-id-infra"))
+	})
+	opts := Options{
+		Timeout:           time.Second,
+		Order:             []string{"metadata"},
+		MetadataAttribute: "app-project",
+	}
+
+	r, err := Resolve(context.Background(), opts)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-app", r.ID)
+	assert.Equal(t, "metadata", r.Source)
+}
+
+func TestMetadata_NoGCECheck(t *testing.T) {
+	var calls int
+	useMetadataServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Metadata-Flavor", "Google")
+		_, _ = io.WriteString(w, "gcp-id-test")
+	})
+
+	for _, v := range []string{"true", "True", "1"} {
+		t.Run(v, func(t *testing.T) {
+			t.Setenv("NO_GCE_CHECK", v)
+
+			got, err := newMetadataSearcher(Options{}).ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Empty(t, got)
+			assert.False(t, OnGCP(context.Background()))
+			assert.Zero(t, calls)
+		})
+	}
+
+	t.Run("false", func(t *testing.T) {
+		t.Setenv("NO_GCE_CHECK", "false")
+
+		got, err := newMetadataSearcher(Options{}).ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.True(t, OnGCP(context.Background()))
+	})
+}
+
+func TestMetadata_GCEMetadataHost(t *testing.T) {
+	useMetadataServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Metadata-Flavor", "Google")
+		_, _ = io.WriteString(w, "gcp-id-test")
+	})
+	host := metadataHost
+	replace(t, &metadataHost, "metadata.invalid")
+	t.Setenv("GCE_METADATA_HOST", host)
+
+	got, err := newMetadataSearcher(Options{}).ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", got)
+	assert.True(t, OnGCP(context.Background()))
+}
+
+func Test_credentialsSearcher_ProjectID_NoGCECheck(t *testing.T) {
+	unsetEnv(t, "GOOGLE_APPLICATION_CREDENTIALS")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	t.Setenv("NO_GCE_CHECK", "true")
+	useMetadataClient(t, func(*http.Request) (*http.Response, error) {
+		t.Error("the metadata server was probed")
+		return nil, errors.New("connection refused")
+	})
+
+	got, err := newCredentialsSearcher(nil, "").ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	t.Run("Credentials file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "credentials.json")
+		content := `{"type":"service_account","project_id":"gcp-id-test",` +
+			`"private_key":"","client_email":"app@gcp-id-test.iam.gserviceaccount.com"}`
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+
+		got, err := newCredentialsSearcher(nil, "").ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	})
+}
@@ -0,0 +1,81 @@
+package project
+
+import "sync"
+
+// changes tracks the project ID of the last search, to notify the
+// subscribers when it changes.
+var changes struct {
+	mu   sync.Mutex
+	last string
+	subs map[chan string]struct{}
+}
+
+// Subscribe returns a channel that receives the new project ID whenever a
+// search resolves a different one than the previous search, as may
+// happen, in edge cases, when credentials are rotated. Values from
+// Explicit, Freeze, the context and the cache don't count as searches.
+//
+// Nothing polls the sources in the background: a change is only noticed
+// when some call runs a search, as when the cached value expires after
+// the CacheTTL. Processes that want to notice changes need to resolve
+// the project ID periodically.
+//
+// The channel holds one value: a slow subscriber only misses the
+// intermediate changes, never blocking the searches, and receives the
+// latest value. Call Unsubscribe when done.
+func Subscribe() <-chan string {
+	ch := make(chan string, 1)
+	changes.mu.Lock()
+	defer changes.mu.Unlock()
+	if changes.subs == nil {
+		changes.subs = make(map[chan string]struct{})
+	}
+	changes.subs[ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe stops the notifications to ch, returned by Subscribe, and
+// closes it. It's a no-op if ch is not subscribed.
+func Unsubscribe(ch <-chan string) {
+	changes.mu.Lock()
+	defer changes.mu.Unlock()
+	for sub := range changes.subs {
+		if sub == ch {
+			delete(changes.subs, sub)
+			close(sub)
+			return
+		}
+	}
+}
+
+// observe records the project ID resolved by a search, notifying the
+// subscribers if it changed since the previous one.
+func observe(id string) {
+	changes.mu.Lock()
+	defer changes.mu.Unlock()
+	if id == changes.last {
+		return
+	}
+	previous := changes.last
+	changes.last = id
+	if previous == "" {
+		// The first resolution is not a change.
+		return
+	}
+	for sub := range changes.subs {
+		select {
+		case <-sub:
+			// Drop the value not received yet, for the latest.
+		default:
+		}
+		sub <- id
+	}
+}
+
+// resetChanges forgets the project ID of the last search, so the next one
+// is not a change. The subscribers are kept.
+func resetChanges() {
+	changes.mu.Lock()
+	defer changes.mu.Unlock()
+	changes.last = ""
+}
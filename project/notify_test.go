@@ -0,0 +1,93 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Run("Change", func(t *testing.T) {
+		useChanges(t)
+		s := &searcherMock{projectID: "gcp-id-first"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		ch := Subscribe()
+		defer Unsubscribe(ch)
+
+		ID(Options{Timeout: time.Second})
+		ID(Options{Timeout: time.Second})
+		assertNoValue(t, ch)
+
+		s.projectID = "gcp-id-second"
+		ID(Options{Timeout: time.Second})
+
+		assert.Equal(t, "gcp-id-second", receive(t, ch))
+	})
+
+	t.Run("Slow subscriber", func(t *testing.T) {
+		useChanges(t)
+		ch := Subscribe()
+		defer Unsubscribe(ch)
+
+		observe("gcp-id-first")
+		observe("gcp-id-second")
+		observe("gcp-id-third")
+
+		assert.Equal(t, "gcp-id-third", receive(t, ch))
+		assertNoValue(t, ch)
+	})
+
+	t.Run("Not searched", func(t *testing.T) {
+		useChanges(t)
+		observe("gcp-id-first")
+		ch := Subscribe()
+		defer Unsubscribe(ch)
+
+		ID(Options{Explicit: "gcp-id-explicit"})
+
+		assertNoValue(t, ch)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		useChanges(t)
+		ch := Subscribe()
+
+		Unsubscribe(ch)
+		Unsubscribe(ch)
+		observe("gcp-id-first")
+		observe("gcp-id-second")
+
+		_, ok := <-ch
+		assert.False(t, ok, "closed")
+	})
+}
+
+// useChanges resets the project ID tracked for the subscribers for the
+// duration of the test.
+func useChanges(t *testing.T) {
+	t.Helper()
+	resetChanges()
+	t.Cleanup(resetChanges)
+}
+
+func receive(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		require.FailNow(t, "no value received")
+		return ""
+	}
+}
+
+func assertNoValue(t *testing.T, ch <-chan string) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		assert.Failf(t, "unexpected value", "received %q", v)
+	default:
+	}
+}
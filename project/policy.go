@@ -0,0 +1,122 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Policy defines how the sources are searched and how the project IDs they
+// provide are combined.
+type Policy int
+
+const (
+	// FirstMatch searches the sources in order and uses the first project
+	// ID found. It's the default.
+	FirstMatch Policy = iota
+
+	// MostSpecific searches the sources from the most to the least specific
+	// one, regardless of their order, and uses the first project ID found.
+	// The environment variables come first, then the configuration files,
+	// the credentials and, finally, the `gcloud` CLI.
+	MostSpecific
+
+	// Consistent searches all the sources and requires the project IDs
+	// found to agree. When they don't, the search fails with
+	// ErrInconsistentProjectID.
+	Consistent
+)
+
+// ErrInconsistentProjectID is returned (wrapped) by the Consistent policy
+// when the sources provide different project IDs.
+var ErrInconsistentProjectID = errors.New("inconsistent project IDs")
+
+// credentialsSources are the sources searched first, in order, with the
+// CredentialsAuthoritative option.
+var credentialsSources = []string{
+	"credentials-env", "credential-helper", "credentials-file", "credentials",
+}
+
+// specificity ranks the sources for the MostSpecific policy. Lower is more
+// specific. Unknown sources rank with the credentials.
+var specificity = map[string]int{
+	"gcloud-property":           0,
+	"env":                       0,
+	"cloud-build":               0,
+	"env-file":                  1,
+	"encrypted-config":          1,
+	"build-time":                1,
+	"remote":                    1,
+	"yaml":                      1,
+	"deploy-config":             1,
+	"systemd":                   1,
+	"boto":                      1,
+	"k8s-token":                 1,
+	"kcc":                       1,
+	"credentials-env":           2,
+	"credential-helper":         2,
+	"credentials-file":          2,
+	"credentials":               2,
+	"credentials:quota_project": 2,
+	"metadata":                  2,
+	"metadata-cache":            2,
+	"gcloud-config":             3,
+	"gcloud-config:inferred":    3,
+	"gcloud-config-helper":      3,
+	"gcloud":                    3,
+}
+
+const unknownSpecificity = 2
+
+func sourceSpecificity(s Searcher) int {
+	if rank, ok := specificity[sourceOf(s)]; ok {
+		return rank
+	}
+	return unknownSpecificity
+}
+
+// bySpecificity returns the searchers sorted from the most to the least
+// specific source, keeping the order of equally specific ones.
+func bySpecificity(searchers []Searcher) []Searcher {
+	sorted := append([]Searcher(nil), searchers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sourceSpecificity(sorted[i]) < sourceSpecificity(sorted[j])
+	})
+	return sorted
+}
+
+// consistentProjectID runs all the searchers and returns the project ID
+// they agree on, with the first searcher that found it.
+func consistentProjectID(
+	ctx context.Context, o Options, searchers []Searcher,
+) (
+	id string, winner Searcher, misses []error, err error,
+) {
+	var found []string
+	for _, s := range searchers {
+		v, err := search(ctx, o, s)
+		if err != nil && !o.ContinueOnError {
+			return "", nil, nil, err
+		}
+		if err != nil || v == "" || rejected(o, v) {
+			misses = addMiss(misses, o, s, err)
+			continue
+		}
+		found = append(found, sourceOf(s)+"="+v)
+		switch {
+		case winner == nil:
+			id, winner = v, s
+		case v != id:
+			return "", nil, nil, fmt.Errorf("%w: %s",
+				ErrInconsistentProjectID, strings.Join(found, ", "))
+		}
+	}
+	if winner != nil {
+		misses = nil
+	} else if err := ctx.Err(); err != nil {
+		return "", nil, nil, err
+	}
+	return id, winner, misses, nil
+}
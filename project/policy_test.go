@@ -0,0 +1,237 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestID_Policy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		searchers []Searcher
+		want      Result
+		wantErr   error
+	}{
+		{
+			name:   "FirstMatch uses the configured order",
+			policy: FirstMatch,
+			searchers: []Searcher{
+				newNamedSearcherMock("gcloud", "gcp-id-gcloud"),
+				newNamedSearcherMock("env", "gcp-id-env"),
+			},
+			want: Result{ID: "gcp-id-gcloud", Source: "gcloud", Found: true},
+		},
+		{
+			name:   "MostSpecific prefers env",
+			policy: MostSpecific,
+			searchers: []Searcher{
+				newNamedSearcherMock("gcloud", "gcp-id-gcloud"),
+				newNamedSearcherMock("credentials", "gcp-id-credentials"),
+				newNamedSearcherMock("env", "gcp-id-env"),
+			},
+			want: Result{ID: "gcp-id-env", Source: "env", Found: true},
+		},
+		{
+			name:   "MostSpecific prefers files over credentials",
+			policy: MostSpecific,
+			searchers: []Searcher{
+				newNamedSearcherMock("gcloud", "gcp-id-gcloud"),
+				newNamedSearcherMock("credentials", "gcp-id-credentials"),
+				newNamedSearcherMock("boto", "gcp-id-boto"),
+				newNamedSearcherMock("env", ""),
+			},
+			want: Result{ID: "gcp-id-boto", Source: "boto", Found: true},
+		},
+		{
+			name:   "Consistent agreement",
+			policy: Consistent,
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-test"),
+				newNamedSearcherMock("credentials", ""),
+				newNamedSearcherMock("gcloud", "gcp-id-test"),
+			},
+			want: Result{ID: "gcp-id-test", Source: "env", Found: true},
+		},
+		{
+			name:   "Consistent disagreement",
+			policy: Consistent,
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newNamedSearcherMock("gcloud", "gcp-id-gcloud"),
+			},
+			wantErr: ErrInconsistentProjectID,
+		},
+		{
+			name:   "Consistent, nothing found",
+			policy: Consistent,
+			searchers: []Searcher{
+				newNamedSearcherMock("env", ""),
+				newNamedSearcherMock("gcloud", ""),
+			},
+			want: Result{},
+		},
+		{
+			name:   "Consistent error",
+			policy: Consistent,
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newSearcherMock(false, true),
+			},
+			wantErr: errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher { return tt.searchers })
+
+			got := resolve(context.Background(), Options{
+				Timeout: time.Second,
+				Policy:  tt.policy,
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, got.Err, tt.wantErr)
+				return
+			}
+			require.NoError(t, got.Err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_bySpecificity(t *testing.T) {
+	searchers := []Searcher{
+		newNamedSearcherMock("gcloud", ""),
+		newNamedSearcherMock("custom", ""),
+		newNamedSearcherMock("credentials", ""),
+		newNamedSearcherMock("yaml", ""),
+		newNamedSearcherMock("env", ""),
+		newNamedSearcherMock("gcloud-property", ""),
+	}
+
+	got := bySpecificity(searchers)
+
+	var sources []string
+	for _, s := range got {
+		sources = append(sources, sourceOf(s))
+	}
+	assert.Equal(t, []string{
+		"env", "gcloud-property", "yaml", "custom", "credentials", "gcloud",
+	}, sources)
+	assert.Equal(t, "gcloud", sourceOf(searchers[0]), "input modified")
+}
+
+type namedSearcherMock struct {
+	searcherMock
+	source string
+}
+
+func newNamedSearcherMock(source, projectID string) *namedSearcherMock {
+	s := namedSearcherMock{
+		searcherMock: searcherMock{projectID: projectID},
+		source:       source,
+	}
+	return &s
+}
+
+func (s *namedSearcherMock) Source() string { return s.source }
+
+func TestResolve_CredentialsAuthoritative(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		searchers []Searcher
+		want      Result
+	}{
+		{
+			name: "Credentials override the environment",
+			opts: Options{CredentialsAuthoritative: true},
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newNamedSearcherMock("credentials", "gcp-id-credentials"),
+				newNamedSearcherMock("gcloud", "gcp-id-gcloud"),
+			},
+			want: Result{ID: "gcp-id-credentials", Source: "credentials", Found: true},
+		},
+		{
+			name: "Credentials in order",
+			opts: Options{CredentialsAuthoritative: true},
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newNamedSearcherMock("credentials", "gcp-id-credentials"),
+				newNamedSearcherMock("credentials-env", "gcp-id-credentials-env"),
+			},
+			want: Result{ID: "gcp-id-credentials-env", Source: "credentials-env", Found: true},
+		},
+		{
+			name: "Credentials without a project",
+			opts: Options{CredentialsAuthoritative: true},
+			searchers: []Searcher{
+				newNamedSearcherMock("gcloud", "gcp-id-gcloud"),
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newNamedSearcherMock("credentials", ""),
+			},
+			want: Result{ID: "gcp-id-gcloud", Source: "gcloud", Found: true},
+		},
+		{
+			name: "Environment first by default",
+			opts: Options{},
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newNamedSearcherMock("credentials", "gcp-id-credentials"),
+			},
+			want: Result{ID: "gcp-id-env", Source: "env", Found: true},
+		},
+		{
+			name: "After the MostSpecific policy",
+			opts: Options{CredentialsAuthoritative: true, Policy: MostSpecific},
+			searchers: []Searcher{
+				newNamedSearcherMock("boto", "gcp-id-boto"),
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newNamedSearcherMock("credentials", "gcp-id-credentials"),
+			},
+			want: Result{ID: "gcp-id-credentials", Source: "credentials", Found: true},
+		},
+		{
+			name: "After the Order option",
+			opts: Options{CredentialsAuthoritative: true, Order: []string{"env"}},
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-env"),
+				newNamedSearcherMock("credentials", "gcp-id-credentials"),
+			},
+			want: Result{ID: "gcp-id-credentials", Source: "credentials", Found: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(o Options) []Searcher {
+				return reorder(tt.searchers, searchOrder(o), o)
+			})
+			opts := tt.opts
+			opts.Timeout = time.Second
+
+			got, err := Resolve(context.Background(), opts)
+
+			require.NoError(t, err)
+			got.Trace = nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Other sources not searched", func(t *testing.T) {
+		env := &countingSearcherMock{projectID: "gcp-id-env"}
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{env, newNamedSearcherMock("credentials", "gcp-id-credentials")}
+		})
+
+		id := ID(Options{Timeout: time.Second, CredentialsAuthoritative: true})
+
+		assert.Equal(t, "gcp-id-credentials", id)
+		assert.Zero(t, env.calls)
+	})
+}
@@ -0,0 +1,143 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResolveProfile accumulates the time spent resolving the project ID, in
+// total and per source, across the calls given it with the Profile option.
+// Unlike the Trace of a Result, which tells the outcome of each strategy of
+// one search, it sums up many calls, to tell where the time goes, like
+// whether caching pays off or the gcloud fallback is worth disabling.
+//
+// The zero value is ready to use. It's safe for concurrent use.
+type ResolveProfile struct {
+	mu          sync.Mutex
+	resolutions int
+	unsearched  int
+	total       time.Duration
+	sources     map[string]*SourceTiming
+}
+
+// SourceTiming is the time spent searching one source, as accumulated by a
+// ResolveProfile.
+type SourceTiming struct {
+	// Source is the name of the source searched, like "env" or "gcloud".
+	Source string
+
+	// Searches is how many times the source was searched.
+	Searches int
+
+	// Total is the time spent searching the source.
+	Total time.Duration
+
+	// Max is the longest search of the source.
+	Max time.Duration
+}
+
+// add records a resolution that took d and ran the steps.
+func (p *ResolveProfile) add(d time.Duration, steps []SearchStep) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resolutions++
+	p.total += d
+	if len(steps) == 0 {
+		p.unsearched++
+	}
+	for _, step := range steps {
+		if p.sources == nil {
+			p.sources = map[string]*SourceTiming{}
+		}
+		t := p.sources[step.Source]
+		if t == nil {
+			t = &SourceTiming{Source: step.Source}
+			p.sources[step.Source] = t
+		}
+		t.Searches++
+		t.Total += step.Duration
+		t.Max = max(t.Max, step.Duration)
+	}
+}
+
+// Resolutions returns how many resolutions were profiled.
+func (p *ResolveProfile) Resolutions() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resolutions
+}
+
+// Unsearched returns how many resolutions ran no search strategy, as when
+// the project ID was cached, Explicit or Frozen.
+func (p *ResolveProfile) Unsearched() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.unsearched
+}
+
+// Total returns the time spent in all the resolutions profiled.
+func (p *ResolveProfile) Total() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.total
+}
+
+// Sources returns the time spent per source, from the slowest in total to
+// the fastest.
+func (p *ResolveProfile) Sources() []SourceTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sources := make([]SourceTiming, 0, len(p.sources))
+	for _, t := range p.sources {
+		sources = append(sources, *t)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Total != sources[j].Total {
+			return sources[i].Total > sources[j].Total
+		}
+		return sources[i].Source < sources[j].Source
+	})
+	return sources
+}
+
+// Reset discards what was profiled so far.
+func (p *ResolveProfile) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resolutions, p.unsearched, p.total, p.sources = 0, 0, 0, nil
+}
+
+// String summarizes the profile, one line for the resolutions and one per
+// source, like:
+//
+//	3 resolutions in 1.2s (avg 400ms), 2 without a search
+//	  gcloud: 1 searches in 1.1s (avg 1.1s, max 1.1s)
+//	  env: 1 searches in 20µs (avg 20µs, max 20µs)
+func (p *ResolveProfile) String() string {
+	sources := p.Sources()
+	p.mu.Lock()
+	resolutions, unsearched, total := p.resolutions, p.unsearched, p.total
+	p.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d resolutions in %v (avg %v), %d without a search",
+		resolutions, total, average(total, resolutions), unsearched)
+	for _, t := range sources {
+		fmt.Fprintf(&b, "\n  %s: %d searches in %v (avg %v, max %v)",
+			t.Source, t.Searches, t.Total, average(t.Total, t.Searches), t.Max)
+	}
+	return b.String()
+}
+
+// average returns total / n, or zero when n is zero.
+func average(total time.Duration, n int) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
@@ -0,0 +1,153 @@
+package project
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveProfile(t *testing.T) {
+	t.Run("Accumulates per source", func(t *testing.T) {
+		clock := useCache(t)
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{
+				&clockSearcherMock{source: "env", clock: clock, d: time.Millisecond},
+				&clockSearcherMock{
+					source: "gcloud", id: "gcp-id-test", clock: clock, d: time.Second,
+				},
+			}
+		})
+		var p ResolveProfile
+		opts := Options{Timeout: time.Minute, Profile: &p}
+
+		ID(opts)
+		ID(opts)
+
+		assert.Equal(t, 2, p.Resolutions())
+		assert.Equal(t, 0, p.Unsearched())
+		assert.Equal(t, 2*time.Second+2*time.Millisecond, p.Total())
+		want := []SourceTiming{
+			{Source: "gcloud", Searches: 2, Total: 2 * time.Second, Max: time.Second},
+			{Source: "env", Searches: 2, Total: 2 * time.Millisecond, Max: time.Millisecond},
+		}
+		assert.Equal(t, want, p.Sources())
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		clock := useCache(t)
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{&clockSearcherMock{
+				source: "gcloud", id: "gcp-id-test", clock: clock, d: time.Second,
+			}}
+		})
+		var p ResolveProfile
+		opts := Options{Timeout: time.Minute, CacheTTL: time.Hour, Profile: &p}
+
+		ID(opts)
+		ID(opts)
+		ID(opts)
+
+		assert.Equal(t, 3, p.Resolutions())
+		assert.Equal(t, 2, p.Unsearched())
+		assert.Equal(t, time.Second, p.Total())
+		want := []SourceTiming{
+			{Source: "gcloud", Searches: 1, Total: time.Second, Max: time.Second},
+		}
+		assert.Equal(t, want, p.Sources())
+	})
+
+	t.Run("OnSearch still called", func(t *testing.T) {
+		clock := useCache(t)
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{&clockSearcherMock{
+				source: "env", id: "gcp-id-test", clock: clock, d: time.Millisecond,
+			}}
+		})
+		var p ResolveProfile
+		var steps []string
+		opts := Options{
+			Timeout:  time.Minute,
+			Profile:  &p,
+			OnSearch: func(step SearchStep) { steps = append(steps, step.Source) },
+		}
+
+		r, err := Resolve(context.Background(), opts)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"env"}, steps)
+		assert.Len(t, r.Trace, 1)
+		assert.Equal(t, 1, p.Resolutions())
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "gcp-id-test")}
+		})
+		var p ResolveProfile
+		opts := Options{Timeout: time.Second, Profile: &p}
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ID(opts)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 10, p.Resolutions())
+		assert.Equal(t, 10, p.Sources()[0].Searches)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "gcp-id-test")}
+		})
+		var p ResolveProfile
+		ID(Options{Timeout: time.Second, Profile: &p})
+
+		p.Reset()
+
+		assert.Zero(t, p.Resolutions())
+		assert.Zero(t, p.Total())
+		assert.Empty(t, p.Sources())
+	})
+}
+
+func TestResolveProfile_String(t *testing.T) {
+	var p ResolveProfile
+	p.add(time.Second+time.Millisecond, []SearchStep{
+		{Source: "env", Duration: time.Millisecond},
+		{Source: "gcloud", Duration: time.Second},
+	})
+	p.add(0, nil)
+
+	want := "2 resolutions in 1.001s (avg 500.5ms), 1 without a search\n" +
+		"  gcloud: 1 searches in 1s (avg 1s, max 1s)\n" +
+		"  env: 1 searches in 1ms (avg 1ms, max 1ms)"
+	assert.Equal(t, want, p.String())
+
+	var empty ResolveProfile
+	assert.Equal(t, "0 resolutions in 0s (avg 0s), 0 without a search", empty.String())
+}
+
+// clockSearcherMock is a searcher that takes d on the test clock.
+type clockSearcherMock struct {
+	source string
+	id     string
+	clock  *time.Time
+	d      time.Duration
+}
+
+var _ Searcher = (*clockSearcherMock)(nil)
+
+func (s *clockSearcherMock) Source() string { return s.source }
+
+func (s *clockSearcherMock) ProjectID(context.Context, ...string) (string, error) {
+	*s.clock = s.clock.Add(s.d)
+	return s.id, nil
+}
@@ -3,12 +3,21 @@
 package project
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"golang.org/x/oauth2/google"
@@ -19,55 +28,968 @@ var (
 )
 
 var (
-	searchers = defaultSearchers()
+	searchers = DefaultSearchers
 )
 
+// Seams for the process environment.
+var (
+	getenv      = os.Getenv
+	environ     = os.Environ
+	goos        = runtime.GOOS
+	userHomeDir = os.UserHomeDir
+)
+
+// ErrProjectIDNotFound is returned (joined) when no project ID is found and
+// the Strict option is enabled, along with a SourceError for each source
+// searched. Use Remediation for the actions they suggest.
+var ErrProjectIDNotFound = errors.New("google cloud project ID not found")
+
+// defaultRejectValues are placeholders commonly left over from templates,
+// which are never a real project ID.
+var defaultRejectValues = []string{
+	"your-project-id",
+	"your_project_id",
+	"your-project",
+	"project-id",
+	"project_id",
+	"<project-id>",
+	"<project_id>",
+	"changeme",
+	"change-me",
+	"replace-me",
+	"todo",
+	"none",
+	"null",
+	"undefined",
+}
+
+// defaultEnvKeys are the environment variables searched by default, in
+// order.
+var defaultEnvKeys = []string{
+	"GCP_PROJECT",
+	"GCLOUD_PROJECT",
+	"GOOGLE_CLOUD_PROJECT",
+}
+
 // ID retrieves the default Google Cloud project ID based on the provided
 // options.
 //
 // It uses the following order when searching:
-//  1. Common environment variables like GCP_PROJECT, GCLOUD_PROJECT,
+//  1. The CLOUDSDK_CORE_PROJECT environment variable, which overrides the
+//     project configured in the `gcloud` CLI.
+//  2. Common environment variables like GCP_PROJECT, GCLOUD_PROJECT,
 //     GOOGLE_CLOUD_PROJECT.
-//  2. The DefaultApplicationCredentials method from the [golang.org/x/oauth2/google]
-//     package.
-//  3. The default project configured in `gcloud` CLI.
+//  3. The PROJECT_ID environment variable, only in Cloud Build, when
+//     BUILD_ID or PROJECT_NUMBER is also set.
+//  4. The `project_id` of the JSON file in GOOGLE_APPLICATION_CREDENTIALS.
+//  5. The DefaultApplicationCredentials method from the [golang.org/x/oauth2/google]
+//     package, or the project of the service account impersonated by the
+//     credentials, falling back to the `quota_project_id` of user
+//     credentials, with the "credentials:quota_project" source.
+//  6. The project of the gcloud configuration, read from its file in the
+//     gcloud configuration directory.
+//  7. The default project configured in `gcloud` CLI, unless the
+//     NoSubprocess option is set.
+//
+// The gcloud configuration is the one given in the GCloudConfiguration
+// option or, as gcloud selects it, the one named by
+// CLOUDSDK_ACTIVE_CONFIG_NAME or else the active one.
+//
+// With the CredentialsAuthoritative option, the credentials (4 and 5) are
+// searched first, so the environment can't override their project.
 //
 // If the project ID is empty and the Strict option is enabled, `ID()`
-// panics.
+// panics with an error wrapping ErrProjectIDNotFound.
+//
+// When the CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT environment variable
+// is set, the `gcloud` CLI is invoked impersonating that service account,
+// so the project reflects the impersonated identity, which may differ from
+// the caller's own project. Impersonation requires the caller to hold the
+// Service Account Token Creator role on the target account and credentials
+// with the https://www.googleapis.com/auth/cloud-platform scope.
 //
 // [golang.org/x/oauth2/google]: https://pkg.go.dev/golang.org/x/oauth2/google#FindDefaultCredentials
 func ID(opts ...Options) string {
+	id, err := IDContext(context.Background(), opts...)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// IDContext retrieves the default Google Cloud project ID like ID, but
+// searches under the given context and returns an error instead of
+// panicking. The Timeout option still applies on top of ctx.
+//
+// A project ID set in ctx with WithProjectID takes precedence over all
+// the other sources, except the Explicit option.
+//
+// If the project ID is empty and the Strict option is enabled, it returns
+// an error wrapping ErrProjectIDNotFound.
+func IDContext(ctx context.Context, opts ...Options) (string, error) {
+	r := resolve(ctx, getOptions(opts...))
+	return r.ID, r.Err
+}
+
+// IDAsync starts retrieving the default Google Cloud project ID in a new
+// goroutine, like IDContext, and returns a channel that receives a single
+// Result and is then closed.
+//
+// The channel is buffered, so the goroutine finishes, bounded by the
+// Timeout option, even if the result is never received.
+func IDAsync(opts ...Options) <-chan Result {
 	o := getOptions(opts...)
+	ch := make(chan Result, 1)
+	go func() {
+		defer close(ch)
+		ch <- resolve(context.Background(), o)
+	}()
+	return ch
+}
+
+// Result is the outcome of a project ID search. Fields may be added in
+// later versions, so it should be built with field names.
+type Result struct {
+	// ID is the project ID found, or empty if none was found.
+	ID string
+
+	// Number is the project number, when the source that provided the ID
+	// also provides it, like Cloud Build's PROJECT_NUMBER, as a decimal
+	// string with no leading zeros, whatever the source's format. It's
+	// empty otherwise.
+	Number string
+
+	// Source is the name of the source that provided the ID, like "env",
+	// "credentials" or "gcloud". It's empty when no ID was found.
+	Source string
+
+	// Found reports whether a project ID was found.
+	Found bool
+
+	// Err is the error that stopped the search, if any.
+	Err error
+
+	// Duration is how long the search took. It's only set in the Result
+	// passed to the OnResolved hook.
+	Duration time.Duration
+
+	// Trace holds the outcome of each search strategy run, in order. It's
+	// only set by Resolve, and is empty when no strategy ran, like for
+	// cached results.
+	Trace []SearchStep
+}
+
+// Resolve retrieves the default Google Cloud project ID like IDContext,
+// and returns the full Result of the search, including the trace of the
+// strategies run. The returned error is also set in Result.Err.
+//
+// It's the most complete entry point; ID, IDContext and TryID are
+// conveniences that return only part of the Result.
+func Resolve(ctx context.Context, opts ...Options) (Result, error) {
+	o := getOptions(opts...)
+	var (
+		mu    sync.Mutex
+		trace []SearchStep
+	)
+	onSearch := o.OnSearch
+	o.OnSearch = func(step SearchStep) {
+		mu.Lock()
+		trace = append(trace, step)
+		mu.Unlock()
+		if onSearch != nil {
+			onSearch(step)
+		}
+	}
+
+	r := resolve(ctx, o)
+	r.Trace = trace
+	return r, r.Err
+}
+
+// TryID retrieves the default Google Cloud project ID like ID, but reports
+// whether one was found instead of panicking. Errors, including the
+// ErrProjectIDNotFound of the Strict option, are reported as not found;
+// use Resolve to tell them apart.
+func TryID(opts ...Options) (string, bool) {
+	r := resolve(context.Background(), getOptions(opts...))
+	return r.ID, r.Found
+}
+
+// ResolveWithTimeout retrieves the default Google Cloud project ID with the
+// default options and the given timeout, returning an error instead of
+// panicking. It's the recommended one-liner for the common case:
+//
+//	id, err := project.ResolveWithTimeout(5 * time.Second)
+//
+// It's the same as IDContext with Options{Timeout: d}.
+func ResolveWithTimeout(d time.Duration) (string, error) {
+	return IDContext(context.Background(), Options{Timeout: d})
+}
+
+// IDOrDefault retrieves the default Google Cloud project ID like ID, but
+// returns def instead when none is found or the search fails.
+func IDOrDefault(def string, opts ...Options) string {
+	if id, ok := TryID(opts...); ok {
+		return id
+	}
+	return def
+}
+
+// MustResolve retrieves the default Google Cloud project ID like
+// IDContext, but always returns an error when none is found, wrapping
+// ErrProjectIDNotFound and the reason each source missed, whatever the
+// Strict option. It never panics.
+//
+// How the entry points handle a missing project ID:
+//
+//	             Strict false             Strict true
+//	ID           ""                       panics
+//	IDContext    "", nil                  "", ErrProjectIDNotFound
+//	TryID        "", false                "", false
+//	IDOrDefault  def                      def
+//	MustResolve  "", ErrProjectIDNotFound "", ErrProjectIDNotFound
+//
+// A Fallback searcher only runs its fallback when its primary finds
+// nothing, so it's a missing project ID for them all only when both miss.
+func MustResolve(ctx context.Context, opts ...Options) (string, error) {
+	o := getOptions(opts...)
+	o.Strict = true
+	r := resolve(ctx, o)
+	return r.ID, r.Err
+}
+
+// ResolveFirst runs the given searchers in order, under ctx, and returns
+// the first non-empty project ID with the name of the source that found it.
+// It stops at the first error, unless the searcher that failed is wrapped
+// in Fallback. It returns empty values when none finds a project ID, and
+// the error of ctx if it's done before they all ran.
+//
+// It's the building block of the other entry points, for full control over
+// the search: none of the options apply, so there's no default timeout,
+// caching, placeholder rejection or post-processing.
+func ResolveFirst(ctx context.Context, sources ...Searcher) (
+	id, source string, err error,
+) {
+	o := Options{RejectValues: []string{}}
+	id, s, _, err := firstProjectID(ctx, o, sources)
+	if err != nil {
+		return "", "", err
+	}
+	return id, sourceOf(s), nil
+}
+
+// resolve searches the project ID with the options and the default
+// Resolver, as the package-level functions do.
+func resolve(ctx context.Context, o Options) Result {
+	return defaultResolver.resolve(ctx, o)
+}
+
+// resolve searches the project ID with the options, reporting the result
+// to the OnResolved hook when one is found.
+func (rv *Resolver) resolve(ctx context.Context, o Options) Result {
+	if o.OnResolved == nil && o.Profile == nil && o.AuditLog == nil {
+		return rv.resolveResult(ctx, o)
+	}
 	var (
-		background  = context.Background()
-		ctx, cancel = context.WithTimeout(background, o.Timeout)
+		mu    sync.Mutex
+		steps []SearchStep
 	)
+	if o.Profile != nil {
+		onSearch := o.OnSearch
+		o.OnSearch = func(step SearchStep) {
+			mu.Lock()
+			steps = append(steps, step)
+			mu.Unlock()
+			if onSearch != nil {
+				onSearch(step)
+			}
+		}
+	}
+
+	start := now()
+	r := rv.resolveResult(ctx, o)
+	d := now().Sub(start)
+	if o.Profile != nil {
+		mu.Lock()
+		o.Profile.add(d, steps)
+		mu.Unlock()
+	}
+	if r.Found && o.AuditLog != nil {
+		writeAudit(o.AuditLog, start, d, r)
+	}
+	if r.Found && o.OnResolved != nil {
+		resolved := r
+		resolved.Duration = d
+		o.OnResolved(resolved)
+	}
+	return r
+}
+
+// resolveResult searches the project ID with the options.
+func (rv *Resolver) resolveResult(ctx context.Context, o Options) Result {
+	if id, ok := Frozen(); ok && !rv.hermetic {
+		return Result{ID: id, Source: "frozen", Found: true}
+	}
+	if o.Explicit != "" {
+		r, err := postProcess(o, Result{ID: o.Explicit, Source: "explicit"})
+		if err != nil {
+			return Result{Err: err}
+		}
+		return r
+	}
+	if id, ok := ProjectIDFromContext(ctx); ok {
+		return Result{ID: id, Source: "context", Found: true}
+	}
+	if o.CacheTTL > 0 && !rv.hermetic {
+		if r, ok := cache.get(o); ok {
+			return r
+		}
+	}
+	if o.CacheTTL > 0 && o.DiskCache && diskCacheable(o) && !rv.hermetic {
+		if r, ok := diskCacheGet(o); ok {
+			cache.put(o, r, nil)
+			return r
+		}
+	}
+
+	ctx, cancel := withDeadline(ctx, o)
 	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return Result{Err: err}
+	}
+
+	found := foundCredentialsFromContext(ctx)
+	if o.VerifyAgainstCredentials && found == nil {
+		found = &foundCredentials{}
+		ctx = withFoundCredentials(ctx, found)
+	}
 
-	id, err := defaultProjectID(ctx, o.Scopes...)
+	id, s, misses, err := rv.searchChain(ctx, o)
 	if err != nil {
-		panic(err)
+		return Result{Err: err}
 	}
-	if id == "" && o.Strict {
-		msg := "Google Cloud project ID not found; check your credentials " +
-			"file, set the GCP_PROJECT environment variable or install the " +
-			"`gcloud` CLI and run `gcloud init` to configure your project."
-		panic(msg)
+	r, err := postProcess(o, Result{
+		ID:     id,
+		Number: projectNumberOf(s),
+		Source: sourceOf(s),
+	})
+	if err == ErrProjectIDNotFound {
+		// The Strict check failed: tell what each source missed.
+		err = notFoundError(misses)
+	}
+	if err != nil {
+		return Result{Err: err}
+	}
+	if errs := failures(misses); !r.Found && len(errs) != 0 {
+		return Result{Err: errors.Join(errs...)}
+	}
+	if r.Found && o.VerifyAgainstCredentials {
+		if err := verifyAgainstCredentials(ctx, o, found, r.ID, r.Source); err != nil {
+			return Result{Err: err}
+		}
 	}
 
+	if rv.hermetic {
+		return r
+	}
+	if r.ID != "" {
+		observe(r.ID)
+	}
+	if r.ID != "" && o.CacheTTL > 0 {
+		cache.put(o, r, s)
+	}
+	if r.ID != "" && o.CacheTTL > 0 && o.DiskCache && diskCacheable(o) {
+		diskCachePut(o, r)
+	}
+	return r
+}
+
+// postProcess applies the options to the result of the searchers, in order:
+// StripResourcePrefix, StripDomain, Aliases, PostResolve, Validate,
+// Validator and Strict. It runs even when no project ID was found.
+func postProcess(o Options, r Result) (Result, error) {
+	if o.StripResourcePrefix {
+		r.ID = StripResourcePrefix(r.ID)
+	}
+	if o.StripDomain {
+		_, r.ID = SplitDomainScopedID(r.ID)
+	}
+	if id, ok := o.Aliases[r.ID]; ok && r.ID != "" {
+		// The number was for the alias, if anything, not for this one.
+		r.ID, r.Number = id, ""
+	}
+	if o.PostResolve != nil {
+		id, err := o.PostResolve(r.ID, r.Source)
+		if err != nil {
+			return Result{}, fmt.Errorf("post resolve: %w", err)
+		}
+		if id != r.ID {
+			// The number was for the project found, not for this one.
+			r.ID, r.Number = id, ""
+		}
+	}
+	if r.ID != "" && o.Validate {
+		if err := ValidateProjectID(r.ID); err != nil {
+			return Result{}, err
+		}
+	}
+	if r.ID != "" && o.Validator != nil {
+		if err := o.Validator(r.ID); err != nil {
+			return Result{}, fmt.Errorf("validator: %w", err)
+		}
+	}
+	if r.ID == "" && o.Strict {
+		return Result{}, ErrProjectIDNotFound
+	}
+	if r.ID == "" {
+		return Result{}, nil
+	}
+	r.Found = true
+	return r, nil
+}
+
+// EnvID retrieves the project ID from the given environment variables, or
+// from the common ones searched by ID when no keys are given.
+//
+// Unlike ID, it never looks for credentials or runs the `gcloud` CLI, so it
+// doesn't block or touch the filesystem or the network. It returns an empty
+// string if none of the variables are set.
+func EnvID(keys ...string) string {
+	if len(keys) == 0 {
+		keys = defaultEnvKeys
+	}
+	s := newEnvironmentSearcher(keys...)
+	id, _ := s.ProjectID(context.Background())
 	return id
 }
 
+// SetEnvKeys returns the environment variables searched by default that are
+// set, with their raw values: CLOUDSDK_CORE_PROJECT, the common ones like
+// GOOGLE_CLOUD_PROJECT and, in Cloud Build, PROJECT_ID. The search uses the
+// first one set, so this helps tell when several disagree.
+func SetEnvKeys() map[string]string {
+	keys := append(append([]string(nil), gcloudPropertyKeys...), defaultEnvKeys...)
+	if inCloudBuild() {
+		keys = append(keys, "PROJECT_ID")
+	}
+	set := map[string]string{}
+	for _, key := range keys {
+		if v := lookupEnv(key); v != "" {
+			set[key] = v
+		}
+	}
+	return set
+}
+
 // Options represents the configuration options for the ID function.
 type Options struct {
-	// Default: 30s.
+	// Timeout bounds the search. When zero, and no Deadline is set, it
+	// defaults to 30s.
 	Timeout time.Duration
 
+	// Deadline, if set, is an absolute deadline for the search, as an
+	// alternative to Timeout. When both are set, the earlier one applies.
+	// A deadline in the past fails the search immediately with
+	// context.DeadlineExceeded.
+	Deadline time.Time
+
+	// DiscoveryTimeout, if positive, bounds the discovery of each source,
+	// apart from the Timeout: finding the gcloud executables in the PATH
+	// and the home directory, and finding the configuration and key
+	// files. Discovery is cheap, but it may hang on a stalled network
+	// filesystem, like an NFS home directory. Sources whose discovery
+	// takes longer are skipped, with a warning in their SearchStep, and
+	// the search goes on with the others, while the discovery goes on in
+	// the background, joined by the later searches. The application
+	// default credentials, which fall back to the metadata server, are
+	// never skipped. Default: no separate budget, discovery is bounded by
+	// the Timeout alone.
+	DiscoveryTimeout time.Duration
+
 	// Scopes is the list OAuth scopes.
 	Scopes []string
 
 	// If true, ID() panics when no default project ID is found.
 	Strict bool
+
+	// If true, ID() panics when the project ID found is not well-formed.
+	// See ValidateProjectID.
+	Validate bool
+
+	// Validator, if set, is called with the project ID found, to enforce
+	// conventions beyond its format, like a team prefix. An error aborts
+	// the search with it, so ID() panics. It runs after the Validate check,
+	// when both are set, and is not called when no project ID is found.
+	Validator func(id string) error
+
+	// FindCredentials, if set, is used instead of
+	// google.FindDefaultCredentials to obtain the credentials that carry
+	// the project ID. It allows callers to plug other credential sources,
+	// such as impersonated credentials. When set, the key file in
+	// GOOGLE_APPLICATION_CREDENTIALS and the quota project of the
+	// application default credentials aren't searched.
+	FindCredentials func(ctx context.Context, scopes ...string) (
+		*google.Credentials, error)
+
+	// VerifyAgainstCredentials, if true, checks that the project ID found,
+	// by any source, is the project of the application default credentials,
+	// or of the FindCredentials option, so an environment variable naming
+	// another project than the credentials is caught early. On mismatch, the
+	// search fails with an error wrapping ErrProjectMismatch. Credentials
+	// without a project, like user credentials, pass. It doesn't apply to
+	// the Explicit option, nor to a project ID from the context.
+	VerifyAgainstCredentials bool
+
+	// StartSpan, if set, is called before each search strategy runs, with
+	// the name of its source (like "env", "credentials" or "gcloud"). The
+	// strategy runs under the returned context, and the returned function,
+	// if not nil, is called when it finishes. It allows tracing each
+	// strategy, including the `gcloud` subprocess and the metadata server
+	// requests, as child spans.
+	StartSpan func(ctx context.Context, name string) (context.Context, func())
+
+	// CacheTTL, if positive, caches the project ID found for this long.
+	// Later calls with the same options, like the scopes, EnvKeys and
+	// Searchers, return the cached ID without searching. The cache is shared
+	// by all calls in the process and errors are never cached.
+	CacheTTL time.Duration
+
+	// InvalidateOnFileChange, when caching, discards the cached project ID
+	// if it came from a file, like the application default credentials
+	// file, and that file's modification time changed since. The file is
+	// checked on each call, so running `gcloud auth application-default
+	// login` is picked up without waiting for the TTL.
+	InvalidateOnFileChange bool
+
+	// DiskCache, if true, also caches the project ID found in a file, for
+	// the CacheTTL, so the later processes with the same options and
+	// environment, like repeated CLI invocations, read it instead of
+	// searching. It has no effect without the CacheTTL, nor with the
+	// Searchers or any function option, like Validator, which another
+	// process can't tell apart. Missing, stale, corrupt or untrusted files
+	// fall back to a search.
+	DiskCache bool
+
+	// DiskCachePath is the file of the DiskCache. Default:
+	// gcp-project-id-<uid>.json in os.TempDir().
+	DiskCachePath string
+
+	// WatchEnv, when caching, discards the cached project ID if any of the
+	// environment variables searched changed since it was found, as when
+	// the process calls os.Setenv. The variables are checked on each call,
+	// which is cheap compared to a search.
+	WatchEnv bool
+
+	// YAMLConfigFile, if set, is a YAML file to read the project ID from,
+	// searched after the environment variables. A missing file or key is
+	// not an error.
+	YAMLConfigFile string
+
+	// YAMLProjectPath is the dotted path of the project ID in the
+	// YAMLConfigFile. Default: "gcp.project".
+	YAMLProjectPath string
+
+	// YAMLUnmarshal decodes the YAMLConfigFile, like yaml.Unmarshal from
+	// the gopkg.in/yaml.v3 package. It's required with YAMLConfigFile and
+	// DeployConfigFile, so this package doesn't depend on a YAML library.
+	YAMLUnmarshal func(data []byte, v any) error
+
+	// DeployConfigFile, if set, is a Skaffold or Cloud Deploy configuration,
+	// like skaffold.yaml or clouddeploy.yaml, to read the target project ID
+	// from, searched after the YAMLConfigFile. It's decoded with
+	// YAMLUnmarshal, which is required with it. A missing file or key is not
+	// an error.
+	DeployConfigFile string
+
+	// DeployProjectPath is the dotted path of the project ID in the
+	// DeployConfigFile, like "deploy.gke.project". Default:
+	// "deploy.cloudrun.projectid".
+	DeployProjectPath string
+
+	// EncryptedConfigFile, if set, is an encrypted file to read the project
+	// ID from, like one managed with SOPS or Berglas, searched after the
+	// DeployConfigFile. Once decrypted, it's either a JSON object or KEY=VALUE
+	// assignments, where the environment variables searched are looked up,
+	// like GOOGLE_CLOUD_PROJECT. A JSON object may also have a `project_id`.
+	// A missing file or key is not an error.
+	EncryptedConfigFile string
+
+	// Decryptor decrypts the EncryptedConfigFile. It's required with
+	// EncryptedConfigFile, so this package doesn't depend on a crypto
+	// library.
+	Decryptor func(ctx context.Context, ciphertext []byte) ([]byte, error)
+
+	// MaxConcurrentGCloud, if positive, limits how many `gcloud`
+	// subprocesses run at once in the process, across all goroutines and
+	// calls with this option set. Searches beyond the limit wait for a free
+	// slot, bounded by the Timeout. Default: unlimited.
+	MaxConcurrentGCloud int
+
+	// MaxGCloudOutput, if positive, is the most bytes read from the output
+	// of a `gcloud` run. A run printing more, like a misbehaving or
+	// impostor gcloud, is stopped and fails, so the next installation found
+	// is tried. Default: 64 KiB.
+	MaxGCloudOutput int
+
+	// EnvKeys, if set, replaces the common environment variables searched
+	// (GCP_PROJECT, GCLOUD_PROJECT and GOOGLE_CLOUD_PROJECT). The keys
+	// are used verbatim, in order.
+	EnvKeys []string
+
+	// EnvChain, if set, replaces the common environment variables searched,
+	// like EnvKeys, with lookups that may have a default: they're searched
+	// in order, and the first one set, or else with a Default, provides the
+	// project ID, with the "env" source. A Default thus ends the chain:
+	//
+	//	EnvChain: []project.EnvLookup{
+	//		{Key: "APP_GCP_PROJECT"},
+	//		{Key: "GCP_PROJECT", Default: "my-project"},
+	//	}
+	//
+	// It takes precedence over EnvKeys and EnvPrefix.
+	EnvChain []EnvLookup
+
+	// EnvPrefix, if set, also searches the common environment variables
+	// under this prefix, like MYTOOL_GCP_PROJECT for "MYTOOL", before the
+	// others. The prefix only applies to the common variables, not to the
+	// EnvKeys.
+	EnvPrefix string
+
+	// StripDomain, if true, returns only the project part of legacy
+	// domain-scoped project IDs, like "my-project" for
+	// "example.com:my-project". See SplitDomainScopedID.
+	StripDomain bool
+
+	// StripResourcePrefix, if true, returns only the project of values
+	// given as resource names, like "my-project" for "projects/my-project",
+	// as plumbed from Resource Manager responses. It runs before
+	// StripDomain. See StripResourcePrefix.
+	StripResourcePrefix bool
+
+	// UseBoto, if true, also searches the `default_project_id` of the
+	// [GSUtil] section in the legacy boto configuration used by gsutil,
+	// after the credentials. The file is ~/.boto, unless overridden by the
+	// BOTO_CONFIG or BOTO_PATH environment variables.
+	UseBoto bool
+
+	// PostResolve, if set, is called once with the project ID found and
+	// the name of its source, and returns the project ID to use instead. It
+	// can transform the ID, pass it through or veto it by returning an
+	// error. It is also called, with empty values, when no project ID is
+	// found, so it can supply a default.
+	//
+	// It runs after StripDomain and before the Validate and Strict checks,
+	// which apply to the value it returns. It's not called for project IDs
+	// set with WithProjectID or returned from the cache.
+	PostResolve func(id, source string) (string, error)
+
+	// OnSearch, if set, is called after each search strategy runs, with
+	// its outcome. It's meant for troubleshooting and observability: the
+	// step includes, for instance, the stderr output of failed `gcloud`
+	// invocations.
+	OnSearch func(step SearchStep)
+
+	// OnResolved, if set, is called once when a project ID is found, with
+	// the final Result, including how long the search took, whatever the
+	// source, like the cache or the Explicit option. It's the hook for a
+	// one-line startup log, like "project=my-project source=credentials",
+	// while OnSearch reports each search strategy run.
+	OnResolved func(r Result)
+
+	// AuditLog, if set, receives one JSON line for each resolution that
+	// finds a project ID, including the cached ones, for retention:
+	//
+	//	{"ts":"2024-05-01T12:00:00.000000001Z","id":"my-project","source":"env","duration":"1.2ms"}
+	//
+	// The lines only hold these fields, never the errors or the values of
+	// other sources, which may be sensitive. Writes to it are serialized
+	// across all the calls, and write errors are ignored.
+	AuditLog io.Writer
+
+	// Profile, if set, accumulates the time spent in each resolution with
+	// these options, in total and per source. Share one across calls to
+	// profile them all, then print it for a summary.
+	Profile *ResolveProfile
+
+	// RejectValues are placeholder values that are treated as empty when a
+	// source returns them, so the search continues with the next source.
+	// Matching is case-insensitive and exact, on the trimmed value. When
+	// nil, a default set of common template placeholders is used, like
+	// "your-project-id", "changeme" and "PROJECT_ID". Set it to an empty,
+	// non-nil slice to reject nothing.
+	RejectValues []string
+
+	// Policy defines how the sources are searched and combined. Default:
+	// FirstMatch.
+	Policy Policy
+
+	// CredentialName, if set, is the name of a systemd credential holding
+	// the project ID, searched after the environment variables. It's read
+	// from $CREDENTIALS_DIRECTORY/<name>, when CREDENTIALS_DIRECTORY is set,
+	// as for services using LoadCredential= or SetCredential=.
+	CredentialName string
+
+	// K8sTokenFile is the Kubernetes projected service account token whose
+	// audience, PROJECT_ID.svc.id.goog for GKE Workload Identity, provides
+	// the project ID. It's searched after the configuration files. Default:
+	// /var/run/secrets/tokens/gcp-ksa/token.
+	K8sTokenFile string
+
+	// KCCAnnotationsFile, if set, is a file holding the Config Connector
+	// annotation cnrm.cloud.google.com/project-id, searched after the
+	// K8sTokenFile. It's either the KEY="VALUE" lines of a downward API
+	// volume projecting the annotations, or just the project ID. A missing
+	// file or annotation is not an error.
+	KCCAnnotationsFile string
+
+	// Aliases maps friendly project names, like "prod" or "staging", to
+	// real project IDs. When the project ID found, or the Explicit one, is
+	// an alias, it's replaced with the project ID it maps to. Aliases are
+	// not chained: the mapped value is not looked up again. They're
+	// resolved after StripDomain and before PostResolve and the
+	// validations.
+	Aliases map[string]string
+
+	// CredentialsBase64Env, if set, is an environment variable holding a
+	// base64-encoded service account key, like GOOGLE_CREDENTIALS_BASE64 in
+	// CI systems, whose `project_id` field is searched before the
+	// credentials file.
+	CredentialsBase64Env string
+
+	// CredentialHelper, if set, is a command, with its args, printing JSON
+	// configuration with a `project_id` field, searched before the
+	// credentials file. A helper exiting with an error, or printing no
+	// project_id, yields no project ID.
+	CredentialHelper []string
+
+	// NoSubprocess, if true, never runs a subprocess: the `gcloud` CLI and
+	// the CredentialHelper are not searched, while the environment, the
+	// credentials, the metadata server and the files, including the gcloud
+	// configuration, still are. It's the recommended setting for hardened
+	// containers, like distroless images or seccomp policies forbidding
+	// exec. It doesn't apply to the Searchers option.
+	NoSubprocess bool
+
+	// SecureFilesOnly refuses to read the project ID from files that are
+	// writable by the group or by other users, like the application default
+	// credentials, the gcloud configuration or an env file, as they could
+	// have been tampered with. Such sources fail with an error wrapping
+	// ErrInsecureFile. It's ignored on Windows.
+	SecureFilesOnly bool
+
+	// UseSystemEnvFile, if true, also searches the environment variables
+	// searched, from CLOUDSDK_CORE_PROJECT to the EnvKeys, in the
+	// SystemEnvFile, right after the environment itself. It's meant for
+	// init systems that write the environment to a file rather than export
+	// it.
+	UseSystemEnvFile bool
+
+	// SystemEnvFile is the file of KEY=VALUE assignments, one per line,
+	// searched with UseSystemEnvFile. Quotes around the values, comments
+	// and `export` prefixes are handled as in shell env files, and NUL
+	// separated assignments are also accepted, so /proc/1/environ works
+	// too. Default: /etc/environment.
+	SystemEnvFile string
+
+	// UseBuildTimeID, if true, searches the BuildTimeProjectID set with the
+	// linker, after the environment variables, so deployments can still
+	// override the value baked into the binary.
+	UseBuildTimeID bool
+
+	// Explicit, if set, is returned as the project ID, with the "explicit"
+	// source, without searching. It's meant for values the user provided,
+	// like a --project flag, so auto-detection is only a fallback when they
+	// are empty. It has the highest precedence among all sources, and is
+	// still subject to StripDomain, PostResolve and Validate.
+	Explicit string
+
+	// WarnOnGCloud, if true, sets a Warning in the step passed to OnSearch
+	// when the `gcloud` CLI, or its configuration files, read directly, are
+	// the source that provides the project ID. It helps catch production
+	// images that ship, and depend on, gcloud. It has no effect without
+	// OnSearch.
+	WarnOnGCloud bool
+
+	// RemoteConfig, if set, is a central configuration service searched
+	// after the environment variables.
+	RemoteConfig *RemoteConfig
+
+	// RaceCredentials, if true, queries the metadata server for the project
+	// ID concurrently with the search of the application default
+	// credentials, and uses whichever answers first, canceling the other.
+	// On Google Cloud, where both answer, it shortens cold starts. When both
+	// have answered by then, the credentials win. The result keeps the
+	// "credentials" source. The metadata server is given up on after the
+	// short deadline of the OnGCP probe, so off Google Cloud the race is
+	// no slower than the credentials alone.
+	RaceCredentials bool
+
+	// UseMetadataCacheFile, if true, searches the project ID cached from
+	// the metadata server by an agent on the instance, in the
+	// MetadataCacheFile, before the credentials, to spare the HTTP request.
+	UseMetadataCacheFile bool
+
+	// MetadataCacheFile is the file, holding the project ID alone, searched
+	// with UseMetadataCacheFile. Default: /var/lib/google/metadata/project-id.
+	MetadataCacheFile string
+
+	// MetadataCacheMaxAge is how long the MetadataCacheFile is fresh after
+	// its modification time. Stale files are ignored. Default: an hour.
+	MetadataCacheMaxAge time.Duration
+
+	// MetadataAttribute, if set, is a custom project metadata attribute,
+	// like "app-project", holding the project ID to use instead of the one
+	// of the instance, as when the infrastructure and the application live
+	// in separate projects. It's read from project/attributes/<name> on the
+	// metadata server, whenever the metadata server is searched, falling
+	// back to the project ID of the instance when it's missing or empty.
+	MetadataAttribute string
+
+	// CredentialsAuthoritative, if true, searches the credentials first:
+	// the key in CredentialsBase64Env, the CredentialHelper and the
+	// application default credentials, before the environment variables,
+	// the configuration files and gcloud, which are only searched when the
+	// credentials carry no project ID, like user credentials. It inverts the
+	// default precedence, so a stray variable can't point the API calls to
+	// another project than the credentials'. It applies after the Order
+	// option and the MostSpecific policy, and has no effect with the
+	// Consistent policy, which searches all sources.
+	CredentialsAuthoritative bool
+
+	// StickySource, if true, remembers the source of the first project ID
+	// found in the process, like "env", and searches it alone on the later
+	// calls, so the answer doesn't move to another source mid-process. The
+	// rest of the chain is searched only when that source now finds
+	// nothing, as when the environment variable was unset. Unlike the
+	// cache, which keeps the value, it keeps the source, and the value it
+	// provides may change. It has no effect with the Consistent policy, and
+	// on the Resolvers from NewHermetic.
+	StickySource bool
+
+	// Order, if set, lists the sources searched first, in order, like
+	// []string{"metadata", "env"}, followed by the rest of the default
+	// chain. The "metadata" source, the metadata server, is added to the
+	// chain when listed. It overrides the order picked on Cloud Functions:
+	// the environment first on the 1st gen, and the metadata server right
+	// after GOOGLE_CLOUD_PROJECT on the 2nd gen. It has no effect with
+	// Searchers.
+	Order []string
+
+	// ChainRetries is how many times the whole chain of sources is searched
+	// again, after the ChainRetryDelay, when it finds no project ID, for
+	// environment variables or credential files that show up shortly after
+	// the process starts. The retries are bounded by the Timeout. Default:
+	// no retries.
+	ChainRetries int
+
+	// ChainRetryDelay is the delay before each of the ChainRetries. Default:
+	// 100ms.
+	ChainRetryDelay time.Duration
+
+	// ChainRetryOnError, if true, also retries the chain when a source
+	// fails, as set by ChainRetries. Otherwise the failure is returned.
+	ChainRetryOnError bool
+
+	// ContinueOnError, if true, continues the search with the next source
+	// when one fails, instead of stopping with its error. The failures are
+	// only returned, as joined SourceError values, when no project ID is
+	// found.
+	ContinueOnError bool
+
+	// GCloudStrictParse, if true, only accepts `gcloud` output that is a
+	// single line holding a valid project ID, and treats anything else as
+	// no project found. By default, the output is parsed leniently, using
+	// its last non-empty line, since gcloud may print update notices or
+	// survey prompts along with the value.
+	GCloudStrictParse bool
+
+	// GCloudConfiguration, if set, is the named gcloud configuration to
+	// read the project from, as with the --configuration flag. When empty,
+	// the configuration named by CLOUDSDK_ACTIVE_CONFIG_NAME is used, and
+	// then the active one, as gcloud does.
+	GCloudConfiguration string
+
+	// UseGCloudConfigFiles, if true, also reads the project from the gcloud
+	// configuration files directly, before running the gcloud CLI, which
+	// it spares when they have one. The CLI stays the source of truth: the
+	// files aren't read when other options make it answer differently, as
+	// told for each. When no configuration is selected and the default one
+	// has no project, the only configuration with one is used, with the
+	// "gcloud-config:inferred" source.
+	UseGCloudConfigFiles bool
+
+	// GCloudAccount, if set, is the account whose gcloud properties are
+	// used to find the project, as with the --account flag, when several
+	// are logged in. The account must be authenticated with
+	// `gcloud auth login`, or gcloud fails. When set, the gcloud
+	// configuration files aren't read directly, even with
+	// UseGCloudConfigFiles, as they don't tell the properties of the
+	// account: only the CLI is, unless NoSubprocess is set, which leaves
+	// the files.
+	GCloudAccount string
+
+	// GCloudFormat, if set, is passed to `gcloud config get-value project`
+	// with the --format flag, like "json". Formats other than the default
+	// plain value need a matching GCloudParse. When either is set, the
+	// gcloud configuration files aren't read directly, even with
+	// UseGCloudConfigFiles, so the project ID comes from the output they
+	// apply to.
+	GCloudFormat string
+
+	// GCloudParse, if set, extracts the project ID from the output of
+	// `gcloud config get-value project`, as printed with the GCloudFormat,
+	// instead of the default parser, which uses the trimmed value. An
+	// error makes it a failed run. It doesn't apply to the `gcloud info`
+	// fallback.
+	GCloudParse func(output []byte) (string, error)
+
+	// UseGCloudConfigHelper, if true, also searches the project of the
+	// configuration printed by `gcloud config config-helper --format=json`,
+	// before `gcloud config get-value project`. It reflects the full
+	// configuration gcloud resolves, including the properties set in the
+	// environment, but also fetches an access token, so it needs an
+	// authenticated account. When set, the gcloud configuration files
+	// aren't read directly, even with UseGCloudConfigFiles, as they would
+	// answer first. The
+	// GCloudConfiguration, GCloudAccount and MaxConcurrentGCloud options
+	// apply. It has no effect with NoSubprocess.
+	UseGCloudConfigHelper bool
+
+	// UniverseDomain, if set, is the universe domain, like "googleapis.com"
+	// for the public Google Cloud or the domain of a sovereign cloud, the
+	// project must belong to. The credentials, from their universe_domain
+	// field, and the metadata server, from its universe/universe-domain
+	// entry, must be in it, or the search fails with an error wrapping
+	// ErrUniverseDomainMismatch. The default universe is assumed when they
+	// don't tell. When empty, no universe is checked.
+	UniverseDomain string
+
+	// Searchers, if set, replaces the default search strategies. It can be
+	// built from the FirstOf and Fallback combinators, and extend the
+	// DefaultSearchers.
+	Searchers []Searcher
+}
+
+// withDeadline returns a copy of ctx bounded by the Timeout and Deadline
+// options. The Timeout counts from the real time, not the now seam, which
+// is only for the cache expiry.
+func withDeadline(ctx context.Context, o Options) (context.Context, context.CancelFunc) {
+	timeout := o.Timeout
+	if o.Deadline.IsZero() {
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		return context.WithTimeout(ctx, timeout)
+	}
+	ctx, cancel := context.WithDeadline(ctx, o.Deadline)
+	if timeout <= 0 {
+		return ctx, cancel
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
 }
 
 func getOptions(opts ...Options) Options {
@@ -80,36 +1002,261 @@ func getOptions(opts ...Options) Options {
 	return o
 }
 
-func defaultProjectID(ctx context.Context, scopes ...string) (string, error) {
-	for _, s := range searchers {
-		id, err := s.ProjectID(ctx, scopes...)
-		if err != nil {
-			return "", err
+// defaultProjectID returns the first project ID found and the searcher that
+// found it. When none is found, and the Strict or ContinueOnError options
+// are set, misses has a SourceError for each source searched.
+func (rv *Resolver) defaultProjectID(ctx context.Context, o Options) (
+	id string, winner Searcher, misses []error, err error,
+) {
+	ss := o.Searchers
+	if len(ss) == 0 {
+		ss = rv.searchers(o)
+	}
+	switch o.Policy {
+	case MostSpecific:
+		ss = bySpecificity(ss)
+	case Consistent:
+		return consistentProjectID(ctx, o, ss)
+	}
+	if o.CredentialsAuthoritative {
+		ss = reorder(ss, credentialsSources, o)
+	}
+	if o.StickySource && !rv.hermetic {
+		return stickyProjectID(ctx, o, ss)
+	}
+
+	return firstProjectID(ctx, o, ss)
+}
+
+// firstProjectID runs the searchers in order and returns the first project
+// ID found, not rejected, and the searcher that found it, as defaultProjectID
+// does for the FirstMatch and MostSpecific policies.
+func firstProjectID(ctx context.Context, o Options, ss []Searcher) (
+	id string, winner Searcher, misses []error, err error,
+) {
+	for _, s := range ss {
+		id, err := search(ctx, o, s)
+		if err != nil && !o.ContinueOnError {
+			return "", nil, nil, err
 		}
-		if id != "" {
-			return id, nil
+		if err == nil && id != "" && !rejected(o, id) {
+			return id, s, nil, nil
 		}
+		misses = addMiss(misses, o, s, err)
 	}
-	return "", nil
+	if err := ctx.Err(); err != nil {
+		// Some sources may not have been searched, or were interrupted.
+		return "", nil, nil, err
+	}
+	return "", nil, misses, nil
+}
+
+// rejected reports whether id is one of the values to reject, given by the
+// RejectValues option or defaultRejectValues.
+func rejected(o Options, id string) bool {
+	values := o.RejectValues
+	if values == nil {
+		values = defaultRejectValues
+	}
+	id = strings.TrimSpace(id)
+	for _, v := range values {
+		if strings.EqualFold(id, strings.TrimSpace(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func search(ctx context.Context, o Options, s Searcher) (string, error) {
+	if o.StartSpan != nil {
+		var end func()
+		ctx, end = o.StartSpan(ctx, sourceOf(s))
+		if end != nil {
+			defer end()
+		}
+	}
+	if o.OnSearch == nil {
+		return projectID(ctx, o, s)
+	}
+
+	step := SearchStep{Source: sourceOf(s)}
+	start := now()
+	id, err := projectID(withStep(ctx, &step), o, s)
+	step.ID, step.Err, step.Duration = id, err, now().Sub(start)
+	if o.WarnOnGCloud && id != "" && fromGCloud(step.Source) {
+		step.Warning = gcloudWarning
+	}
+	o.OnSearch(step)
+	return id, err
+}
+
+// projectID runs the searcher, after checking the files backing it when the
+// SecureFilesOnly option is set.
+func projectID(ctx context.Context, o Options, s Searcher) (string, error) {
+	if skipUndiscovered(ctx, o, s) {
+		return "", nil
+	}
+	if o.SecureFilesOnly {
+		if err := checkSecureSearcher(s); err != nil {
+			return "", fmt.Errorf("%s: %w", sourceOf(s), err)
+		}
+	}
+	return s.ProjectID(ctx, o.Scopes...)
+}
+
+const gcloudWarning = "WARNING: the project ID was resolved with the " +
+	"gcloud CLI or its configuration files, which are not expected in " +
+	"production; set GCP_PROJECT or use credentials that carry the project ID"
+
+// fromGCloud reports whether the source is the gcloud CLI or its
+// configuration files, which development machines have, but production
+// images shouldn't depend on.
+func fromGCloud(source string) bool {
+	return source == "gcloud" || strings.HasPrefix(source, "gcloud-config")
 }
 
-func defaultSearchers() []searcher {
-	return []searcher{
-		// First try: check the registered environment variables.
+// DefaultSearchers returns the default search strategies, in order, for
+// the given options, like the environment variables in EnvKeys or the
+// opt-in sources. It's the chain searched when the Searchers option is
+// not set, so it can be extended while keeping the standard one:
+//
+//	o := project.Options{EnvKeys: []string{"MY_PROJECT"}}
+//	o.Searchers = append([]project.Searcher{mine}, project.DefaultSearchers(o)...)
+//
+// It returns a new slice on each call, which the caller may modify. The
+// searchers are safe for concurrent use.
+func DefaultSearchers(o Options) []Searcher {
+	s := make([]Searcher, 0, 16)
+	s = append(s,
+		// The gcloud property override. It's authoritative for gcloud
+		// commands, so when set it's what the gcloud searcher below would
+		// return, without the cost of running the CLI.
+		newGCloudPropertySearcher(),
+
+		// Check the registered environment variables.
 		// Might work for some environments like Cloud Functions and
 		// on premises installations.
-		newEnvironmentSearcher(
-			"GCP_PROJECT",
-			"GCLOUD_PROJECT",
-			"GOOGLE_CLOUD_PROJECT",
-		),
+		envSearcher(o),
+
+		// The PROJECT_ID substitution, when running in Cloud Build.
+		newCloudBuildSearcher(),
+	)
+
+	// The environment written to a file, if opted in.
+	if o.UseSystemEnvFile {
+		keys := append(append([]string(nil), gcloudPropertyKeys...), envKeys(o)...)
+		s = append(s, newEnvFileSearcher(o.SystemEnvFile, keys...))
+	}
+
+	// The project ID baked into the binary, if opted in.
+	if o.UseBuildTimeID {
+		s = append(s, newBuildTimeSearcher())
+	}
+
+	// The central configuration service, if set.
+	if o.RemoteConfig != nil && o.RemoteConfig.Fetch != nil {
+		s = append(s, newRemoteSearcher(o.RemoteConfig))
+	}
+
+	// Opt-in configuration files, explicitly set by the caller.
+	if o.YAMLConfigFile != "" {
+		s = append(s, newYAMLSearcher(
+			o.YAMLConfigFile, o.YAMLProjectPath, o.YAMLUnmarshal,
+		))
+	}
+	if o.DeployConfigFile != "" {
+		s = append(s, newDeploySearcher(
+			o.DeployConfigFile, o.DeployProjectPath, o.YAMLUnmarshal,
+		))
+	}
+	if o.EncryptedConfigFile != "" {
+		keys := append(append([]string(nil), gcloudPropertyKeys...), envKeys(o)...)
+		s = append(s, newEncryptedSearcher(o.EncryptedConfigFile, o.Decryptor, keys...))
+	}
+	if o.CredentialName != "" {
+		s = append(s, newSystemdSearcher(o.CredentialName))
+	}
+
+	s = append(s,
+		// The GKE Workload Identity token, when mounted.
+		newK8sTokenSearcher(o.K8sTokenFile),
+	)
+
+	// The Config Connector annotations, if set.
+	if o.KCCAnnotationsFile != "" {
+		s = append(s, newKCCSearcher(o.KCCAnnotationsFile))
+	}
+
+	// The project ID cached from the metadata server, if opted in.
+	if o.UseMetadataCacheFile {
+		s = append(s, newMetadataCacheSearcher(
+			o.MetadataCacheFile, o.MetadataCacheMaxAge,
+		))
+	}
+
+	// A base64-encoded service account key in the environment, if opted in.
+	if o.CredentialsBase64Env != "" {
+		s = append(s, newCredentialsEnvSearcher(
+			o.CredentialsBase64Env, o.UniverseDomain,
+		))
+	}
+
+	// The credential helper, if set.
+	if len(o.CredentialHelper) > 0 && !o.NoSubprocess {
+		s = append(s, newCredentialHelperSearcher(o.CredentialHelper))
+	}
+
+	// A service account key in GOOGLE_APPLICATION_CREDENTIALS carries the
+	// project ID, which we can read directly, unless the credentials come
+	// from elsewhere.
+	if o.FindCredentials == nil {
+		s = append(s, newCredentialsFileSearcher(o.UniverseDomain))
+	}
 
+	s = append(s,
 		// Another possibility: Use the application default credentials.
 		// This will search a credentials file on well know locations,
 		// or issue a request to the GCE metadata server if running on
 		// Google Cloud.
-		newCredentialsSearcher(),
+		credentialsOrRace(o),
+	)
+
+	// The quota project of user credentials, unless the credentials come
+	// from elsewhere.
+	if o.FindCredentials == nil {
+		s = append(s, newCredentialsQuotaSearcher())
+	}
+
+	// The legacy gsutil configuration, if opted in.
+	if o.UseBoto {
+		s = append(s, newBotoSearcher())
+	}
+
+	if gcloudConfigFilesApply(o) {
+		s = append(s,
+			// The gcloud configuration files, read directly, if opted in.
+			// They hold what the gcloud CLI below would return, without
+			// running it.
+			newGCloudConfigSearcher(o.GCloudConfiguration),
+		)
+
+		// The only gcloud configuration with a project, when none is
+		// selected.
+		if o.GCloudConfiguration == "" {
+			s = append(s, newGCloudConfigScanSearcher())
+		}
+	}
+
+	if o.NoSubprocess {
+		return reorder(s, searchOrder(o), o)
+	}
+
+	// The configuration resolved by gcloud, if opted in.
+	if o.UseGCloudConfigHelper {
+		s = append(s, newGCloudConfigHelperSearcher(o))
+	}
 
+	s = append(s,
 		// Last resort: try to find the project id using the gcloud cli. On
 		// a local development machine this might be the only way to
 		// programmatically get a projectID, if none of the environment
@@ -118,122 +1265,855 @@ func defaultSearchers() []searcher {
 		// do not have an associated project. See:
 		//  - https://github.com/golang/oauth2/issues/241#issuecomment-447902482
 		//  - https://github.com/googleapis/google-cloud-go/issues/1294
-		newGCloudSearcher(),
+		newGCloudSearcher(o),
+	)
+	return reorder(s, searchOrder(o), o)
+}
+
+// gcloudConfigFilesApply reports whether the gcloud configuration files are
+// to be read directly: when opted in with the UseGCloudConfigFiles option,
+// and they hold what the gcloud CLI would return with the options.
+// They don't when it runs for another account than the configured one, and
+// don't tell the full configuration gcloud resolves, asked with the
+// UseGCloudConfigHelper option. Nor do they go through the GCloudFormat and
+// GCloudParse options. So they're left to the CLI then, unless it can't
+// run.
+func gcloudConfigFilesApply(o Options) bool {
+	if !o.UseGCloudConfigFiles {
+		return false
 	}
+	return o.NoSubprocess || o.GCloudAccount == "" && !o.UseGCloudConfigHelper &&
+		o.GCloudFormat == "" && o.GCloudParse == nil
 }
 
-// searcher provides a search strategy for project IDs.
-type searcher interface {
+// credentialsOrRace returns the searcher for the application default
+// credentials, raced against the metadata server when the RaceCredentials
+// option is set. The metadata server is given no longer than the OnGCP
+// probe: off Google Cloud its address may not answer at all, and the race
+// mustn't be slower than the credentials alone.
+func credentialsOrRace(o Options) Searcher {
+	s := newCredentialsSearcher(o.FindCredentials, o.UniverseDomain)
+	if !o.RaceCredentials {
+		return s
+	}
+	race := newRaceSearcher(s, newMetadataSearcher(o))
+	race.otherTimeout = onGCPTimeout
+	return race
+}
+
+// envSearcher returns the searcher for the environment variables, those of
+// the EnvChain option, if set, or else envKeys.
+func envSearcher(o Options) Searcher {
+	if len(o.EnvChain) != 0 {
+		return newEnvChainSearcher(o.EnvChain)
+	}
+	return newEnvironmentSearcher(envKeys(o)...)
+}
+
+// envKeys returns the environment variables to search, in order, for the
+// given options.
+func envKeys(o Options) []string {
+	if len(o.EnvChain) != 0 {
+		return envChainKeys(o.EnvChain)
+	}
+	if o.EnvPrefix == "" {
+		if len(o.EnvKeys) != 0 {
+			return o.EnvKeys
+		}
+		return defaultEnvKeys
+	}
+	var keys []string
+	prefix := strings.TrimSuffix(o.EnvPrefix, "_") + "_"
+	for _, key := range defaultEnvKeys {
+		keys = append(keys, prefix+key)
+	}
+	if len(o.EnvKeys) != 0 {
+		return append(keys, o.EnvKeys...)
+	}
+	return append(keys, defaultEnvKeys...)
+}
+
+// Searcher provides a search strategy for project IDs. Searchers may also
+// implement a `Source() string` method that names their source, as
+// reported in Result.Source and SearchStep.Source.
+type Searcher interface {
 	ProjectID(ctx context.Context, scopes ...string) (string, error)
 }
 
+// numberSource is implemented by searchers whose source also provides the
+// project number.
+type numberSource interface {
+	// projectNumber returns the project number, or an empty string if the
+	// source doesn't provide it.
+	projectNumber() string
+}
+
+// projectNumberOf returns the project number provided by the source of s,
+// if any, in its canonical format.
+func projectNumberOf(s Searcher) string {
+	if n, ok := s.(numberSource); ok {
+		return canonicalProjectNumber(n.projectNumber())
+	}
+	return ""
+}
+
+// sourceOf returns the name of the source searched by s.
+func sourceOf(s Searcher) string {
+	if s == nil {
+		return ""
+	}
+	if n, ok := s.(interface{ Source() string }); ok {
+		return n.Source()
+	}
+	return fmt.Sprintf("%T", s)
+}
+
 // Environment Searcher
 
 type environmentSearcher struct {
 	envLookupKeys []string
+	source        string
 }
 
-var _ searcher = (*environmentSearcher)(nil)
+var _ Searcher = (*environmentSearcher)(nil)
 
 func newEnvironmentSearcher(keys ...string) *environmentSearcher {
 	s := environmentSearcher{
 		envLookupKeys: keys,
+		source:        "env",
 	}
 	return &s
 }
 
+func (s *environmentSearcher) Source() string { return s.source }
+
 func (s *environmentSearcher) ProjectID(context.Context, ...string) (string, error) {
 	for _, key := range s.envLookupKeys {
-		if id := os.Getenv(key); id != "" {
+		if id := normalizeEnvValue(lookupEnv(key)); id != "" {
 			return id, nil
 		}
 	}
 	return "", nil
 }
 
+// lookupEnv returns the value of the environment variable key. On Windows,
+// where variable names are case-insensitive, it falls back to a
+// case-insensitive scan when the exact-case lookup misses.
+func lookupEnv(key string) string {
+	if v := getenv(key); v != "" || goos != "windows" {
+		return v
+	}
+	for _, kv := range environ() {
+		k, v, ok := strings.Cut(kv, "=")
+		// Windows has special variables like "=C:" that start with "=".
+		if ok && k != "" && strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// newGCloudPropertySearcher returns a searcher for the environment variable
+// that overrides the `core/project` gcloud property.
+func newGCloudPropertySearcher() *environmentSearcher {
+	s := newEnvironmentSearcher(gcloudPropertyKeys...)
+	s.source = "gcloud-property"
+	return s
+}
+
+var gcloudPropertyKeys = []string{gcloudProjectPropertyKey}
+
+// Cloud Build Searcher
+
+// inCloudBuild reports whether the process runs in Cloud Build, as told by
+// its BUILD_ID and PROJECT_NUMBER substitutions.
+func inCloudBuild() bool {
+	return lookupEnv("BUILD_ID") != "" || lookupEnv("PROJECT_NUMBER") != ""
+}
+
+// cloudBuildSearcher reads the PROJECT_ID substitution of Cloud Build. The
+// name is too generic to trust anywhere else, so it's only read when the
+// other Cloud Build substitutions, BUILD_ID or PROJECT_NUMBER, show that
+// the process runs in a build.
+type cloudBuildSearcher struct{}
+
+var _ Searcher = (*cloudBuildSearcher)(nil)
+
+func newCloudBuildSearcher() *cloudBuildSearcher { return &cloudBuildSearcher{} }
+
+func (*cloudBuildSearcher) Source() string { return "cloud-build" }
+
+func (*cloudBuildSearcher) projectNumber() string {
+	return normalizeEnvValue(lookupEnv("PROJECT_NUMBER"))
+}
+
+func (*cloudBuildSearcher) ProjectID(context.Context, ...string) (string, error) {
+	if !inCloudBuild() {
+		return "", nil
+	}
+	return normalizeEnvValue(lookupEnv("PROJECT_ID")), nil
+}
+
+// Credentials File Searcher
+
+// credentialsFileSearcher reads the `project_id` field of the JSON file in
+// GOOGLE_APPLICATION_CREDENTIALS, like a service account key, without the
+// full credentials machinery, which may also probe the metadata server.
+type credentialsFileSearcher struct {
+	// universeDomain, if set, is the universe the credentials must be in.
+	universeDomain string
+}
+
+var _ Searcher = (*credentialsFileSearcher)(nil)
+
+// defaultCredentialsFileSearcher is shared by the searches without a
+// UniverseDomain.
+var defaultCredentialsFileSearcher = &credentialsFileSearcher{}
+
+func newCredentialsFileSearcher(universeDomain string) *credentialsFileSearcher {
+	if universeDomain == "" {
+		return defaultCredentialsFileSearcher
+	}
+	s := credentialsFileSearcher{
+		universeDomain: universeDomain,
+	}
+	return &s
+}
+
+func (*credentialsFileSearcher) Source() string { return "credentials-file" }
+
+func (*credentialsFileSearcher) backingFile() string {
+	return getenv("GOOGLE_APPLICATION_CREDENTIALS")
+}
+
+func (s *credentialsFileSearcher) ProjectID(context.Context, ...string) (
+	string, error,
+) {
+	file := s.backingFile()
+	if file == "" {
+		return "", nil
+	}
+	b, err := readFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Leave the error to report to the credentials searcher.
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read credentials file: %w", err)
+	}
+	var f struct {
+		ProjectID      string `json:"project_id"`
+		UniverseDomain string `json:"universe_domain"`
+	}
+	if err = json.Unmarshal(b, &f); err != nil {
+		return "", fmt.Errorf("parse credentials file %s: %w", file, err)
+	}
+	id := sanitizeValue(strings.TrimSpace(f.ProjectID))
+	if id == "" {
+		return "", nil
+	}
+	err = checkUniverseDomain(s.universeDomain, f.UniverseDomain, file)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
 // Default Credentials Searcher
 
 type credentialsSearcher struct {
 	findCredentialsFn func(ctx context.Context, scopes ...string) (
 		*google.Credentials, error)
+
+	// universeDomain, if set, is the universe the credentials must be in.
+	universeDomain string
+
+	// adc tells findCredentialsFn is google.FindDefaultCredentials, which
+	// falls back to the metadata server.
+	adc bool
 }
 
-var _ searcher = (*credentialsSearcher)(nil)
+var _ Searcher = (*credentialsSearcher)(nil)
 
-func newCredentialsSearcher() *credentialsSearcher {
+func newCredentialsSearcher(
+	findCredentialsFn func(ctx context.Context, scopes ...string) (
+		*google.Credentials, error),
+	universeDomain string,
+) *credentialsSearcher {
+	adc := findCredentialsFn == nil
+	if adc {
+		findCredentialsFn = google.FindDefaultCredentials
+	}
 	s := credentialsSearcher{
-		findCredentialsFn: google.FindDefaultCredentials,
+		findCredentialsFn: findCredentialsFn,
+		universeDomain:    universeDomain,
+		adc:               adc,
 	}
 	return &s
 }
 
+func (*credentialsSearcher) Source() string { return "credentials" }
+
+func (*credentialsSearcher) backingFile() string { return credentialsFile() }
+
+// credentialsFile returns the path of the application default credentials
+// file: the one in GOOGLE_APPLICATION_CREDENTIALS or the well-known file.
+func credentialsFile() string {
+	if f := getenv("GOOGLE_APPLICATION_CREDENTIALS"); f != "" {
+		return f
+	}
+	return WellKnownCredentialsPath()
+}
+
+// credentialsFileExists reports whether google.FindDefaultCredentials
+// would find credentials in a file: GOOGLE_APPLICATION_CREDENTIALS is set,
+// even to a missing file, which it then reports, or the well-known file
+// exists.
+func credentialsFileExists() bool {
+	if getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
+		return true
+	}
+	_, err := stat(WellKnownCredentialsPath())
+	return err == nil
+}
+
+// WellKnownCredentialsPath returns the path of the application default
+// credentials file written by `gcloud auth application-default login`, as
+// searched by FindDefaultCredentials when GOOGLE_APPLICATION_CREDENTIALS is
+// not set. It's in the gcloud configuration directory: $CLOUDSDK_CONFIG if
+// set, otherwise %APPDATA%\gcloud on Windows and ~/.config/gcloud elsewhere.
+// It returns an empty string if the directory can't be determined. The file
+// may not exist.
+func WellKnownCredentialsPath() string {
+	dir := gcloudConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "application_default_credentials.json")
+}
+
+// gcloudConfigDir returns the gcloud configuration directory, or an empty
+// string if it can't be determined.
+func gcloudConfigDir() string {
+	if dir := getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return dir
+	}
+	if goos == "windows" {
+		if appData := getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "gcloud")
+		}
+		return ""
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud")
+}
+
 func (s *credentialsSearcher) ProjectID(
 	ctx context.Context, scopes ...string,
 ) (
 	string, error,
 ) {
+	if s.adc && noGCECheck() && !credentialsFileExists() {
+		// Only the metadata server is left, which mustn't be probed.
+		return "", nil
+	}
 	credentials, err := s.findCredentialsFn(ctx, scopes...)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil && !errors.Is(err, ctxErr) {
+		// The failure is likely due to the context, but the credentials
+		// machinery, like the metadata client, doesn't always wrap it.
+		return "", fmt.Errorf("find credentials: %w: %w", ctxErr, err)
+	}
 	if err != nil {
 		err = fmt.Errorf("find credentials: %w", err)
 		return "", err
 	}
+	foundCredentialsFromContext(ctx).set(credentials)
 	id := credentials.ProjectID
+	if id == "" {
+		id = impersonatedProjectID(credentials.JSON)
+	}
+	if id == "" || s.universeDomain == "" {
+		return id, nil
+	}
+	// On Google Cloud, the universe domain is asked to the metadata server.
+	ud, err := credentials.GetUniverseDomain()
+	if err != nil {
+		return "", fmt.Errorf("credentials universe domain: %w", err)
+	}
+	if err = checkUniverseDomain(s.universeDomain, ud, "credentials"); err != nil {
+		return "", err
+	}
 	return id, nil
 }
 
 // GCloud Searcher
 
+const (
+	gcloudProjectPropertyKey     = "CLOUDSDK_CORE_PROJECT"
+	impersonateServiceAccountKey = "CLOUDSDK_AUTH_IMPERSONATE_SERVICE_ACCOUNT"
+)
+
 func commonGCloudPaths() []string {
 	p, _ := exec.LookPath("gcloud")
-	home, _ := os.UserHomeDir()
+	home, _ := userHomeDir()
 	paths := []string{
 		p,
 		"gcloud",
 		path.Join(home, "google-cloud-sdk", "bin", "gcloud"),
 	}
-	return paths
+	for _, root := range sdkRoots() {
+		paths = append(paths, filepath.Join(root, "bin", "gcloud"))
+	}
+	return dedupeExecutables(paths)
+}
+
+// dedupeExecutables returns the paths without the empty ones and those
+// resolving to the same file as an earlier one, like "gcloud" found in PATH
+// and the installation it links to, so the same gcloud isn't run twice.
+func dedupeExecutables(paths []string) []string {
+	seen := map[string]bool{}
+	var deduped []string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		resolved := p
+		if lp, err := exec.LookPath(p); err == nil {
+			resolved = lp
+		}
+		if abs, err := filepath.EvalSymlinks(resolved); err == nil {
+			resolved = abs
+		}
+		if seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+		deduped = append(deduped, p)
+	}
+	return deduped
+}
+
+// sdkRoots returns the Cloud SDK installation directories that can be
+// inferred from the environment: the CLOUDSDK_ROOT_DIR variable and the
+// installation a `gsutil` found in PATH belongs to.
+func sdkRoots() []string {
+	var roots []string
+	if root := getenv("CLOUDSDK_ROOT_DIR"); root != "" {
+		roots = append(roots, root)
+	}
+	if p, _ := exec.LookPath("gsutil"); p != "" {
+		roots = append(roots, filepath.Dir(filepath.Dir(p)))
+	}
+	return roots
+}
+
+// commonGCloudEntrypoints returns commands that run the gcloud entrypoint
+// through the interpreter selected by CLOUDSDK_PYTHON, for installations
+// where the `gcloud` wrapper itself is missing or broken.
+func commonGCloudEntrypoints() [][]string {
+	python := getenv("CLOUDSDK_PYTHON")
+	if python == "" {
+		return nil
+	}
+	home, _ := userHomeDir()
+	roots := append(sdkRoots(), filepath.Join(home, "google-cloud-sdk"))
+	var entrypoints [][]string
+	for _, root := range roots {
+		script := filepath.Join(root, "lib", "gcloud.py")
+		entrypoints = append(entrypoints, []string{python, script})
+	}
+	return entrypoints
 }
 
 type gcloudSearcher struct {
 	executables []string
-	output      func(cmd *exec.Cmd) ([]byte, error)
+
+	// entrypoints are commands, with their leading arguments, that run
+	// gcloud through an interpreter. They are tried after the executables.
+	entrypoints [][]string
+
+	// discover, if set, is called on the first search to find the
+	// executables and entrypoints, until it finishes in time. It keeps the
+	// PATH lookups and filesystem access out of the construction, so they
+	// only happen when the previous searchers found nothing.
+	discover func() (executables []string, entrypoints [][]string)
+
+	// discoveryKey, if set, identifies the discovery shared by the
+	// searchers with the same discover, or else it's their own. The
+	// searches wait for it up to discoveryTimeout, if positive.
+	discoveryKey     string
+	discoveryTimeout time.Duration
+
+	// mu guards executables, entrypoints and discovered, which tells they
+	// were discovered.
+	mu         sync.Mutex
+	discovered bool
+
+	// maxConcurrent limits the gcloud subprocesses running at once in the
+	// process. Zero means unlimited.
+	maxConcurrent int
+
+	// configuration, if set, is passed to gcloud with the --configuration
+	// flag. Otherwise gcloud selects it, honoring CLOUDSDK_ACTIVE_CONFIG_NAME
+	// from the environment it inherits.
+	configuration string
+
+	// account, if set, is passed to gcloud with the --account flag.
+	account string
+
+	// strictParse selects parseGCloudOutputStrict to parse the output.
+	strictParse bool
+
+	// format, if set, is passed to `gcloud config get-value` with the
+	// --format flag, and parseFn, if set, extracts the project ID from its
+	// output instead of the default parser.
+	format  string
+	parseFn func(output []byte) (string, error)
+
+	// maxOutput is the most bytes read from the output of a gcloud run,
+	// or defaultMaxOutput when not positive.
+	maxOutput int
+
+	output func(cmd *exec.Cmd, limit int) ([]byte, error)
 }
 
-var _ searcher = (*gcloudSearcher)(nil)
+var _ Searcher = (*gcloudSearcher)(nil)
 
-func newGCloudSearcher() *gcloudSearcher {
-	executables := commonGCloudPaths()
+func newGCloudSearcher(o Options) *gcloudSearcher {
 	s := gcloudSearcher{
-		executables: executables,
-		output:      cmdOutput,
+		discover:         discoverGCloud,
+		discoveryKey:     "gcloud",
+		discoveryTimeout: o.DiscoveryTimeout,
+		maxConcurrent:    o.MaxConcurrentGCloud,
+		configuration:    o.GCloudConfiguration,
+		account:          o.GCloudAccount,
+		strictParse:      o.GCloudStrictParse,
+		format:           o.GCloudFormat,
+		parseFn:          o.GCloudParse,
+		maxOutput:        o.MaxGCloudOutput,
+		output:           cmdOutput,
 	}
 	return &s
 }
 
-func cmdOutput(cmd *exec.Cmd) ([]byte, error) { return cmd.Output() }
+func discoverGCloud() (executables []string, entrypoints [][]string) {
+	return commonGCloudPaths(), commonGCloudEntrypoints()
+}
+
+func (*gcloudSearcher) Source() string { return "gcloud" }
+
+// defaultMaxOutput is the most bytes read from the output of a command,
+// when the MaxGCloudOutput option is not set.
+const defaultMaxOutput = 64 << 10
+
+// errOutputTooLarge is returned (wrapped) when a command prints more than
+// allowed.
+var errOutputTooLarge = errors.New("output too large")
+
+// cmdOutput runs cmd and returns its standard output, like cmd.Output, but
+// reads at most limit+1 bytes, killing the command when it prints more, so
+// a runaway command can't exhaust the memory. Callers tell the overflow by
+// the length. As with cmd.Output, the standard error is returned in the
+// *exec.ExitError, if any, also bounded by limit.
+func cmdOutput(cmd *exec.Cmd, limit int) ([]byte, error) {
+	stderr := cappedBuffer{limit: limit}
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	b, readErr := io.ReadAll(io.LimitReader(stdout, int64(limit)+1))
+	if len(b) > limit {
+		// Don't wait for the rest of the output.
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return b, nil
+	}
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	if err == nil {
+		err = readErr
+	}
+	return b, err
+}
+
+// checkOutputSize returns an error wrapping errOutputTooLarge if b, the
+// output of a command read by cmdOutput, exceeds limit.
+func checkOutputSize(b []byte, limit int) error {
+	if len(b) > limit {
+		return fmt.Errorf("%w: over %d bytes", errOutputTooLarge, limit)
+	}
+	return nil
+}
+
+// cappedBuffer is a bytes.Buffer that silently discards what's written
+// past its limit.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
 
 func (s *gcloudSearcher) ProjectID(
 	ctx context.Context, _ ...string,
 ) (
 	string, error,
 ) {
-	for _, executable := range s.executables {
-		gcloud := executable
-		c := exec.CommandContext(
-			ctx,
-			gcloud,
-			"config", "get-value", "project",
-		)
-		b, err := s.output(c)
-		if err != nil {
+	args := gcloudArgs(s.configuration, s.account, s.format)
+	infoArgs := gcloudInfoArgs(s.configuration, s.account)
+	step := stepFromContext(ctx)
+	commands, ok := s.commands(ctx)
+	if !ok {
+		return "", s.undiscovered(ctx, step)
+	}
+
+	var infoRan bool
+	for _, command := range commands {
+		id, ok := s.query(ctx, step, command, args, s.parseValue)
+		if id != "" {
+			return id, nil
+		}
+		if !ok || infoRan || ctx.Err() != nil {
 			// Try the next possible gcloud executable path.
 			continue
 		}
-		if len(b) != 0 {
-			id := strings.TrimSpace(string(b))
+		// `config get-value` may print nothing even when gcloud has a
+		// project, as for some property overrides from the environment.
+		// `gcloud info` reports the project gcloud actually uses. It's
+		// only asked once: another installation is unlikely to tell more.
+		infoRan = true
+		if id, _ = s.query(ctx, step, command, infoArgs, s.parse); id != "" {
 			return id, nil
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		// The runs were interrupted, so gcloud may still have a project.
+		return "", fmt.Errorf("run gcloud: %w", err)
+	}
+	if step != nil {
+		step.Warning = gcloudFailures(step.Attempts)
+	}
 	return "", nil
 }
+
+// commands returns the commands that run gcloud, in the order to try them,
+// discovering them on the first call. It reports false when the discovery
+// didn't finish within the discoveryTimeout, or before ctx was done, in
+// which case the next call tries again.
+func (s *gcloudSearcher) commands(ctx context.Context) ([][]string, bool) {
+	executables, entrypoints, ok := s.discovery(ctx)
+	if !ok {
+		return nil, false
+	}
+	commands := make([][]string, 0, len(executables)+len(entrypoints))
+	for _, executable := range executables {
+		commands = append(commands, []string{executable})
+	}
+	return append(commands, entrypoints...), true
+}
+
+// gcloudCommands are the result of the discovery of gcloud.
+type gcloudCommands struct {
+	executables []string
+	entrypoints [][]string
+}
+
+// discovery returns the executables and entrypoints, discovered once.
+func (s *gcloudSearcher) discovery(ctx context.Context) (
+	executables []string, entrypoints [][]string, ok bool,
+) {
+	s.mu.Lock()
+	if s.discover == nil || s.discovered {
+		defer s.mu.Unlock()
+		return s.executables, s.entrypoints, true
+	}
+	s.mu.Unlock()
+
+	key := s.discoveryKey
+	if key == "" {
+		key = fmt.Sprintf("gcloud %p", s)
+	}
+	v, ok := discover(ctx, key, s.discoveryTimeout, func() any {
+		var c gcloudCommands
+		c.executables, c.entrypoints = s.discover()
+		return c
+	})
+	if !ok {
+		return nil, nil, false
+	}
+	c := v.(gcloudCommands)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.executables, s.entrypoints, s.discovered = c.executables, c.entrypoints, true
+	return c.executables, c.entrypoints, true
+}
+
+// undiscovered returns the error of a search that gave up on the
+// discovery: the error of ctx, if done, or else none, warning the step
+// that gcloud was skipped.
+func (s *gcloudSearcher) undiscovered(ctx context.Context, step *SearchStep) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("discover gcloud: %w", err)
+	}
+	if step != nil {
+		step.Warning = discoveryWarning(s.discoveryTimeout)
+	}
+	return nil
+}
+
+// gcloudFailures returns a summary of the failed gcloud attempts, when
+// gcloud is installed but none of the attempts ran. It's empty if one ran,
+// even without printing a project ID, or if gcloud is not installed.
+func gcloudFailures(attempts []Attempt) string {
+	var failed []string
+	for _, a := range attempts {
+		if a.Err == nil {
+			return ""
+		}
+		var pathErr *fs.PathError
+		if errors.Is(a.Err, exec.ErrNotFound) ||
+			errors.As(a.Err, &pathErr) && !isSymlink(pathErr.Path) {
+			// Not installed there. A broken symlink is worth reporting.
+			continue
+		}
+		failed = append(failed, a.Command+": "+a.Err.Error())
+	}
+	if len(failed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("tried %d gcloud commands, all failed: %s",
+		len(failed), strings.Join(failed, "; "))
+}
+
+func isSymlink(path string) bool {
+	info, err := lstat(path)
+	return err == nil && info.Mode()&fs.ModeSymlink != 0
+}
+
+// query runs the command with the given gcloud args and returns the project
+// ID it printed, extracted with parse, and whether it ran successfully.
+//
+// A failed run is not a hard failure, even when it exits with a Python
+// traceback because the interpreter gcloud wraps is broken. Its stdout is
+// discarded, so no partial output is mistaken for an ID, and its stderr is
+// added to the step. Output that parse rejects is a failed run as well.
+func (s *gcloudSearcher) query(
+	ctx context.Context, step *SearchStep, command, args []string,
+	parse func([]byte) (string, error),
+) (
+	id string, ok bool,
+) {
+	b, err := s.run(ctx, command, args)
+	if err == nil {
+		if id, err = parse(b); err != nil {
+			err = fmt.Errorf("parse gcloud output: %w", err)
+		}
+	}
+	step.addAttempt(command, args, err)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			step.addStderr(strings.Join(command, " "), exitErr.Stderr)
+		}
+		return "", false
+	}
+	return id, true
+}
+
+// parseValue extracts the project ID from the `config get-value` output,
+// with the parseFn when set.
+func (s *gcloudSearcher) parseValue(b []byte) (string, error) {
+	if s.parseFn == nil {
+		return s.parse(b)
+	}
+	id, err := s.parseFn(b)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeValue(strings.TrimSpace(id)), nil
+}
+
+// parse extracts the project ID from gcloud output in the value format.
+func (s *gcloudSearcher) parse(b []byte) (string, error) {
+	if s.strictParse {
+		return parseGCloudOutputStrict(b), nil
+	}
+	return parseGCloudOutput(b), nil
+}
+
+// run executes the command with the given gcloud args. If the command is an
+// executable that the OS refuses to run, as happens with shell wrapper
+// scripts without a shebang line, it's run again through `sh`. It waits for
+// a free gcloud slot first, when concurrency is limited.
+func (s *gcloudSearcher) run(
+	ctx context.Context, command, args []string,
+) (
+	[]byte, error,
+) {
+	release, err := gcloudSlots.acquire(ctx, s.maxConcurrent)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	limit := s.maxOutput
+	if limit <= 0 {
+		limit = defaultMaxOutput
+	}
+	name, prefix := command[0], command[1:]
+	c := exec.CommandContext(ctx, name, append(prefix, args...)...)
+	b, err := s.output(c, limit)
+	if errors.Is(err, syscall.ENOEXEC) && len(prefix) == 0 {
+		c = exec.CommandContext(ctx, "sh", append([]string{name}, args...)...)
+		b, err = s.output(c, limit)
+	}
+	if err == nil {
+		err = checkOutputSize(b, limit)
+	}
+	return decodeWindowsOutput(b), err
+}
+
+func gcloudArgs(configuration, account, format string) []string {
+	args := []string{"config", "get-value", "project"}
+	if format != "" {
+		args = append(args, "--format="+format)
+	}
+	return append(args, gcloudFlags(configuration, account)...)
+}
+
+func gcloudInfoArgs(configuration, account string) []string {
+	return append([]string{"info", "--format=value(config.project)"},
+		gcloudFlags(configuration, account)...)
+}
+
+// gcloudFlags returns the global gcloud flags for the searches.
+func gcloudFlags(configuration, account string) []string {
+	var args []string
+	if configuration != "" {
+		args = append(args, "--configuration="+configuration)
+	}
+	if account != "" {
+		args = append(args, "--account="+account)
+	}
+	if sa := getenv(impersonateServiceAccountKey); sa != "" {
+		args = append(args, "--impersonate-service-account="+sa)
+	}
+	return args
+}
@@ -1,9 +1,17 @@
 package project
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io/fs"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -19,6 +27,7 @@ func TestID(t *testing.T) {
 		expectedID  bool
 		expectError bool
 		expectPanic bool
+		panicErr    error
 	}{
 		{
 			name:        "Default project ID found",
@@ -47,22 +56,1285 @@ func TestID(t *testing.T) {
 			expectedID:  false,
 			expectError: false,
 			expectPanic: true,
+			panicErr:    ErrProjectIDNotFound,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			searchers = []searcher{
-				newSearcherMock(test.expectedID, test.expectError),
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{
+					newSearcherMock(test.expectedID, test.expectError),
+				}
+			})
+
+			if test.expectPanic {
+				assert.Panics(t, func() { ID(test.opts) })
+			}
+			if test.panicErr != nil {
+				v := recoverPanic(func() { ID(test.opts) })
+				err, ok := v.(error)
+				require.True(t, ok, "panic value is not an error: %v", v)
+				assert.ErrorIs(t, err, test.panicErr)
+			}
+			if test.expectedID {
+				assert.NotEmpty(t, ID(test.opts))
+			}
+		})
+	}
+}
+
+func TestIDContext(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		searcher  Searcher
+		want      string
+		wantError error
+	}{
+		{
+			name:     "Project ID found",
+			opts:     Options{Timeout: time.Second},
+			searcher: newSearcherMock(true, false),
+			want:     "gcp-project-id",
+		},
+		{
+			name:     "Empty project ID",
+			opts:     Options{Timeout: time.Second},
+			searcher: newSearcherMock(false, false),
+			want:     "",
+		},
+		{
+			name:      "Empty project ID and strict mode",
+			opts:      Options{Timeout: time.Second, Strict: true},
+			searcher:  newSearcherMock(false, false),
+			wantError: ErrProjectIDNotFound,
+		},
+		{
+			name:      "Parent context canceled",
+			opts:      Options{Timeout: time.Second},
+			searcher:  &contextSearcherMock{},
+			wantError: context.Canceled,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{test.searcher}
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			if test.wantError == context.Canceled {
+				cancel()
+			}
+			defer cancel()
+
+			got, err := IDContext(ctx, test.opts)
+
+			if test.wantError != nil {
+				require.ErrorIs(t, err, test.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestIDAsync(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		searcher Searcher
+		want     Result
+	}{
+		{
+			name:     "Project ID found",
+			opts:     Options{Timeout: time.Second},
+			searcher: newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__"),
+			want:     Result{ID: "gcp-id-test", Source: "env", Found: true},
+		},
+		{
+			name:     "Empty project ID",
+			opts:     Options{Timeout: time.Second},
+			searcher: newSearcherMock(false, false),
+			want:     Result{},
+		},
+		{
+			name:     "Empty project ID and strict mode",
+			opts:     Options{Timeout: time.Second, Strict: true},
+			searcher: newSearcherMock(false, false),
+			want:     Result{Err: ErrProjectIDNotFound},
+		},
+		{
+			name:     "Timeout",
+			opts:     Options{Timeout: time.Nanosecond},
+			searcher: &contextSearcherMock{},
+			want:     Result{Err: context.DeadlineExceeded},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("__GCP_PROJECT_ID_TEST__", "gcp-id-test")
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{test.searcher}
+			})
+
+			ch := IDAsync(test.opts)
+
+			got, ok := <-ch
+			require.True(t, ok)
+			assert.ErrorIs(t, got.Err, test.want.Err)
+			got.Err = nil
+			test.want.Err = nil
+			assert.Equal(t, test.want, got)
+
+			_, ok = <-ch
+			assert.False(t, ok, "channel not closed")
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		searchers []Searcher
+		opts      Options
+		want      Result
+		wantTrace []string
+		wantErr   error
+	}{
+		{
+			name: "Found in the first source",
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-test"),
+				newNamedSearcherMock("gcloud", "gcp-id-other"),
+			},
+			want:      Result{ID: "gcp-id-test", Source: "env", Found: true},
+			wantTrace: []string{"env=gcp-id-test"},
+		},
+		{
+			name: "Found in a later source",
+			searchers: []Searcher{
+				newNamedSearcherMock("env", ""),
+				newNamedSearcherMock("credentials", ""),
+				newNamedSearcherMock("gcloud", "gcp-id-test"),
+			},
+			want:      Result{ID: "gcp-id-test", Source: "gcloud", Found: true},
+			wantTrace: []string{"env=", "credentials=", "gcloud=gcp-id-test"},
+		},
+		{
+			name: "Not found",
+			searchers: []Searcher{
+				newNamedSearcherMock("env", ""),
+				newNamedSearcherMock("gcloud", ""),
+			},
+			want:      Result{},
+			wantTrace: []string{"env=", "gcloud="},
+		},
+		{
+			name: "Not found, strict",
+			searchers: []Searcher{
+				newNamedSearcherMock("env", ""),
+			},
+			opts:      Options{Strict: true},
+			wantTrace: []string{"env="},
+			wantErr:   ErrProjectIDNotFound,
+		},
+		{
+			name: "Error",
+			searchers: []Searcher{
+				newNamedSearcherMock("env", ""),
+				&searcherMock{wantError: true},
+				newNamedSearcherMock("gcloud", "gcp-id-test"),
+			},
+			wantTrace: []string{"env=", "*project.searcherMock= test error"},
+			wantErr:   errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher { return tt.searchers })
+			var steps int
+			tt.opts.Timeout = time.Second
+			tt.opts.OnSearch = func(SearchStep) { steps++ }
+
+			got, err := Resolve(context.Background(), tt.opts)
+
+			trace := make([]string, len(got.Trace))
+			for i, step := range got.Trace {
+				trace[i] = step.Source + "=" + step.ID
+				if step.Err != nil {
+					trace[i] += " " + step.Err.Error()
+				}
+			}
+			assert.Equal(t, tt.wantTrace, trace)
+			assert.Equal(t, len(tt.wantTrace), steps, "OnSearch not called")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.ErrorIs(t, got.Err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			got.Trace = nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve_NoTrace(t *testing.T) {
+	useSearchers(t, func(Options) []Searcher {
+		t.Error("searchers used")
+		return nil
+	})
+	ctx := WithProjectID(context.Background(), "gcp-id-test")
+
+	got, err := Resolve(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, Result{ID: "gcp-id-test", Source: "context", Found: true}, got)
+}
+
+func TestTryID(t *testing.T) {
+	tests := []struct {
+		name     string
+		searcher Searcher
+		opts     Options
+		want     string
+		wantOK   bool
+	}{
+		{
+			name:     "Found",
+			searcher: newSearcherMock(true, false),
+			want:     "gcp-project-id",
+			wantOK:   true,
+		},
+		{
+			name:     "Not found",
+			searcher: newSearcherMock(false, false),
+			want:     "",
+			wantOK:   false,
+		},
+		{
+			name:     "Not found, strict",
+			searcher: newSearcherMock(false, false),
+			opts:     Options{Strict: true},
+			want:     "",
+			wantOK:   false,
+		},
+		{
+			name:     "Error",
+			searcher: newSearcherMock(false, true),
+			want:     "",
+			wantOK:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{tt.searcher}
+			})
+			tt.opts.Timeout = time.Second
+
+			got, ok := TryID(tt.opts)
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
+
+func TestResolveWithTimeout(t *testing.T) {
+	t.Run("Default chain", func(t *testing.T) {
+		unsetEnv(t, gcloudProjectPropertyKey)
+		unsetEnv(t, defaultEnvKeys...)
+		t.Setenv("GOOGLE_CLOUD_PROJECT", "gcp-id-test")
+
+		got, err := ResolveWithTimeout(time.Second)
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		useSearchers(t, func(o Options) []Searcher {
+			assert.Equal(t, time.Millisecond, o.Timeout)
+			return []Searcher{&contextSearcherMock{}}
+		})
+
+		_, err := ResolveWithTimeout(time.Millisecond)
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestIDOrDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		searcher Searcher
+		want     string
+	}{
+		{name: "Found", searcher: newSearcherMock(true, false), want: "gcp-project-id"},
+		{name: "Not found", searcher: newSearcherMock(false, false), want: "gcp-id-default"},
+		{name: "Error", searcher: newSearcherMock(false, true), want: "gcp-id-default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{tt.searcher}
+			})
+
+			got := IDOrDefault("gcp-id-default", Options{Timeout: time.Second})
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMustResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       Searcher
+		strict  bool
+		want    string
+		wantErr error
+	}{
+		{
+			name: "Found",
+			s:    newSearcherMock(true, false),
+			want: "gcp-project-id",
+		},
+		{
+			name:    "Not found",
+			s:       newSearcherMock(false, false),
+			wantErr: ErrProjectIDNotFound,
+		},
+		{
+			name:    "Not found, strict",
+			s:       newSearcherMock(false, false),
+			strict:  true,
+			wantErr: ErrProjectIDNotFound,
+		},
+		{
+			name:    "Fallback",
+			s:       Fallback(newSearcherMock(false, false), newSearcherMock(false, false)),
+			wantErr: ErrProjectIDNotFound,
+		},
+		{
+			name:    "Search error",
+			s:       newSearcherMock(false, true),
+			wantErr: errTest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher { return []Searcher{tt.s} })
+
+			got, err := MustResolve(context.Background(), Options{
+				Timeout: time.Second,
+				Strict:  tt.strict,
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Default options", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newSearcherMock(false, false)}
+		})
+
+		assert.NotPanics(t, func() {
+			_, err := MustResolve(context.Background())
+			assert.ErrorIs(t, err, ErrProjectIDNotFound)
+		})
+	})
+}
+
+func TestResolveFirst(t *testing.T) {
+	errSearch := errors.New("search error")
+	tests := []struct {
+		name       string
+		sources    []Searcher
+		want       string
+		wantSource string
+		wantErr    error
+	}{
+		{
+			name: "First non-empty wins",
+			sources: []Searcher{
+				newNamedSearcherMock("a", ""),
+				newNamedSearcherMock("b", "gcp-id-test"),
+				newNamedSearcherMock("c", "gcp-id-other"),
+			},
+			want:       "gcp-id-test",
+			wantSource: "b",
+		},
+		{
+			name: "Nothing found",
+			sources: []Searcher{
+				newNamedSearcherMock("a", ""),
+				newNamedSearcherMock("b", ""),
+			},
+		},
+		{
+			name: "No sources",
+		},
+		{
+			name: "Placeholders are not rejected",
+			sources: []Searcher{
+				newNamedSearcherMock("a", "your-project-id"),
+			},
+			want:       "your-project-id",
+			wantSource: "a",
+		},
+		{
+			name: "Stops at the first error",
+			sources: []Searcher{
+				&namedSearcherErrMock{source: "a", err: errSearch},
+				newNamedSearcherMock("b", "gcp-id-test"),
+			},
+			wantErr: errSearch,
+		},
+		{
+			name: "Error after a match",
+			sources: []Searcher{
+				newNamedSearcherMock("a", "gcp-id-test"),
+				&namedSearcherErrMock{source: "b", err: errSearch},
+			},
+			want:       "gcp-id-test",
+			wantSource: "a",
+		},
+		{
+			name: "Fallback continues",
+			sources: []Searcher{
+				Fallback(
+					&namedSearcherErrMock{source: "a", err: errSearch},
+					newNamedSearcherMock("b", ""),
+				),
+				newNamedSearcherMock("c", "gcp-id-test"),
+			},
+			want:       "gcp-id-test",
+			wantSource: "c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, source, err := ResolveFirst(context.Background(), tt.sources...)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, id)
+				assert.Empty(t, source)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, id)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, err := ResolveFirst(ctx, newNamedSearcherMock("a", ""))
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestID_StartSpan(t *testing.T) {
+	type spanKey struct{}
+	unsetEnv(t, defaultEnvKeys...)
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{
+			newEnvironmentSearcher(defaultEnvKeys...),
+			&credentialsSearcher{
+				findCredentialsFn: func(ctx context.Context, _ ...string) (
+					*google.Credentials, error,
+				) {
+					assert.Equal(t, "credentials", ctx.Value(spanKey{}))
+					c := google.Credentials{ProjectID: "gcp-id-test"}
+					return &c, nil
+				},
+			},
+		}
+	})
+
+	var started, ended []string
+	opts := Options{
+		Timeout: time.Second,
+		StartSpan: func(ctx context.Context, name string) (
+			context.Context, func(),
+		) {
+			started = append(started, name)
+			ctx = context.WithValue(ctx, spanKey{}, name)
+			return ctx, func() { ended = append(ended, name) }
+		},
+	}
+
+	got := ID(opts)
+
+	assert.Equal(t, "gcp-id-test", got)
+	assert.Equal(t, []string{"env", "credentials"}, started)
+	assert.Equal(t, started, ended)
+}
+
+func TestID_EnvShortCircuits(t *testing.T) {
+	useSearchers(t, DefaultSearchers)
+	unsetEnv(t, gcloudProjectPropertyKey)
+	t.Setenv("GCP_PROJECT", "gcp-id-test")
+
+	opts := Options{
+		Timeout: time.Second,
+		FindCredentials: func(context.Context, ...string) (
+			*google.Credentials, error,
+		) {
+			t.Error("credentials searched")
+			return nil, errors.New("test error")
+		},
+	}
+
+	assert.Equal(t, "gcp-id-test", ID(opts))
+}
+
+func TestID_PostResolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		searcher    Searcher
+		postResolve func(t *testing.T) func(id, source string) (string, error)
+		want        Result
+		wantErr     error
+	}{
+		{
+			name:     "Transform",
+			searcher: newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__"),
+			postResolve: func(t *testing.T) func(string, string) (string, error) {
+				return func(id, source string) (string, error) {
+					assert.Equal(t, "gcp-id-test", id)
+					assert.Equal(t, "env", source)
+					return id + "-remapped", nil
+				}
+			},
+			want: Result{ID: "gcp-id-test-remapped", Source: "env", Found: true},
+		},
+		{
+			name:     "Veto",
+			searcher: newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__"),
+			postResolve: func(*testing.T) func(string, string) (string, error) {
+				return func(string, string) (string, error) {
+					return "", errTest
+				}
+			},
+			wantErr: errTest,
+		},
+		{
+			name:     "Default when not found",
+			searcher: newSearcherMock(false, false),
+			opts:     Options{Strict: true},
+			postResolve: func(t *testing.T) func(string, string) (string, error) {
+				return func(id, source string) (string, error) {
+					assert.Empty(t, id)
+					assert.Empty(t, source)
+					return "gcp-id-default", nil
+				}
+			},
+			want: Result{ID: "gcp-id-default", Found: true},
+		},
+		{
+			name:     "Validated after",
+			searcher: newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__"),
+			opts:     Options{Validate: true},
+			postResolve: func(*testing.T) func(string, string) (string, error) {
+				return func(string, string) (string, error) {
+					return "Not Valid", nil
+				}
+			},
+			wantErr: ErrInvalidProjectID,
+		},
+		{
+			name:     "Strict after",
+			searcher: newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__"),
+			opts:     Options{Strict: true},
+			postResolve: func(*testing.T) func(string, string) (string, error) {
+				return func(string, string) (string, error) {
+					return "", nil
+				}
+			},
+			wantErr: ErrProjectIDNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("__GCP_PROJECT_ID_TEST__", "gcp-id-test")
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{tt.searcher}
+			})
+			tt.opts.Timeout = time.Second
+			tt.opts.PostResolve = tt.postResolve(t)
+
+			got := <-IDAsync(tt.opts)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, got.Err, tt.wantErr)
+				return
+			}
+			require.NoError(t, got.Err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestID_OnSearch(t *testing.T) {
+	unsetEnv(t, defaultEnvKeys...)
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{
+			newEnvironmentSearcher(defaultEnvKeys...),
+			&gcloudSearcher{
+				executables: []string{"gcloud", "/opt/bin/gcloud"},
+				output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+					if cmd.Args[0] == "gcloud" {
+						stderr := "ERROR: (gcloud.config) broken\n"
+						return nil, &exec.ExitError{Stderr: []byte(stderr)}
+					}
+					return []byte("gcp-id-test"), nil
+				},
+			},
+		}
+	})
+
+	var steps []SearchStep
+	opts := Options{
+		Timeout:  time.Second,
+		OnSearch: func(step SearchStep) { steps = append(steps, step) },
+	}
+	got := ID(opts)
+
+	assert.Equal(t, "gcp-id-test", got)
+	require.Len(t, steps, 2)
+	assert.Equal(t, "env", steps[0].Source)
+	assert.Empty(t, steps[0].ID)
+	assert.Empty(t, steps[0].Stderr)
+	assert.Equal(t, "gcloud", steps[1].Source)
+	assert.Equal(t, "gcp-id-test", steps[1].ID)
+	assert.Equal(t, "gcloud: ERROR: (gcloud.config) broken", steps[1].Stderr)
+}
+
+func TestID_OnResolved(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Searcher
+		opts Options
+		ctx  context.Context
+		want []Result
+	}{
+		{
+			name: "Searched",
+			s:    newNamedSearcherMock("credentials", "gcp-id-test"),
+			want: []Result{{ID: "gcp-id-test", Source: "credentials", Found: true}},
+		},
+		{
+			name: "Explicit",
+			s:    newNamedSearcherMock("credentials", "gcp-id-other"),
+			opts: Options{Explicit: "gcp-id-test"},
+			want: []Result{{ID: "gcp-id-test", Source: "explicit", Found: true}},
+		},
+		{
+			name: "Context",
+			s:    newNamedSearcherMock("credentials", "gcp-id-other"),
+			ctx:  WithProjectID(context.Background(), "gcp-id-test"),
+			want: []Result{{ID: "gcp-id-test", Source: "context", Found: true}},
+		},
+		{
+			name: "Not found",
+			s:    newNamedSearcherMock("credentials", ""),
+			want: nil,
+		},
+		{
+			name: "Search error",
+			s:    newSearcherMock(false, true),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := time.Now()
+			replace(t, &now, func() time.Time {
+				clock = clock.Add(time.Millisecond)
+				return clock
+			})
+			useSearchers(t, func(Options) []Searcher { return []Searcher{tt.s} })
+			var got []Result
+			opts := tt.opts
+			opts.Timeout = time.Second
+			opts.OnResolved = func(r Result) {
+				assert.Positive(t, r.Duration)
+				r.Duration = 0
+				got = append(got, r)
+			}
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			r, _ := Resolve(ctx, opts)
+
+			assert.Equal(t, tt.want, got)
+			assert.Zero(t, r.Duration)
+		})
+	}
+
+	t.Run("Cached", func(t *testing.T) {
+		useCache(t)
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("credentials", "gcp-id-test")}
+		})
+		var got []string
+		opts := Options{
+			Timeout:    time.Second,
+			CacheTTL:   time.Minute,
+			OnResolved: func(r Result) { got = append(got, r.Source) },
+		}
+
+		ID(opts)
+		ID(opts)
+
+		assert.Equal(t, []string{"credentials", "credentials"}, got)
+	})
+}
+
+func TestID_OnSearch_NoStderr(t *testing.T) {
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{
+			&gcloudSearcher{
+				executables: []string{"gcloud", "/opt/bin/gcloud"},
+				output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+					if cmd.Args[0] == "gcloud" {
+						return nil, &exec.ExitError{}
+					}
+					return []byte("gcp-id-test"), nil
+				},
+			},
+		}
+	})
+
+	var steps []SearchStep
+	opts := Options{
+		Timeout:  time.Second,
+		OnSearch: func(step SearchStep) { steps = append(steps, step) },
+	}
+	ID(opts)
+
+	require.Len(t, steps, 1)
+	assert.Empty(t, steps[0].Stderr)
+}
+
+func TestID_RejectValues(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		rejectValues []string
+		want         string
+	}{
+		{
+			name:  "Default placeholder",
+			value: "your-project-id",
+			want:  "gcp-id-fallback",
+		},
+		{
+			name:  "Default placeholder, different case",
+			value: "PROJECT_ID",
+			want:  "gcp-id-fallback",
+		},
+		{
+			name:  "Not a placeholder",
+			value: "gcp-id-test",
+			want:  "gcp-id-test",
+		},
+		{
+			name:         "Custom values",
+			value:        "gcp-id-template",
+			rejectValues: []string{" GCP-ID-TEMPLATE "},
+			want:         "gcp-id-fallback",
+		},
+		{
+			name:         "Custom values replace the defaults",
+			value:        "changeme",
+			rejectValues: []string{"gcp-id-template"},
+			want:         "changeme",
+		},
+		{
+			name:         "Nothing rejected",
+			value:        "changeme",
+			rejectValues: []string{},
+			want:         "changeme",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{
+					&searcherMock{projectID: tt.value},
+					&searcherMock{projectID: "gcp-id-fallback"},
+				}
+			})
+
+			got := ID(Options{Timeout: time.Second, RejectValues: tt.rejectValues})
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestID_Explicit(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		ctx     context.Context
+		want    Result
+		wantErr error
+	}{
+		{
+			name: "Explicit value",
+			opts: Options{Explicit: "gcp-id-flag"},
+			ctx:  context.Background(),
+			want: Result{ID: "gcp-id-flag", Source: "explicit", Found: true},
+		},
+		{
+			name: "Explicit value over context",
+			opts: Options{Explicit: "gcp-id-flag"},
+			ctx:  WithProjectID(context.Background(), "gcp-id-context"),
+			want: Result{ID: "gcp-id-flag", Source: "explicit", Found: true},
+		},
+		{
+			name: "Empty explicit value",
+			opts: Options{},
+			ctx:  context.Background(),
+			want: Result{ID: "gcp-id-searched", Source: "*project.countingSearcherMock", Found: true},
+		},
+		{
+			name: "Normalized",
+			opts: Options{Explicit: "example.com:gcp-id-flag", StripDomain: true},
+			ctx:  context.Background(),
+			want: Result{ID: "gcp-id-flag", Source: "explicit", Found: true},
+		},
+		{
+			name:    "Validated",
+			opts:    Options{Explicit: "Not Valid", Validate: true},
+			ctx:     context.Background(),
+			wantErr: ErrInvalidProjectID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &countingSearcherMock{projectID: "gcp-id-searched"}
+			useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+			tt.opts.Timeout = time.Second
+
+			got := resolve(tt.ctx, tt.opts)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, got.Err, tt.wantErr)
+				return
+			}
+			require.NoError(t, got.Err)
+			assert.Equal(t, tt.want, got)
+			if tt.opts.Explicit != "" {
+				assert.Zero(t, s.calls)
+			}
+		})
+	}
+}
+
+func TestID_Aliases(t *testing.T) {
+	aliases := map[string]string{
+		"prod":    "gcp-id-prod",
+		"staging": "gcp-id-staging",
+		"old":     "staging",
+	}
+	tests := []struct {
+		name string
+		id   string
+		opts Options
+		want Result
+	}{
+		{
+			name: "Alias hit",
+			id:   "prod",
+			want: Result{ID: "gcp-id-prod", Source: "env", Found: true},
+		},
+		{
+			name: "Alias miss",
+			id:   "gcp-id-test",
+			want: Result{ID: "gcp-id-test", Source: "env", Found: true},
+		},
+		{
+			name: "Not chained",
+			id:   "old",
+			want: Result{ID: "staging", Source: "env", Found: true},
+		},
+		{
+			name: "Explicit alias",
+			opts: Options{Explicit: "staging"},
+			want: Result{ID: "gcp-id-staging", Source: "explicit", Found: true},
+		},
+		{
+			name: "Before validation",
+			id:   "prod",
+			opts: Options{Validate: true},
+			want: Result{ID: "gcp-id-prod", Source: "env", Found: true},
+		},
+		{
+			name: "Before PostResolve",
+			id:   "prod",
+			opts: Options{PostResolve: func(id, _ string) (string, error) {
+				return id + "-app", nil
+			}},
+			want: Result{ID: "gcp-id-prod-app", Source: "env", Found: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{newNamedSearcherMock("env", tt.id)}
+			})
+			tt.opts.Timeout = time.Second
+			tt.opts.Aliases = aliases
+
+			got := <-IDAsync(tt.opts)
+
+			require.NoError(t, got.Err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestID_WarnOnGCloud(t *testing.T) {
+	tests := []struct {
+		name        string
+		searcher    Searcher
+		warn        bool
+		wantWarning bool
+	}{
+		{
+			name:        "gcloud provides the ID",
+			searcher:    newNamedSearcherMock("gcloud", "gcp-id-test"),
+			warn:        true,
+			wantWarning: true,
+		},
+		{
+			name:        "Option disabled",
+			searcher:    newNamedSearcherMock("gcloud", "gcp-id-test"),
+			warn:        false,
+			wantWarning: false,
+		},
+		{
+			name:        "gcloud finds nothing",
+			searcher:    newNamedSearcherMock("gcloud", ""),
+			warn:        true,
+			wantWarning: false,
+		},
+		{
+			name:        "Other source",
+			searcher:    newNamedSearcherMock("env", "gcp-id-test"),
+			warn:        true,
+			wantWarning: false,
+		},
+		{
+			name:        "gcloud property",
+			searcher:    newNamedSearcherMock("gcloud-property", "gcp-id-test"),
+			warn:        true,
+			wantWarning: false,
+		},
+		{
+			name:        "gcloud config file",
+			searcher:    newGCloudConfigSearcher(""),
+			warn:        true,
+			wantWarning: true,
+		},
+		{
+			name:        "Inferred gcloud config",
+			searcher:    newNamedSearcherMock("gcloud-config:inferred", "gcp-id-test"),
+			warn:        true,
+			wantWarning: true,
+		},
+		{
+			name:        "gcloud config helper",
+			searcher:    newNamedSearcherMock("gcloud-config-helper", "gcp-id-test"),
+			warn:        true,
+			wantWarning: true,
+		},
+	}
+	unsetEnv(t, "CLOUDSDK_ACTIVE_CONFIG_NAME", impersonateServiceAccountKey)
+	useGCloudConfigDir(t, "[core]\nproject = gcp-id-test\n")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{tt.searcher}
+			})
+
+			var steps []SearchStep
+			ID(Options{
+				Timeout:      time.Second,
+				WarnOnGCloud: tt.warn,
+				OnSearch:     func(step SearchStep) { steps = append(steps, step) },
+			})
+
+			require.Len(t, steps, 1)
+			if tt.wantWarning {
+				assert.Contains(t, steps[0].Warning, "gcloud")
+			} else {
+				assert.Empty(t, steps[0].Warning)
+			}
+		})
+	}
+}
+
+func Test_withDeadline(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name string
+		opts Options
+		want time.Time
+	}{
+		{
+			name: "Default timeout",
+			opts: Options{},
+			want: start.Add(defaultTimeout),
+		},
+		{
+			name: "Timeout",
+			opts: Options{Timeout: time.Second},
+			want: start.Add(time.Second),
+		},
+		{
+			name: "Deadline",
+			opts: Options{Deadline: start.Add(time.Hour)},
+			want: start.Add(time.Hour),
+		},
+		{
+			name: "Deadline before the timeout",
+			opts: Options{Timeout: time.Hour, Deadline: start.Add(time.Second)},
+			want: start.Add(time.Second),
+		},
+		{
+			name: "Timeout before the deadline",
+			opts: Options{Timeout: time.Second, Deadline: start.Add(time.Hour)},
+			want: start.Add(time.Second),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := withDeadline(context.Background(), tt.opts)
+			defer cancel()
+
+			got, ok := ctx.Deadline()
+
+			require.True(t, ok)
+			assert.WithinDuration(t, tt.want, got, time.Second)
+		})
+	}
+
+	t.Run("Fake clock", func(t *testing.T) {
+		replace(t, &now, func() time.Time {
+			return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		})
+
+		ctx, cancel := withDeadline(context.Background(), Options{Timeout: time.Minute})
+		defer cancel()
+
+		require.NoError(t, ctx.Err())
+		got, _ := ctx.Deadline()
+		assert.WithinDuration(t, time.Now().Add(time.Minute), got, time.Second)
+	})
+}
+
+func TestID_Deadline(t *testing.T) {
+	t.Run("Past deadline", func(t *testing.T) {
+		s := &countingSearcherMock{projectID: "gcp-id-test"}
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+		opts := Options{Deadline: time.Now().Add(-time.Second)}
+
+		_, err := IDContext(context.Background(), opts)
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Zero(t, s.calls)
+	})
+
+	t.Run("Future deadline", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newSearcherMock(true, false)}
+		})
+		opts := Options{Deadline: time.Now().Add(time.Minute)}
+
+		got, err := IDContext(context.Background(), opts)
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-project-id", got)
+	})
+
+	t.Run("Deadline reached while searching", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{&contextSearcherMock{}}
+		})
+		opts := Options{
+			Timeout:  time.Minute,
+			Deadline: time.Now().Add(10 * time.Millisecond),
+		}
+
+		_, err := IDContext(context.Background(), opts)
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestID_TimeoutClassification(t *testing.T) {
+	// swallowing waits for the context to be done and reports no project
+	// ID, like the searchers that treat a failure as a miss.
+	swallowing := searcherFunc(func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", nil
+	})
+	credentials := newCredentialsSearcher(func(ctx context.Context, _ ...string) (
+		*google.Credentials, error,
+	) {
+		<-ctx.Done()
+		return nil, errors.New("metadata: GCE metadata server unreachable")
+	}, "")
+	gcloud := &gcloudSearcher{
+		executables: []string{"gcloud"},
+		output: func(*exec.Cmd, int) ([]byte, error) {
+			// Killed at the deadline.
+			time.Sleep(20 * time.Millisecond)
+			return nil, &exec.ExitError{}
+		},
+	}
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		opts    Options
+		s       Searcher
+	}{
+		{name: "1ns timeout", timeout: time.Nanosecond, s: swallowing},
+		{name: "Swallowed", timeout: 10 * time.Millisecond, s: swallowing},
+		{name: "Credentials", timeout: 10 * time.Millisecond, s: credentials},
+		{name: "GCloud", timeout: 10 * time.Millisecond, s: gcloud},
+		{
+			name:    "Continue on error",
+			timeout: 10 * time.Millisecond,
+			opts:    Options{ContinueOnError: true},
+			s:       credentials,
+		},
+		{
+			name:    "Consistent",
+			timeout: 10 * time.Millisecond,
+			opts:    Options{Policy: Consistent},
+			s:       swallowing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher { return []Searcher{tt.s} })
+			opts := tt.opts
+			opts.Timeout, opts.Strict = tt.timeout, true
+
+			_, err := IDContext(context.Background(), opts)
+
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.NotErrorIs(t, err, ErrProjectIDNotFound)
+		})
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := searcherFunc(func(context.Context) (string, error) {
+			cancel()
+			return "", nil
+		})
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+
+		_, err := IDContext(ctx, Options{Timeout: time.Second, Strict: true})
+
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// searcherFunc adapts a function to the Searcher interface.
+type searcherFunc func(ctx context.Context) (string, error)
+
+var _ Searcher = searcherFunc(nil)
+
+func (f searcherFunc) ProjectID(ctx context.Context, _ ...string) (string, error) {
+	return f(ctx)
+}
+
+func TestID_FindCredentials(t *testing.T) {
+	useSearchers(t, DefaultSearchers)
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+
+	var gotScopes []string
+	opts := Options{
+		Timeout: time.Second,
+		Scopes:  []string{"scope-a"},
+		FindCredentials: func(_ context.Context, scopes ...string) (
+			*google.Credentials, error,
+		) {
+			gotScopes = scopes
+			c := google.Credentials{
+				ProjectID: "injected-id",
 			}
+			return &c, nil
+		},
+	}
+
+	got := ID(opts)
+
+	assert.Equal(t, "injected-id", got)
+	assert.Equal(t, []string{"scope-a"}, gotScopes)
+}
+
+var errTest = errors.New("test error")
+
+func ptr[T any](v T) *T { return &v }
 
-			if test.expectPanic {
-				assert.Panics(t, func() { ID(test.opts) })
-			}
-			if test.expectedID {
-				assert.NotEmpty(t, ID(test.opts))
-			}
-		})
+// recoverPanic calls f and returns the value it panicked with, if any.
+func recoverPanic(f func()) (v any) {
+	defer func() { v = recover() }()
+	f()
+	return nil
+}
+
+// replace sets *p to v for the duration of the test.
+func replace[T any](t testing.TB, p *T, v T) {
+	t.Helper()
+	old := *p
+	*p = v
+	t.Cleanup(func() { *p = old })
+}
+
+// useSearchers replaces the searchers used by ID for the duration of the
+// test.
+func useSearchers(t testing.TB, fn func(Options) []Searcher) {
+	t.Helper()
+	old := searchers
+	searchers = fn
+	t.Cleanup(func() { searchers = old })
+}
+
+// unsetEnv clears the given environment variables for the duration of the
+// test.
+func unsetEnv(t testing.TB, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
 	}
 }
 
@@ -71,16 +1343,29 @@ type searcherMock struct {
 	wantError bool
 }
 
-var _ searcher = (*searcherMock)(nil)
+var _ Searcher = (*searcherMock)(nil)
 
 func (s *searcherMock) ProjectID(context.Context, ...string) (string, error) {
 	if s.wantError {
-		return "", errors.New("test error")
+		return "", errTest
 	}
 	return s.projectID, nil
 }
 
-func newSearcherMock(wantID, wantError bool) searcher {
+// contextSearcherMock waits for the context it's called with to be done and
+// returns its error.
+type contextSearcherMock struct{}
+
+var _ Searcher = (*contextSearcherMock)(nil)
+
+func (*contextSearcherMock) ProjectID(ctx context.Context, _ ...string) (
+	string, error,
+) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func newSearcherMock(wantID, wantError bool) Searcher {
 	s := searcherMock{
 		wantError: wantError,
 	}
@@ -134,6 +1419,408 @@ func Test_environmentSearcher_ProjectID(t *testing.T) {
 	}
 }
 
+func Test_envKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "Defaults",
+			opts: Options{},
+			want: defaultEnvKeys,
+		},
+		{
+			name: "Custom keys",
+			opts: Options{EnvKeys: []string{"APP_PROJECT"}},
+			want: []string{"APP_PROJECT"},
+		},
+		{
+			name: "Prefix",
+			opts: Options{EnvPrefix: "MYTOOL"},
+			want: []string{
+				"MYTOOL_GCP_PROJECT",
+				"MYTOOL_GCLOUD_PROJECT",
+				"MYTOOL_GOOGLE_CLOUD_PROJECT",
+				"GCP_PROJECT",
+				"GCLOUD_PROJECT",
+				"GOOGLE_CLOUD_PROJECT",
+			},
+		},
+		{
+			name: "Prefix with a trailing underscore",
+			opts: Options{EnvPrefix: "MYTOOL_", EnvKeys: []string{"APP_PROJECT"}},
+			want: []string{
+				"MYTOOL_GCP_PROJECT",
+				"MYTOOL_GCLOUD_PROJECT",
+				"MYTOOL_GOOGLE_CLOUD_PROJECT",
+				"APP_PROJECT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, envKeys(tt.opts))
+		})
+	}
+}
+
+func TestID_EnvPrefix(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	t.Setenv("GCP_PROJECT", "gcp-id-test")
+	t.Setenv("MYTOOL_GOOGLE_CLOUD_PROJECT", "gcp-id-mytool")
+
+	got := ID(Options{Timeout: time.Second, EnvPrefix: "MYTOOL"})
+
+	assert.Equal(t, "gcp-id-mytool", got)
+}
+
+func TestEnvID(t *testing.T) {
+	t.Run("Default keys", func(t *testing.T) {
+		unsetEnv(t, defaultEnvKeys...)
+		t.Setenv("GOOGLE_CLOUD_PROJECT", "gcp-id-test")
+
+		assert.Equal(t, "gcp-id-test", EnvID())
+	})
+
+	t.Run("Custom keys", func(t *testing.T) {
+		t.Setenv("GOOGLE_CLOUD_PROJECT", "gcp-id-test")
+		t.Setenv("__GCP_PROJECT_ID_TEST__", "gcp-id-custom")
+
+		assert.Equal(t, "gcp-id-custom", EnvID("__GCP_PROJECT_ID_TEST__"))
+	})
+
+	t.Run("Not set", func(t *testing.T) {
+		unsetEnv(t, defaultEnvKeys...)
+
+		assert.Empty(t, EnvID())
+	})
+}
+
+func TestID_GCloudPropertyOverride(t *testing.T) {
+	useSearchers(t, DefaultSearchers)
+	t.Setenv(gcloudProjectPropertyKey, "gcp-id-override")
+	t.Setenv("GCP_PROJECT", "gcp-id-test")
+
+	opts := Options{
+		Timeout: time.Second,
+		FindCredentials: func(context.Context, ...string) (
+			*google.Credentials, error,
+		) {
+			t.Error("credentials searched")
+			return nil, errors.New("test error")
+		},
+	}
+
+	assert.Equal(t, "gcp-id-override", ID(opts))
+}
+
+func Test_environmentSearcher_ProjectID_CaseInsensitive(t *testing.T) {
+	tests := []struct {
+		name string
+		goos string
+		want string
+	}{
+		{name: "Windows", goos: "windows", want: "gcp-id-test"},
+		{name: "POSIX", goos: "linux", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &goos, tt.goos)
+			replace(t, &getenv, func(string) string { return "" })
+			replace(t, &environ, func() []string {
+				return []string{"=C:=C:\\", "google_cloud_project=gcp-id-test"}
+			})
+			s := newEnvironmentSearcher("GOOGLE_CLOUD_PROJECT")
+
+			got, err := s.ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetEnvKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want map[string]string
+	}{
+		{
+			name: "Conflicting values",
+			env: map[string]string{
+				"GCP_PROJECT":          "gcp-id-test",
+				"GOOGLE_CLOUD_PROJECT": "gcp-id-other",
+				"UNRELATED":            "value",
+			},
+			want: map[string]string{
+				"GCP_PROJECT":          "gcp-id-test",
+				"GOOGLE_CLOUD_PROJECT": "gcp-id-other",
+			},
+		},
+		{
+			name: "gcloud property",
+			env:  map[string]string{gcloudProjectPropertyKey: "gcp-id-test"},
+			want: map[string]string{gcloudProjectPropertyKey: "gcp-id-test"},
+		},
+		{
+			name: "PROJECT_ID in Cloud Build",
+			env:  map[string]string{"PROJECT_ID": "gcp-id-test", "BUILD_ID": "b-123"},
+			want: map[string]string{"PROJECT_ID": "gcp-id-test"},
+		},
+		{
+			name: "PROJECT_ID outside Cloud Build",
+			env:  map[string]string{"PROJECT_ID": "gcp-id-test"},
+			want: map[string]string{},
+		},
+		{
+			name: "None set",
+			env:  nil,
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &getenv, func(key string) string { return tt.env[key] })
+
+			assert.Equal(t, tt.want, SetEnvKeys())
+		})
+	}
+}
+
+// Cloud Build Searcher
+
+func Test_cloudBuildSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "In Cloud Build, BUILD_ID",
+			env:  map[string]string{"PROJECT_ID": "gcp-id-test", "BUILD_ID": "b-123"},
+			want: "gcp-id-test",
+		},
+		{
+			name: "In Cloud Build, PROJECT_NUMBER",
+			env:  map[string]string{"PROJECT_ID": "gcp-id-test", "PROJECT_NUMBER": "123"},
+			want: "gcp-id-test",
+		},
+		{
+			name: "Outside Cloud Build",
+			env:  map[string]string{"PROJECT_ID": "gcp-id-test"},
+			want: "",
+		},
+		{
+			name: "In Cloud Build, PROJECT_ID not set",
+			env:  map[string]string{"BUILD_ID": "b-123"},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &getenv, func(key string) string { return tt.env[key] })
+			s := newCloudBuildSearcher()
+
+			got, err := s.ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDefaultSearchers(t *testing.T) {
+	t.Run("New slice on each call", func(t *testing.T) {
+		a := DefaultSearchers(Options{})
+		b := DefaultSearchers(Options{})
+		a[0] = newNamedSearcherMock("mine", "")
+
+		assert.Equal(t, "gcloud-property", sourceOf(b[0]))
+		assert.Equal(t, len(a), len(b))
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		unsetEnv(t, gcloudProjectPropertyKey)
+		t.Setenv("__GCP_PROJECT_ID_TEST__", "gcp-id-test")
+		o := Options{EnvKeys: []string{"__GCP_PROJECT_ID_TEST__"}}
+
+		got, err := DefaultSearchers(o)[1].ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	})
+
+	t.Run("GCloud account", func(t *testing.T) {
+		ss := DefaultSearchers(Options{GCloudAccount: "dev@example.com"})
+
+		s, ok := ss[len(ss)-1].(*gcloudSearcher)
+		require.True(t, ok)
+		assert.Equal(t, "dev@example.com", s.account)
+	})
+
+	t.Run("GCloud config files", func(t *testing.T) {
+		sources := func(o Options) []string {
+			var sources []string
+			for _, s := range DefaultSearchers(o) {
+				sources = append(sources, sourceOf(s))
+			}
+			return sources
+		}
+
+		assert.NotContains(t, sources(Options{}), "gcloud-config")
+		assert.NotContains(t, sources(Options{}), "gcloud-config:inferred")
+
+		got := sources(Options{UseGCloudConfigFiles: true})
+		assert.Equal(t, []string{"gcloud-config", "gcloud-config:inferred", "gcloud"},
+			got[len(got)-3:])
+	})
+
+	t.Run("No subprocess", func(t *testing.T) {
+		ss := DefaultSearchers(Options{
+			NoSubprocess:         true,
+			CredentialHelper:     []string{"helper"},
+			UseGCloudConfigFiles: true,
+		})
+
+		for _, s := range ss {
+			assert.NotContains(t, []string{"gcloud", "credential-helper"}, sourceOf(s))
+			_, isGCloud := s.(*gcloudSearcher)
+			assert.False(t, isGCloud)
+		}
+		assert.Equal(t, "gcloud-config", sourceOf(ss[len(ss)-2]))
+		assert.Equal(t, "gcloud-config:inferred", sourceOf(ss[len(ss)-1]))
+	})
+
+	t.Run("Prepend", func(t *testing.T) {
+		t.Setenv("GOOGLE_CLOUD_PROJECT", "gcp-id-env")
+		mine := newNamedSearcherMock("mine", "gcp-id-test")
+		o := Options{Timeout: time.Second}
+		o.Searchers = append([]Searcher{mine}, DefaultSearchers(o)...)
+
+		r, err := Resolve(context.Background(), o)
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", r.ID)
+		assert.Equal(t, "mine", r.Source)
+	})
+}
+
+func TestID_CloudBuild(t *testing.T) {
+	env := map[string]string{
+		"PROJECT_ID":     "gcp-id-test",
+		"PROJECT_NUMBER": "123",
+		"BUILD_ID":       "b-123",
+	}
+	replace(t, &getenv, func(key string) string { return env[key] })
+
+	r := <-IDAsync(Options{Timeout: time.Second})
+
+	require.NoError(t, r.Err)
+	assert.Equal(t, Result{
+		ID:     "gcp-id-test",
+		Number: "123",
+		Source: "cloud-build",
+		Found:  true,
+	}, r)
+}
+
+// Credentials File Searcher
+
+func Test_credentialsFileSearcher_ProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Service account key",
+			content: ptr(`{"type": "service_account", "project_id": "gcp-id-test"}`),
+			want:    "gcp-id-test",
+		},
+		{
+			name:    "Missing field",
+			content: ptr(`{"type": "authorized_user"}`),
+			want:    "",
+		},
+		{
+			name:    "Not a project ID",
+			content: ptr(`{"type": "service_account", "project_id": "gcp id test"}`),
+			want:    "",
+		},
+		{
+			name:    "Malformed JSON",
+			content: ptr(`{"type": "service_account",`),
+			wantErr: true,
+		},
+		{
+			name:    "Missing file",
+			content: nil,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "sa.json")
+			if tt.content != nil {
+				err := os.WriteFile(file, []byte(*tt.content), 0o600)
+				require.NoError(t, err)
+			}
+			t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+			s := newCredentialsFileSearcher("")
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve_CredentialsFile_FindCredentials(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sa.json")
+	content := `{"type": "service_account", "project_id": "gcp-id-file"}`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+	o := Options{
+		Timeout: time.Second,
+		FindCredentials: func(context.Context, ...string) (*google.Credentials, error) {
+			return &google.Credentials{ProjectID: "gcp-id-test"}, nil
+		},
+	}
+	for _, s := range DefaultSearchers(o) {
+		if strings.HasPrefix(sourceOf(s), "credentials") {
+			o.Searchers = append(o.Searchers, s)
+		}
+	}
+
+	r, err := Resolve(context.Background(), o)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", r.ID)
+	assert.Equal(t, "credentials", r.Source)
+}
+
+func Test_credentialsFileSearcher_ProjectID_NotSet(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	replace(t, &readFile, func(string) ([]byte, error) {
+		t.Error("file read")
+		return nil, nil
+	})
+	s := newCredentialsFileSearcher("")
+
+	got, err := s.ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
 // Default Credentials Searcher
 
 func Test_credentialsSearcher_ProjectID(t *testing.T) {
@@ -196,32 +1883,159 @@ func Test_credentialsSearcher_ProjectID_Error(t *testing.T) {
 		args   args
 	}{
 		{
-			name: "google.FindDefaultCredentials fails",
-			fields: fields{
-				findCredentialsFn: func(context.Context, ...string) (
-					*google.Credentials, error,
-				) {
-					return nil, errors.New("test error")
-				},
+			name: "google.FindDefaultCredentials fails",
+			fields: fields{
+				findCredentialsFn: func(context.Context, ...string) (
+					*google.Credentials, error,
+				) {
+					return nil, errors.New("test error")
+				},
+			},
+			args: args{ctx: context.Background(), scopes: nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &credentialsSearcher{
+				findCredentialsFn: tt.fields.findCredentialsFn,
+			}
+
+			_, err := s.ProjectID(tt.args.ctx, tt.args.scopes...)
+
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestWellKnownCredentialsPath(t *testing.T) {
+	const file = "application_default_credentials.json"
+	tests := []struct {
+		name    string
+		goos    string
+		env     map[string]string
+		home    string
+		homeErr error
+		want    string
+	}{
+		{
+			name: "CLOUDSDK_CONFIG",
+			goos: "linux",
+			env:  map[string]string{"CLOUDSDK_CONFIG": "/gcloud"},
+			home: "/home/user",
+			want: filepath.Join("/gcloud", file),
+		},
+		{
+			name: "CLOUDSDK_CONFIG on Windows",
+			goos: "windows",
+			env: map[string]string{
+				"CLOUDSDK_CONFIG": `D:\gcloud`,
+				"APPDATA":         `C:\Users\user\AppData\Roaming`,
+			},
+			want: filepath.Join(`D:\gcloud`, file),
+		},
+		{
+			name: "Linux",
+			goos: "linux",
+			home: "/home/user",
+			want: filepath.Join("/home/user", ".config", "gcloud", file),
+		},
+		{
+			name: "macOS",
+			goos: "darwin",
+			home: "/Users/user",
+			want: filepath.Join("/Users/user", ".config", "gcloud", file),
+		},
+		{
+			name: "Windows",
+			goos: "windows",
+			env:  map[string]string{"APPDATA": `C:\Users\user\AppData\Roaming`},
+			home: `C:\Users\user`,
+			want: filepath.Join(`C:\Users\user\AppData\Roaming`, "gcloud", file),
+		},
+		{
+			name: "Windows without APPDATA",
+			goos: "windows",
+			home: `C:\Users\user`,
+			want: "",
+		},
+		{
+			name:    "No home directory",
+			goos:    "linux",
+			homeErr: errTest,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &goos, tt.goos)
+			replace(t, &getenv, func(key string) string { return tt.env[key] })
+			replace(t, &userHomeDir, func() (string, error) {
+				return tt.home, tt.homeErr
+			})
+
+			assert.Equal(t, tt.want, WellKnownCredentialsPath())
+		})
+	}
+}
+
+// GCloud Searcher
+
+func Test_gcloudFailures(t *testing.T) {
+	dir := t.TempDir()
+	link := filepath.Join(dir, "gcloud")
+	if err := os.Symlink(filepath.Join(dir, "missing"), link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	missing := filepath.Join(dir, "not-installed")
+	notExist := func(path string) error {
+		return &fs.PathError{Op: "fork/exec", Path: path, Err: fs.ErrNotExist}
+	}
+	tests := []struct {
+		name     string
+		attempts []Attempt
+		want     string
+	}{
+		{
+			name: "All failed",
+			attempts: []Attempt{
+				{Command: "gcloud config get-value project", Err: errTest},
+				{Command: link + " config get-value project", Err: notExist(link)},
+				{Command: missing + " config get-value project", Err: notExist(missing)},
+			},
+			want: "tried 2 gcloud commands, all failed: " +
+				"gcloud config get-value project: test error; " +
+				link + " config get-value project: fork/exec " + link +
+				": file does not exist",
+		},
+		{
+			name: "One ran",
+			attempts: []Attempt{
+				{Command: "gcloud config get-value project", Err: errTest},
+				{Command: "/opt/bin/gcloud config get-value project"},
+			},
+			want: "",
+		},
+		{
+			name: "Not installed",
+			attempts: []Attempt{
+				{Command: "gcloud config get-value project", Err: exec.ErrNotFound},
+				{Command: missing + " config get-value project", Err: notExist(missing)},
 			},
-			args: args{ctx: context.Background(), scopes: nil},
+			want: "",
+		},
+		{
+			name:     "No attempts",
+			attempts: nil,
+			want:     "",
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := &credentialsSearcher{
-				findCredentialsFn: tt.fields.findCredentialsFn,
-			}
-
-			_, err := s.ProjectID(tt.args.ctx, tt.args.scopes...)
-
-			require.Error(t, err)
+			assert.Equal(t, tt.want, gcloudFailures(tt.attempts))
 		})
 	}
 }
 
-// GCloud Searcher
-
 func checkGCloud(t *testing.T) (executable string, ok bool) {
 	executable, _ = exec.LookPath("gcloud")
 	if executable == "" {
@@ -254,7 +2068,7 @@ func Test_gcloudSearcher_ProjectID(t *testing.T) {
 			output:      cmdOutput,
 		}
 		if !useGCloud {
-			s.output = func(cmd *exec.Cmd) ([]byte, error) {
+			s.output = func(cmd *exec.Cmd, _ int) ([]byte, error) {
 				return []byte("gcp-id-test"), nil
 			}
 		}
@@ -271,7 +2085,7 @@ func Test_gcloudSearcher_ProjectID(t *testing.T) {
 			output:      cmdOutput,
 		}
 		if !useGCloud {
-			s.output = func(cmd *exec.Cmd) ([]byte, error) {
+			s.output = func(cmd *exec.Cmd, _ int) ([]byte, error) {
 				return []byte("gcp-id-test"), nil
 			}
 		}
@@ -282,6 +2096,533 @@ func Test_gcloudSearcher_ProjectID(t *testing.T) {
 		assert.NotEmpty(t, got)
 	})
 
+	t.Run("Impersonate service account", func(t *testing.T) {
+		sa := "robot@gcp-id-test.iam.gserviceaccount.com"
+		t.Setenv(impersonateServiceAccountKey, sa)
+
+		var gotArgs []string
+		s := &gcloudSearcher{
+			executables: []string{"gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = cmd.Args[1:]
+				return []byte("gcp-id-test"), nil
+			},
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.Equal(t, []string{
+			"config", "get-value", "project",
+			"--impersonate-service-account=" + sa,
+		}, gotArgs)
+	})
+
+	t.Run("No impersonation", func(t *testing.T) {
+		t.Setenv(impersonateServiceAccountKey, "")
+
+		var gotArgs []string
+		s := &gcloudSearcher{
+			executables: []string{"gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = cmd.Args[1:]
+				return []byte("gcp-id-test"), nil
+			},
+		}
+
+		_, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"config", "get-value", "project"}, gotArgs)
+	})
+
+	t.Run("Broken Python", func(t *testing.T) {
+		const traceback = `Traceback (most recent call last):
+  File "/usr/lib/google-cloud-sdk/lib/gcloud.py", line 104, in <module>
+    main()
+ModuleNotFoundError: No module named 'encodings'
+`
+		var calls []string
+		s := &gcloudSearcher{
+			executables: []string{"/usr/bin/gcloud", "/opt/bin/gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				calls = append(calls, cmd.Path)
+				if cmd.Path == "/usr/bin/gcloud" {
+					// Output with a value-like last line, to check that the
+					// stdout of a failed run is not used.
+					return []byte("gcp-id-partial\n"),
+						&exec.ExitError{Stderr: []byte(traceback)}
+				}
+				return []byte("gcp-id-test\n"), nil
+			},
+		}
+		var step SearchStep
+		ctx := withStep(context.Background(), &step)
+
+		got, err := s.ProjectID(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.Equal(t, []string{"/usr/bin/gcloud", "/opt/bin/gcloud"}, calls)
+		assert.Contains(t, step.Stderr, "ModuleNotFoundError")
+	})
+
+	t.Run("Broken Python everywhere", func(t *testing.T) {
+		s := &gcloudSearcher{
+			executables: []string{"/usr/bin/gcloud", "/opt/bin/gcloud"},
+			output: func(*exec.Cmd, int) ([]byte, error) {
+				return []byte("Traceback (most recent call last):\n"),
+					&exec.ExitError{Stderr: []byte("ImportError: bad magic number\n")}
+			},
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Attempts", func(t *testing.T) {
+		s := &gcloudSearcher{
+			executables: []string{"/usr/bin/gcloud", "/opt/bin/gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				if cmd.Path == "/usr/bin/gcloud" {
+					return nil, &exec.ExitError{}
+				}
+				return []byte(""), nil
+			},
+		}
+		var step SearchStep
+		ctx := withStep(context.Background(), &step)
+
+		got, err := s.ProjectID(ctx)
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		assert.Empty(t, step.Warning, "working but empty gcloud is masked")
+		require.Len(t, step.Attempts, 3)
+		assert.Equal(t, "/usr/bin/gcloud config get-value project", step.Attempts[0].Command)
+		assert.Error(t, step.Attempts[0].Err)
+		assert.Equal(t, "/opt/bin/gcloud config get-value project", step.Attempts[1].Command)
+		assert.NoError(t, step.Attempts[1].Err)
+		assert.Equal(t, "/opt/bin/gcloud info --format=value(config.project)", step.Attempts[2].Command)
+		assert.NoError(t, step.Attempts[2].Err)
+	})
+
+	t.Run("Strict parse", func(t *testing.T) {
+		outputs := map[string]string{
+			"/usr/bin/gcloud": "Updates are available.\ngcp-id-notice\n",
+			"/opt/bin/gcloud": "gcp-id-test\n",
+		}
+		tests := []struct {
+			name        string
+			strictParse bool
+			want        string
+		}{
+			{name: "Lenient", strictParse: false, want: "gcp-id-notice"},
+			{name: "Strict", strictParse: true, want: "gcp-id-test"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := &gcloudSearcher{
+					executables: []string{"/usr/bin/gcloud", "/opt/bin/gcloud"},
+					strictParse: tt.strictParse,
+					output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+						return []byte(outputs[cmd.Path]), nil
+					},
+				}
+
+				got, err := s.ProjectID(context.Background())
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			})
+		}
+	})
+
+	t.Run("Configuration", func(t *testing.T) {
+		t.Setenv(impersonateServiceAccountKey, "")
+
+		var gotArgs []string
+		s := &gcloudSearcher{
+			executables:   []string{"gcloud"},
+			configuration: "staging",
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = cmd.Args[1:]
+				return []byte("gcp-id-test"), nil
+			},
+		}
+
+		_, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"config", "get-value", "project", "--configuration=staging",
+		}, gotArgs)
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		t.Setenv(impersonateServiceAccountKey, "")
+		parseJSON := func(b []byte) (string, error) {
+			var id string
+			err := json.Unmarshal(b, &id)
+			return id, err
+		}
+		tests := []struct {
+			name     string
+			opts     Options
+			output   string
+			want     string
+			wantArgs []string
+		}{
+			{
+				name:     "Plain",
+				opts:     Options{},
+				output:   "gcp-id-test\n",
+				want:     "gcp-id-test",
+				wantArgs: []string{"config", "get-value", "project"},
+			},
+			{
+				name:   "JSON",
+				opts:   Options{GCloudFormat: "json", GCloudParse: parseJSON},
+				output: "\"gcp-id-test\"\n",
+				want:   "gcp-id-test",
+				wantArgs: []string{
+					"config", "get-value", "project", "--format=json",
+				},
+			},
+			{
+				name:   "JSON not parsed",
+				opts:   Options{GCloudFormat: "json", GCloudParse: parseJSON},
+				output: "not json",
+				want:   "",
+				wantArgs: []string{
+					"config", "get-value", "project", "--format=json",
+				},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var gotArgs [][]string
+				s := newGCloudSearcher(tt.opts)
+				s.discover = func() ([]string, [][]string) {
+					return []string{"gcloud"}, nil
+				}
+				s.output = func(cmd *exec.Cmd, _ int) ([]byte, error) {
+					gotArgs = append(gotArgs, cmd.Args[1:])
+					return []byte(tt.output), nil
+				}
+				var step SearchStep
+
+				got, err := s.ProjectID(withStep(context.Background(), &step))
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.wantArgs, gotArgs[0])
+				if tt.want == "" {
+					require.NotEmpty(t, step.Attempts)
+					assert.ErrorContains(t, step.Attempts[0].Err, "parse gcloud output")
+				}
+			})
+		}
+	})
+
+	t.Run("Account", func(t *testing.T) {
+		t.Setenv(impersonateServiceAccountKey, "")
+		tests := []struct {
+			name    string
+			account string
+			want    []string
+		}{
+			{
+				name:    "Set",
+				account: "dev@example.com",
+				want: []string{
+					"config", "get-value", "project", "--account=dev@example.com",
+				},
+			},
+			{
+				name:    "Default",
+				account: "",
+				want:    []string{"config", "get-value", "project"},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var gotArgs []string
+				s := newGCloudSearcher(Options{GCloudAccount: tt.account})
+				s.discover = func() ([]string, [][]string) {
+					return []string{"gcloud"}, nil
+				}
+				s.output = func(cmd *exec.Cmd, _ int) ([]byte, error) {
+					gotArgs = cmd.Args[1:]
+					return []byte("gcp-id-test"), nil
+				}
+
+				_, err := s.ProjectID(context.Background())
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, gotArgs)
+			})
+		}
+	})
+
+	t.Run("Shell wrapper without a shebang", func(t *testing.T) {
+		var gotArgs [][]string
+		s := &gcloudSearcher{
+			executables: []string{"/opt/bin/gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = append(gotArgs, cmd.Args)
+				if cmd.Args[0] == "/opt/bin/gcloud" {
+					err := &os.PathError{
+						Op: "fork/exec", Path: cmd.Path, Err: syscall.ENOEXEC,
+					}
+					return nil, err
+				}
+				return []byte("gcp-id-test\n"), nil
+			},
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.Equal(t, [][]string{
+			{"/opt/bin/gcloud", "config", "get-value", "project"},
+			{"sh", "/opt/bin/gcloud", "config", "get-value", "project"},
+		}, gotArgs)
+	})
+
+	t.Run("Shell wrapper runs through sh", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("shell scripts are not supported on windows")
+		}
+		wrapper := filepath.Join(t.TempDir(), "gcloud")
+		err := os.WriteFile(wrapper, []byte("echo gcp-id-test\n"), 0o700)
+		require.NoError(t, err)
+		s := &gcloudSearcher{
+			executables: []string{wrapper},
+			output:      cmdOutput,
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	})
+
+	t.Run("Output too large", func(t *testing.T) {
+		var gotLimits []int
+		s := &gcloudSearcher{
+			executables: []string{"gcloud", "/opt/bin/gcloud"},
+			maxOutput:   1024,
+			output: func(cmd *exec.Cmd, limit int) ([]byte, error) {
+				gotLimits = append(gotLimits, limit)
+				if cmd.Args[0] == "gcloud" {
+					return bytes.Repeat([]byte("gcp-id-test\n"), 1<<20), nil
+				}
+				return []byte("gcp-id-test"), nil
+			},
+		}
+		var step SearchStep
+
+		got, err := s.ProjectID(withStep(context.Background(), &step))
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.Equal(t, []int{1024, 1024}, gotLimits)
+		require.Len(t, step.Attempts, 2)
+		assert.ErrorIs(t, step.Attempts[0].Err, errOutputTooLarge)
+	})
+
+	t.Run("Default output limit", func(t *testing.T) {
+		var gotLimit int
+		s := &gcloudSearcher{
+			executables: []string{"gcloud"},
+			output: func(_ *exec.Cmd, limit int) ([]byte, error) {
+				gotLimit = limit
+				return []byte("gcp-id-test"), nil
+			},
+		}
+
+		_, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, defaultMaxOutput, gotLimit)
+	})
+
+	t.Run("Endless output is stopped", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("shell scripts are not supported on windows")
+		}
+		wrapper := filepath.Join(t.TempDir(), "gcloud")
+		script := "while :; do echo gcp-id-test; done\n"
+		require.NoError(t, os.WriteFile(wrapper, []byte(script), 0o700))
+		s := &gcloudSearcher{
+			executables: []string{wrapper},
+			maxOutput:   1024,
+			output:      cmdOutput,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		got, err := s.ProjectID(ctx)
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		require.NoError(t, ctx.Err(), "the command was not stopped")
+	})
+
+	t.Run("Stderr of a failed run", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("shell scripts are not supported on windows")
+		}
+		wrapper := filepath.Join(t.TempDir(), "gcloud")
+		script := "echo 'not logged in' >&2\nexit 1\n"
+		require.NoError(t, os.WriteFile(wrapper, []byte(script), 0o700))
+		s := &gcloudSearcher{
+			executables: []string{wrapper},
+			output:      cmdOutput,
+		}
+		var step SearchStep
+
+		got, err := s.ProjectID(withStep(context.Background(), &step))
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		assert.Contains(t, step.Stderr, "not logged in")
+	})
+
+	t.Run("Interpreter entrypoint", func(t *testing.T) {
+		var gotArgs [][]string
+		s := &gcloudSearcher{
+			executables: []string{"_"},
+			entrypoints: [][]string{{"python3", "/sdk/lib/gcloud.py"}},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = append(gotArgs, cmd.Args)
+				if cmd.Args[0] == "_" {
+					return nil, exec.ErrNotFound
+				}
+				return []byte("gcp-id-test"), nil
+			},
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.Equal(t, []string{
+			"python3", "/sdk/lib/gcloud.py", "config", "get-value", "project",
+		}, gotArgs[1])
+	})
+
+	t.Run("Project from gcloud info", func(t *testing.T) {
+		var gotArgs [][]string
+		s := &gcloudSearcher{
+			executables: []string{"gcloud", "/opt/bin/gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = append(gotArgs, cmd.Args)
+				if cmd.Args[1] == "info" {
+					return []byte("gcp-id-test\n"), nil
+				}
+				return []byte("(unset)\n"), nil
+			},
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.Equal(t, [][]string{
+			{"gcloud", "config", "get-value", "project"},
+			{"gcloud", "info", "--format=value(config.project)"},
+		}, gotArgs)
+	})
+
+	t.Run("gcloud info run once", func(t *testing.T) {
+		var gotArgs [][]string
+		s := &gcloudSearcher{
+			executables: []string{"gcloud", "/opt/bin/gcloud", "/usr/bin/gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = append(gotArgs, cmd.Args)
+				return []byte("(unset)\n"), nil
+			},
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		assert.Equal(t, [][]string{
+			{"gcloud", "config", "get-value", "project"},
+			{"gcloud", "info", "--format=value(config.project)"},
+			{"/opt/bin/gcloud", "config", "get-value", "project"},
+			{"/usr/bin/gcloud", "config", "get-value", "project"},
+		}, gotArgs)
+	})
+
+	t.Run("gcloud info not run after a failure", func(t *testing.T) {
+		var calls int
+		s := &gcloudSearcher{
+			executables: []string{"gcloud"},
+			output: func(*exec.Cmd, int) ([]byte, error) {
+				calls++
+				return nil, &exec.ExitError{}
+			},
+		}
+
+		got, err := s.ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("gcloud info not run after the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var gotArgs [][]string
+		s := &gcloudSearcher{
+			executables: []string{"gcloud"},
+			output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+				gotArgs = append(gotArgs, cmd.Args[1:])
+				cancel()
+				return []byte(""), nil
+			},
+		}
+
+		got, err := s.ProjectID(ctx)
+
+		// Interrupted, so it's reported as such, not as no project.
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, got)
+		assert.Equal(t, [][]string{{"config", "get-value", "project"}}, gotArgs)
+	})
+
+	t.Run("Unset project", func(t *testing.T) {
+		for _, output := range []string{"(unset)", "(UNSET)\n", " (unset) \n"} {
+			var calls int
+			s := &gcloudSearcher{
+				executables: []string{"gcloud", "/opt/bin/gcloud"},
+				output: func(cmd *exec.Cmd, _ int) ([]byte, error) {
+					calls++
+					if cmd.Args[0] == "gcloud" {
+						return []byte(output), nil
+					}
+					return []byte("gcp-id-test"), nil
+				},
+			}
+
+			got, err := s.ProjectID(context.Background())
+
+			require.NoError(t, err)
+			assert.Equal(t, "gcp-id-test", got, "output %q", output)
+			// The config and info queries of "gcloud", then the config
+			// query of "/opt/bin/gcloud".
+			assert.Equal(t, 3, calls)
+		}
+	})
+
 	t.Run("gcloud command not found", func(t *testing.T) {
 		s := &gcloudSearcher{
 			executables: []string{"_"},
@@ -295,6 +2636,89 @@ func Test_gcloudSearcher_ProjectID(t *testing.T) {
 	})
 }
 
+func Test_newGCloudSearcher_Lazy(t *testing.T) {
+	var discovered int
+	s := newGCloudSearcher(Options{})
+	assert.Nil(t, s.executables)
+	assert.Nil(t, s.entrypoints)
+
+	s.discover = func() ([]string, [][]string) {
+		discovered++
+		return []string{"gcloud"}, nil
+	}
+	s.output = func(*exec.Cmd, int) ([]byte, error) {
+		return []byte("gcp-id-test"), nil
+	}
+	for i := 0; i < 2; i++ {
+		got, err := s.ProjectID(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	}
+
+	assert.Equal(t, 1, discovered)
+}
+
+func Test_commonGCloudEntrypoints(t *testing.T) {
+	t.Run("CLOUDSDK_PYTHON not set", func(t *testing.T) {
+		t.Setenv("CLOUDSDK_PYTHON", "")
+
+		assert.Empty(t, commonGCloudEntrypoints())
+	})
+
+	t.Run("CLOUDSDK_PYTHON set", func(t *testing.T) {
+		root := filepath.Join(t.TempDir(), "sdk")
+		t.Setenv("CLOUDSDK_PYTHON", "python3")
+		t.Setenv("CLOUDSDK_ROOT_DIR", root)
+
+		got := commonGCloudEntrypoints()
+
+		require.NotEmpty(t, got)
+		assert.Equal(t, []string{
+			"python3", filepath.Join(root, "lib", "gcloud.py"),
+		}, got[0])
+	})
+
+	t.Run("Home installation", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("CLOUDSDK_PYTHON", "python3")
+		t.Setenv("CLOUDSDK_ROOT_DIR", "")
+		replace(t, &userHomeDir, func() (string, error) { return home, nil })
+
+		got := commonGCloudEntrypoints()
+
+		assert.Contains(t, got, []string{
+			"python3", filepath.Join(home, "google-cloud-sdk", "lib", "gcloud.py"),
+		})
+	})
+}
+
+func Test_commonGCloudPaths_SDKRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "sdk")
+	t.Setenv("CLOUDSDK_ROOT_DIR", root)
+
+	got := commonGCloudPaths()
+
+	assert.Contains(t, got, filepath.Join(root, "bin", "gcloud"))
+}
+
+func Test_dedupeExecutables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executables are told by their extension on Windows")
+	}
+	dir := t.TempDir()
+	gcloud := filepath.Join(dir, "gcloud")
+	require.NoError(t, os.WriteFile(gcloud, []byte("#!/bin/sh\n"), 0o700))
+	link := filepath.Join(t.TempDir(), "gcloud")
+	require.NoError(t, os.Symlink(gcloud, link))
+	t.Setenv("PATH", dir)
+
+	got := dedupeExecutables([]string{
+		"", "gcloud", gcloud, link, "/missing/gcloud", "/missing/gcloud",
+	})
+
+	assert.Equal(t, []string{"gcloud", "/missing/gcloud"}, got)
+}
+
 // Other
 
 func TestGetOptions(t *testing.T) {
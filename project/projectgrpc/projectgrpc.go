@@ -0,0 +1,70 @@
+// Package projectgrpc provides gRPC server interceptors that take the
+// Google Cloud project ID from the request metadata and make it available
+// to handlers through the request context, for multi-tenant services.
+package projectgrpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/lucmq/gcp-project-id/project"
+)
+
+// MetadataKey is the request metadata key carrying the project ID.
+const MetadataKey = "x-goog-project-id"
+
+// FromIncomingContext returns the project ID in the MetadataKey of the
+// incoming request metadata of ctx, trimmed, and whether there's one. The
+// first non-empty value is used when the key is repeated. The value is not
+// validated.
+func FromIncomingContext(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, v := range md.Get(MetadataKey) {
+		if v = strings.TrimSpace(v); v != "" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// UnaryServerInterceptor returns an interceptor that serves the requests
+// carrying a project ID in their metadata, as found by FromIncomingContext,
+// with it in their context. Handlers get it with
+// project.ProjectIDFromContext, and project.IDContext returns it without
+// searching. A resource name, like "projects/my-project", is reduced to the
+// project ID.
+//
+// Requests with a malformed project ID, as told by
+// project.ValidateProjectID, fail with the InvalidArgument code, without
+// echoing the value. Legacy domain-scoped project IDs, like
+// "example.com:my-project", are accepted as is when their project part is
+// well-formed. Requests without one are served as is.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		id, ok := FromIncomingContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+		id = project.StripResourcePrefix(id)
+		err := project.ValidateProjectID(id)
+		if err != nil && !errors.Is(err, project.ErrDomainScopedID) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s: %v",
+				MetadataKey, project.ErrInvalidProjectID)
+		}
+		return handler(project.WithProjectID(ctx, id), req)
+	}
+}
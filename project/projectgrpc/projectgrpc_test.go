@@ -0,0 +1,157 @@
+package projectgrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/lucmq/gcp-project-id/project"
+)
+
+func TestFromIncomingContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		wantID string
+		wantOK bool
+	}{
+		{
+			name:   "Present",
+			ctx:    incoming(MetadataKey, "gcp-id-test"),
+			wantID: "gcp-id-test",
+			wantOK: true,
+		},
+		{
+			name:   "Trimmed",
+			ctx:    incoming(MetadataKey, "  gcp-id-test "),
+			wantID: "gcp-id-test",
+			wantOK: true,
+		},
+		{
+			name:   "Case-insensitive key",
+			ctx:    incoming("X-Goog-Project-Id", "gcp-id-test"),
+			wantID: "gcp-id-test",
+			wantOK: true,
+		},
+		{
+			name:   "First non-empty value",
+			ctx:    incoming(MetadataKey, "", MetadataKey, "gcp-id-test", MetadataKey, "gcp-id-other"),
+			wantID: "gcp-id-test",
+			wantOK: true,
+		},
+		{
+			name:   "Empty",
+			ctx:    incoming(MetadataKey, " "),
+			wantOK: false,
+		},
+		{
+			name:   "Other keys",
+			ctx:    incoming("x-goog-user-project", "gcp-id-test"),
+			wantOK: false,
+		},
+		{
+			name:   "No metadata",
+			ctx:    context.Background(),
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := FromIncomingContext(tt.ctx)
+
+			assert.Equal(t, tt.wantID, id)
+			assert.Equal(t, tt.wantOK, ok)
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantCode codes.Code
+		wantID   string
+		wantOK   bool
+	}{
+		{
+			name:   "Present",
+			ctx:    incoming(MetadataKey, "gcp-id-test"),
+			wantID: "gcp-id-test",
+			wantOK: true,
+		},
+		{
+			name:   "Resource name",
+			ctx:    incoming(MetadataKey, "projects/gcp-id-test"),
+			wantID: "gcp-id-test",
+			wantOK: true,
+		},
+		{
+			name:   "Domain-scoped",
+			ctx:    incoming(MetadataKey, "example.com:gcp-id-test"),
+			wantID: "example.com:gcp-id-test",
+			wantOK: true,
+		},
+		{
+			name:     "Invalid",
+			ctx:      incoming(MetadataKey, "Not A Project"),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Invalid domain-scoped",
+			ctx:      incoming(MetadataKey, "example.com:Not A Project"),
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "Absent",
+			ctx:    incoming("other", "value"),
+			wantOK: false,
+		},
+		{
+			name:   "No metadata",
+			ctx:    context.Background(),
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				served bool
+				gotID  string
+				gotOK  bool
+			)
+			handler := func(ctx context.Context, req any) (any, error) {
+				served = true
+				gotID, gotOK = project.ProjectIDFromContext(ctx)
+				return "response", nil
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+			resp, err := UnaryServerInterceptor()(tt.ctx, "request", info, handler)
+
+			if tt.wantCode != codes.OK {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				assert.NotContains(t, err.Error(), "Not A Project")
+				assert.False(t, served)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "response", resp)
+			assert.True(t, served)
+			assert.Equal(t, tt.wantID, gotID)
+			assert.Equal(t, tt.wantOK, gotOK)
+		})
+	}
+}
+
+// incoming returns a context with the key-value pairs as incoming request
+// metadata.
+func incoming(kv ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+}
@@ -0,0 +1,118 @@
+// Package projecthttp provides HTTP middleware that makes the Google Cloud
+// project ID available to handlers through the request context.
+package projecthttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lucmq/gcp-project-id/project"
+)
+
+// defaultCacheTTL is the CacheTTL used when the options don't set one, so
+// requests reuse the project ID found instead of searching each time.
+const defaultCacheTTL = 5 * time.Minute
+
+// Middleware returns a handler that resolves the project ID, like
+// project.IDContext with the given options under the request context, and
+// serves the request with next, carrying the project ID in its context.
+// Handlers down the stack get it with project.ProjectIDFromContext.
+//
+// The outcome of the search is cached for the CacheTTL option, which
+// defaults to 5 minutes here, so the search only runs once in a while, not
+// on each request. That's true of misses and failures too. The requests
+// arriving while a search runs wait for it instead of searching again. When
+// no project ID is found, the request is served without one. When the
+// search fails, as with the Strict option, the request fails with 500
+// Internal Server Error, without details. When the request is canceled
+// before the search ends, nothing is written, as the client is gone.
+func Middleware(next http.Handler, opts ...project.Options) http.Handler {
+	var o project.Options
+	if len(opts) != 0 {
+		o = opts[0]
+	}
+	if o.CacheTTL <= 0 {
+		o.CacheTTL = defaultCacheTTL
+	}
+	l := lookup{o: o}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id, ok := project.ProjectIDFromContext(ctx)
+		if !ok {
+			var err error
+			id, err = l.projectID(ctx)
+			if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				code := http.StatusInternalServerError
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+		}
+		if id != "" {
+			r = r.WithContext(project.WithProjectID(ctx, id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// lookup searches the project ID for the requests of a Middleware, caching
+// the outcome, found or not, for the CacheTTL, and sharing the search in
+// flight, if any, among the requests.
+type lookup struct {
+	o project.Options
+
+	mu      sync.Mutex
+	result  *result
+	expires time.Time
+}
+
+// result is the outcome of a search. It's set before done is closed.
+type result struct {
+	done chan struct{}
+	id   string
+	err  error
+}
+
+// projectID returns the project ID cached, or joins the search in flight,
+// or starts one, and waits for it as long as ctx allows. The search isn't
+// canceled with ctx, as other requests may be waiting for it.
+func (l *lookup) projectID(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	res := l.result
+	if res == nil || isDone(res.done) && !time.Now().Before(l.expires) {
+		res = &result{done: make(chan struct{})}
+		l.result = res
+		go l.search(context.WithoutCancel(ctx), res)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.id, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (l *lookup) search(ctx context.Context, res *result) {
+	res.id, res.err = project.IDContext(ctx, l.o)
+
+	l.mu.Lock()
+	l.expires = time.Now().Add(l.o.CacheTTL)
+	l.mu.Unlock()
+	close(res.done)
+}
+
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,213 @@
+package projecthttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lucmq/gcp-project-id/project"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        project.Searcher
+		strict   bool
+		wantCode int
+		wantID   string
+		wantOK   bool
+	}{
+		{
+			name:     "Found",
+			s:        &searcherMock{projectID: "gcp-id-test"},
+			wantCode: http.StatusOK,
+			wantID:   "gcp-id-test",
+			wantOK:   true,
+		},
+		{
+			name:     "Not found",
+			s:        &searcherMock{},
+			wantCode: http.StatusOK,
+			wantOK:   false,
+		},
+		{
+			name:     "Not found, strict",
+			s:        &searcherMock{},
+			strict:   true,
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "Search error",
+			s:        &searcherMock{err: errors.New("secret details")},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				served bool
+				gotID  string
+				gotOK  bool
+			)
+			next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				served = true
+				gotID, gotOK = project.ProjectIDFromContext(r.Context())
+			})
+			h := Middleware(next, project.Options{
+				Timeout:   time.Second,
+				Strict:    tt.strict,
+				Searchers: []project.Searcher{tt.s},
+			})
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantCode == http.StatusOK, served)
+			assert.Equal(t, tt.wantID, gotID)
+			assert.Equal(t, tt.wantOK, gotOK)
+			assert.NotContains(t, w.Body.String(), "secret details")
+		})
+	}
+}
+
+func TestMiddleware_Cache(t *testing.T) {
+	s := &searcherMock{projectID: "gcp-id-test"}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := project.ProjectIDFromContext(r.Context())
+		_, _ = w.Write([]byte(id))
+	})
+	h := Middleware(next, project.Options{
+		Timeout:   time.Second,
+		Searchers: []project.Searcher{s},
+	})
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gcp-id-test", w.Body.String())
+	}
+	assert.Equal(t, int32(1), s.calls.Load())
+}
+
+func TestMiddleware_CacheMisses(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        *searcherMock
+		wantCode int
+	}{
+		{name: "Not found", s: &searcherMock{}, wantCode: http.StatusOK},
+		{
+			name:     "Search error",
+			s:        &searcherMock{err: errors.New("test error")},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), project.Options{
+				Timeout:   time.Second,
+				Searchers: []project.Searcher{tt.s},
+			})
+
+			for i := 0; i < 3; i++ {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+				require.Equal(t, tt.wantCode, w.Code)
+			}
+			assert.Equal(t, int32(1), tt.s.calls.Load())
+		})
+	}
+}
+
+func TestMiddleware_Concurrent(t *testing.T) {
+	s := &searcherMock{projectID: "gcp-id-test", release: make(chan struct{})}
+	h := Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), project.Options{
+		Timeout:   time.Second,
+		Searchers: []project.Searcher{s},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(s.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), s.calls.Load())
+}
+
+func TestMiddleware_Canceled(t *testing.T) {
+	s := &searcherMock{projectID: "gcp-id-test", release: make(chan struct{})}
+	defer close(s.release)
+	var served bool
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		served = true
+	})
+	h := Middleware(next, project.Options{
+		Timeout:   time.Second,
+		Searchers: []project.Searcher{s},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	assert.False(t, served)
+	assert.False(t, w.Flushed)
+	assert.Empty(t, w.Body.String())
+	assert.NotEqual(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestMiddleware_ContextOverride(t *testing.T) {
+	s := &searcherMock{projectID: "gcp-id-searched"}
+	var gotID string
+	next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		gotID, _ = project.ProjectIDFromContext(r.Context())
+	})
+	h := Middleware(next, project.Options{
+		Timeout:   time.Second,
+		Searchers: []project.Searcher{s},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(project.WithProjectID(r.Context(), "gcp-id-test"))
+
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "gcp-id-test", gotID)
+	assert.Zero(t, s.calls.Load())
+}
+
+type searcherMock struct {
+	projectID string
+	err       error
+	calls     atomic.Int32
+
+	// release, if set, is waited for before answering.
+	release chan struct{}
+}
+
+var _ project.Searcher = (*searcherMock)(nil)
+
+func (s *searcherMock) ProjectID(context.Context, ...string) (string, error) {
+	s.calls.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	return s.projectID, s.err
+}
@@ -0,0 +1,99 @@
+package project
+
+import "context"
+
+// rawSource is implemented by searchers that process the value they read,
+// like trimming it, to provide it unprocessed.
+type rawSource interface {
+	// rawProjectID returns the value read, before any processing, or an
+	// empty string if there's none.
+	rawProjectID(ctx context.Context, scopes ...string) (string, error)
+}
+
+// RawValues returns the value each source of the search chain provides,
+// by source, before any trimming, normalization or validation: the
+// environment variable verbatim, the full stdout of gcloud, the ProjectID
+// of the credentials. Sources without one, or failing, are left out.
+//
+// It's a diagnostic, to tell why a project ID differs from what a source
+// holds. Unlike the other entry points, it runs all the searchers,
+// including gcloud, bounded by the Timeout option, and ignores the caches
+// and the options processing the project ID, like Aliases and Validate.
+func RawValues(opts ...Options) map[string]string {
+	o := getOptions(opts...)
+	ctx, cancel := withDeadline(context.Background(), o)
+	defer cancel()
+
+	ss := o.Searchers
+	if len(ss) == 0 {
+		ss = defaultResolver.searchers(o)
+	}
+	values := map[string]string{}
+	for _, s := range ss {
+		source := sourceOf(s)
+		if _, ok := values[source]; ok {
+			continue
+		}
+		if skipUndiscovered(ctx, o, s) {
+			continue
+		}
+		if o.SecureFilesOnly && checkSecureSearcher(s) != nil {
+			continue
+		}
+		v, err := rawProjectID(ctx, s, o.Scopes...)
+		if err == nil && v != "" {
+			values[source] = v
+		}
+	}
+	return values
+}
+
+// rawProjectID returns the unprocessed value of the searcher, if it keeps
+// one, or else the project ID it finds.
+func rawProjectID(ctx context.Context, s Searcher, scopes ...string) (string, error) {
+	if r, ok := s.(rawSource); ok {
+		return r.rawProjectID(ctx, scopes...)
+	}
+	return s.ProjectID(ctx, scopes...)
+}
+
+func (s *environmentSearcher) rawProjectID(context.Context, ...string) (string, error) {
+	for _, key := range s.envLookupKeys {
+		if v := lookupEnv(key); v != "" {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+func (s *credentialsSearcher) rawProjectID(
+	ctx context.Context, scopes ...string,
+) (
+	string, error,
+) {
+	if s.adc && noGCECheck() && !credentialsFileExists() {
+		return "", nil
+	}
+	credentials, err := s.findCredentialsFn(ctx, scopes...)
+	if err != nil {
+		return "", err
+	}
+	return credentials.ProjectID, nil
+}
+
+// rawProjectID returns the output of the first `config get-value` run that
+// succeeds with some, as printed.
+func (s *gcloudSearcher) rawProjectID(ctx context.Context, _ ...string) (string, error) {
+	args := gcloudArgs(s.configuration, s.account, s.format)
+	commands, ok := s.commands(ctx)
+	if !ok {
+		return "", ctx.Err()
+	}
+	for _, command := range commands {
+		b, err := s.run(ctx, command, args)
+		if err == nil && len(b) != 0 {
+			return string(b), nil
+		}
+	}
+	return "", ctx.Err()
+}
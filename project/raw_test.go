@@ -0,0 +1,57 @@
+package project
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2/google"
+)
+
+func TestRawValues(t *testing.T) {
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "  gcp-id-test\n")
+	unsetEnv(t, gcloudProjectPropertyKey)
+	credentials := &credentialsSearcher{
+		findCredentialsFn: func(context.Context, ...string) (*google.Credentials, error) {
+			c := google.Credentials{ProjectID: "projects/gcp-id-test"}
+			return &c, nil
+		},
+	}
+	gcloud := &gcloudSearcher{
+		executables: []string{"gcloud"},
+		output: func(*exec.Cmd, int) ([]byte, error) {
+			return []byte("gcp-id-test\r\n\n"), nil
+		},
+	}
+	SetSearchersForTest(t,
+		newGCloudPropertySearcher(),
+		newEnvironmentSearcher("GOOGLE_CLOUD_PROJECT"),
+		credentials,
+		newNamedSearcherMock("yaml", ""),
+		newSearcherMock(false, true),
+		gcloud,
+	)
+
+	got := RawValues()
+
+	want := map[string]string{
+		"env":         "  gcp-id-test\n",
+		"credentials": "projects/gcp-id-test",
+		"gcloud":      "gcp-id-test\r\n\n",
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestRawValues_Searchers(t *testing.T) {
+	o := Options{
+		Searchers: []Searcher{
+			newNamedSearcherMock("remote", "gcp-id-test"),
+			newNamedSearcherMock("remote", "gcp-id-other"),
+		},
+	}
+
+	got := RawValues(o)
+
+	assert.Equal(t, map[string]string{"remote": "gcp-id-test"}, got)
+}
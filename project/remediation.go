@@ -0,0 +1,129 @@
+package project
+
+import (
+	"errors"
+	"strings"
+)
+
+// SourceError is the outcome of a source that provided no project ID. It's
+// part of the error returned when no project ID is found with the Strict
+// option, and of the errors collected with the ContinueOnError option.
+type SourceError struct {
+	// Source is the name of the source, like "env" or "gcloud".
+	Source string
+
+	// Err is the error the source failed with, or nil if it found no
+	// project ID.
+	Err error
+}
+
+func (e *SourceError) Error() string {
+	if e.Err == nil {
+		return e.Source + ": no project ID found"
+	}
+	return e.Source + ": " + e.Err.Error()
+}
+
+func (e *SourceError) Unwrap() error { return e.Err }
+
+// Remediation returns the suggested action to provide the project ID with
+// the source, or an empty string if there's none.
+func (e *SourceError) Remediation() string { return remediations[e.Source] }
+
+// remediations are the suggested actions to provide the project ID, by
+// source.
+var remediations = map[string]string{
+	"gcloud-property":           "set the GOOGLE_CLOUD_PROJECT environment variable to the project ID",
+	"env":                       "set the GOOGLE_CLOUD_PROJECT environment variable to the project ID",
+	"env-file":                  "set GOOGLE_CLOUD_PROJECT in the SystemEnvFile",
+	"encrypted-config":          "set GOOGLE_CLOUD_PROJECT in the encrypted config file",
+	"build-time":                "build with -ldflags \"-X github.com/lucmq/gcp-project-id/project.BuildTimeProjectID=PROJECT_ID\"",
+	"remote":                    "check that the RemoteConfig service returns the project ID",
+	"yaml":                      "set the project ID in the YAML config file",
+	"deploy-config":             "set the project ID in the deploy config, at the DeployProjectPath",
+	"systemd":                   "pass the project ID as a systemd credential, with LoadCredential= or SetCredential=",
+	"boto":                      "set default_project_id in the [GSUtil] section of the boto config",
+	"k8s-token":                 "mount a projected service account token with the PROJECT_ID.svc.id.goog audience",
+	"kcc":                       "project the cnrm.cloud.google.com/project-id annotation to the KCCAnnotationsFile with the downward API",
+	"credentials-env":           "set the CredentialsBase64Env variable to a base64-encoded service account key",
+	"credential-helper":         "check that the CredentialHelper command prints a project_id",
+	"credentials-file":          "mount a service account key and set GOOGLE_APPLICATION_CREDENTIALS to its path",
+	"credentials":               "mount a service account key and set GOOGLE_APPLICATION_CREDENTIALS to its path",
+	"credentials:quota_project": "run `gcloud auth application-default set-quota-project PROJECT_ID`",
+	"metadata":                  "run on Google Cloud, where the metadata server provides the project ID",
+	"metadata-cache":            "check that the agent caching the metadata keeps MetadataCacheFile fresh",
+	"gcloud-config":             "run `gcloud init` or `gcloud config set project PROJECT_ID`",
+	"gcloud-config:inferred":    "activate a configuration with `gcloud config configurations activate NAME`",
+	"gcloud-config-helper":      "run `gcloud auth login` and `gcloud config set project PROJECT_ID`",
+	"gcloud":                    "run `gcloud init` or `gcloud config set project PROJECT_ID`",
+}
+
+// Remediation returns a summary of the actions suggested by the errors in
+// the tree of err that have a `Remediation() string` method, like
+// SourceError, one per line and without duplicates. It's empty if none
+// suggests an action.
+func Remediation(err error) string {
+	var (
+		lines []string
+		seen  = map[string]bool{}
+	)
+	walkErrors(err, func(err error) {
+		r, ok := err.(interface{ Remediation() string })
+		if !ok {
+			return
+		}
+		if line := r.Remediation(); line != "" && !seen[line] {
+			seen[line] = true
+			lines = append(lines, "- "+line)
+		}
+	})
+	if len(lines) == 0 {
+		return ""
+	}
+	return "To provide the project ID, either:\n" + strings.Join(lines, "\n")
+}
+
+// walkErrors calls fn on err and all the errors it wraps, depth first.
+func walkErrors(err error, fn func(error)) {
+	if err == nil {
+		return
+	}
+	fn(err)
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		walkErrors(u.Unwrap(), fn)
+	case interface{ Unwrap() []error }:
+		for _, err := range u.Unwrap() {
+			walkErrors(err, fn)
+		}
+	}
+}
+
+// addMiss records that the searcher s provided no project ID, failing with
+// err if not nil, when the options need it.
+func addMiss(misses []error, o Options, s Searcher, err error) []error {
+	if !o.Strict && !o.ContinueOnError {
+		return misses
+	}
+	return append(misses, &SourceError{Source: sourceOf(s), Err: err})
+}
+
+// notFoundError returns the error for no project ID found, given the
+// misses of the sources.
+func notFoundError(misses []error) error {
+	if len(misses) == 0 {
+		return ErrProjectIDNotFound
+	}
+	return errors.Join(append([]error{ErrProjectIDNotFound}, misses...)...)
+}
+
+// failures returns the misses of the sources that failed.
+func failures(misses []error) []error {
+	var errs []error
+	for _, err := range misses {
+		if e, ok := err.(*SourceError); ok && e.Err != nil {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
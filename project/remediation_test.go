@@ -0,0 +1,200 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceError(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             *SourceError
+		wantMessage     string
+		wantRemediation string
+	}{
+		{
+			name:            "Miss",
+			err:             &SourceError{Source: "env"},
+			wantMessage:     "env: no project ID found",
+			wantRemediation: remediations["env"],
+		},
+		{
+			name:            "Failure",
+			err:             &SourceError{Source: "gcloud", Err: errTest},
+			wantMessage:     "gcloud: test error",
+			wantRemediation: remediations["gcloud"],
+		},
+		{
+			name:            "Unknown source",
+			err:             &SourceError{Source: "custom"},
+			wantMessage:     "custom: no project ID found",
+			wantRemediation: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantMessage, tt.err.Error())
+			assert.Equal(t, tt.wantRemediation, tt.err.Remediation())
+			assert.Equal(t, tt.err.Err, errors.Unwrap(tt.err))
+		})
+	}
+}
+
+func TestRemediation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "Joined, without duplicates",
+			err: errors.Join(
+				ErrProjectIDNotFound,
+				&SourceError{Source: "gcloud-property"},
+				&SourceError{Source: "env"},
+				&SourceError{Source: "credentials", Err: errTest},
+				&SourceError{Source: "gcloud"},
+			),
+			want: "To provide the project ID, either:\n" +
+				"- " + remediations["env"] + "\n" +
+				"- " + remediations["credentials"] + "\n" +
+				"- " + remediations["gcloud"],
+		},
+		{
+			name: "Wrapped",
+			err:  fmt.Errorf("startup: %w", &SourceError{Source: "gcloud"}),
+			want: "To provide the project ID, either:\n" +
+				"- " + remediations["gcloud"],
+		},
+		{
+			name: "No remediation",
+			err:  errors.Join(errTest, &SourceError{Source: "custom"}),
+			want: "",
+		},
+		{
+			name: "Nil",
+			err:  nil,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Remediation(tt.err))
+		})
+	}
+}
+
+func TestID_StrictSourceErrors(t *testing.T) {
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{
+			newNamedSearcherMock("env", ""),
+			newNamedSearcherMock("gcloud", ""),
+		}
+	})
+
+	r := <-IDAsync(Options{Timeout: time.Second, Strict: true})
+
+	require.ErrorIs(t, r.Err, ErrProjectIDNotFound)
+	assert.Equal(t, "google cloud project ID not found\n"+
+		"env: no project ID found\n"+
+		"gcloud: no project ID found", r.Err.Error())
+	var sourceErr *SourceError
+	require.ErrorAs(t, r.Err, &sourceErr)
+	assert.Equal(t, "env", sourceErr.Source)
+	assert.Contains(t, Remediation(r.Err), remediations["gcloud"])
+}
+
+func TestID_ContinueOnError(t *testing.T) {
+	errOther := errors.New("other error")
+	tests := []struct {
+		name            string
+		policy          Policy
+		continueOnError bool
+		searchers       []Searcher
+		want            string
+		wantErrs        []error
+	}{
+		{
+			name:            "Failure skipped",
+			continueOnError: true,
+			searchers: []Searcher{
+				&searcherMock{wantError: true},
+				newNamedSearcherMock("gcloud", "gcp-id-test"),
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name:            "Failures returned when not found",
+			continueOnError: true,
+			searchers: []Searcher{
+				&searcherMock{wantError: true},
+				newNamedSearcherMock("env", ""),
+				&namedSearcherErrMock{source: "gcloud", err: errOther},
+			},
+			wantErrs: []error{errTest, errOther},
+		},
+		{
+			name:            "Consistent, failure skipped",
+			policy:          Consistent,
+			continueOnError: true,
+			searchers: []Searcher{
+				newNamedSearcherMock("env", "gcp-id-test"),
+				&searcherMock{wantError: true},
+				newNamedSearcherMock("gcloud", "gcp-id-test"),
+			},
+			want: "gcp-id-test",
+		},
+		{
+			name:            "Option disabled",
+			continueOnError: false,
+			searchers: []Searcher{
+				&searcherMock{wantError: true},
+				newNamedSearcherMock("gcloud", "gcp-id-test"),
+			},
+			wantErrs: []error{errTest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher { return tt.searchers })
+			opts := Options{
+				Timeout:         time.Second,
+				Policy:          tt.policy,
+				ContinueOnError: tt.continueOnError,
+			}
+
+			r := <-IDAsync(opts)
+
+			if tt.wantErrs != nil {
+				for _, want := range tt.wantErrs {
+					require.ErrorIs(t, r.Err, want)
+				}
+				assert.Empty(t, r.ID)
+				return
+			}
+			require.NoError(t, r.Err)
+			assert.Equal(t, tt.want, r.ID)
+		})
+	}
+}
+
+type namedSearcherErrMock struct {
+	source string
+	err    error
+}
+
+var _ Searcher = (*namedSearcherErrMock)(nil)
+
+func (s *namedSearcherErrMock) Source() string { return s.source }
+
+func (s *namedSearcherErrMock) ProjectID(context.Context, ...string) (
+	string, error,
+) {
+	return "", s.err
+}
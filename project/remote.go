@@ -0,0 +1,66 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RemoteConfig configures a central configuration service, like an HTTP or
+// gRPC endpoint run by a platform team, that returns the project ID.
+//
+// The same RemoteConfig should be reused across calls, as it holds the
+// cache of the value fetched.
+type RemoteConfig struct {
+	// Fetch returns the project ID from the service, or an empty string if
+	// it has none. It's called with the context of the search, bounded by
+	// the Timeout and Deadline options.
+	Fetch func(ctx context.Context) (string, error)
+
+	// CacheTTL, if positive, is how long a value fetched is reused before
+	// Fetch is called again. Errors are not cached.
+	CacheTTL time.Duration
+
+	mu      sync.Mutex
+	id      string
+	expires time.Time
+}
+
+// Remote Config Searcher
+
+type remoteSearcher struct {
+	config *RemoteConfig
+}
+
+var _ Searcher = (*remoteSearcher)(nil)
+
+func newRemoteSearcher(config *RemoteConfig) *remoteSearcher {
+	s := remoteSearcher{
+		config: config,
+	}
+	return &s
+}
+
+func (*remoteSearcher) Source() string { return "remote" }
+
+func (s *remoteSearcher) ProjectID(ctx context.Context, _ ...string) (
+	string, error,
+) {
+	c := s.config
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.CacheTTL > 0 && now().Before(c.expires) {
+		return c.id, nil
+	}
+
+	id, err := c.Fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetch remote config: %w", err)
+	}
+	id = sanitizeValue(id)
+	if c.CacheTTL > 0 {
+		c.id, c.expires = id, now().Add(c.CacheTTL)
+	}
+	return id, nil
+}
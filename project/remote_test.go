@@ -0,0 +1,106 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestID_RemoteConfig(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	unsetEnv(t, "BUILD_ID", "PROJECT_NUMBER")
+
+	t.Run("Value", func(t *testing.T) {
+		remote := &RemoteConfig{
+			Fetch: func(ctx context.Context) (string, error) {
+				_, ok := ctx.Deadline()
+				assert.True(t, ok, "search context not used")
+				return "gcp-id-test", nil
+			},
+		}
+
+		r := <-IDAsync(Options{Timeout: time.Second, RemoteConfig: remote})
+
+		require.NoError(t, r.Err)
+		assert.Equal(t, Result{ID: "gcp-id-test", Source: "remote", Found: true}, r)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		remote := &RemoteConfig{
+			Fetch: func(context.Context) (string, error) {
+				return "", errTest
+			},
+		}
+
+		r := <-IDAsync(Options{Timeout: time.Second, RemoteConfig: remote})
+
+		require.ErrorIs(t, r.Err, errTest)
+		assert.Empty(t, r.ID)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		remote := &RemoteConfig{
+			Fetch: func(ctx context.Context) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			},
+		}
+
+		r := <-IDAsync(Options{Timeout: time.Millisecond, RemoteConfig: remote})
+
+		require.ErrorIs(t, r.Err, context.DeadlineExceeded)
+	})
+}
+
+func Test_remoteSearcher_ProjectID_Cache(t *testing.T) {
+	clock := useCache(t)
+	var calls int
+	fail := false
+	remote := &RemoteConfig{
+		Fetch: func(context.Context) (string, error) {
+			calls++
+			if fail {
+				return "", errTest
+			}
+			return "gcp-id-test", nil
+		},
+		CacheTTL: time.Minute,
+	}
+	s := newRemoteSearcher(remote)
+
+	for range 2 {
+		got, err := s.ProjectID(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+	}
+	assert.Equal(t, 1, calls)
+
+	*clock = clock.Add(time.Minute)
+	fail = true
+	_, err := s.ProjectID(context.Background())
+	require.ErrorIs(t, err, errTest)
+	_, err = s.ProjectID(context.Background())
+	require.ErrorIs(t, err, errTest)
+	assert.Equal(t, 3, calls, "error cached")
+}
+
+func Test_remoteSearcher_ProjectID_NoCache(t *testing.T) {
+	var calls int
+	remote := &RemoteConfig{
+		Fetch: func(context.Context) (string, error) {
+			calls++
+			return "gcp-id-test", nil
+		},
+	}
+	s := newRemoteSearcher(remote)
+
+	for range 2 {
+		_, err := s.ProjectID(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+}
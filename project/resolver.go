@@ -0,0 +1,110 @@
+package project
+
+import "context"
+
+// Resolver resolves the project ID with its own search chain and options,
+// so independent configurations can coexist in one process. The
+// package-level functions, like ID and Resolve, use a default Resolver,
+// which searches the DefaultSearchers chain, with the options given to
+// each call.
+//
+// It's safe for concurrent use.
+type Resolver struct {
+	// chain returns the searchers for the options, when the Searchers
+	// option is not set. When nil, the default chain is searched.
+	chain func(o Options) []Searcher
+
+	// opts are the options of the resolutions run by the methods.
+	opts Options
+
+	// hermetic isolates the resolutions from the process state: the
+	// Frozen project ID, the caches and the Subscribe notifications.
+	hermetic bool
+}
+
+// defaultResolver is the Resolver used by the package-level functions.
+var defaultResolver = &Resolver{}
+
+// NewHermetic returns a Resolver searching only the given searchers, in
+// order, with the default options, which WithOptions replaces. Unlike SetSearchersForTest, it doesn't
+// change package state: it ignores the Frozen project ID and the caches,
+// and doesn't notify the Subscribe channels, so tests using it may run in
+// parallel:
+//
+//	r := project.NewHermetic(stub)
+//	id, err := r.ID(ctx)
+func NewHermetic(searchers ...Searcher) *Resolver {
+	ss := append([]Searcher(nil), searchers...)
+	r := Resolver{
+		chain:    func(Options) []Searcher { return ss },
+		opts:     getOptions(),
+		hermetic: true,
+	}
+	return &r
+}
+
+// searchers returns the search chain for the options, when the Searchers
+// option is not set.
+func (rv *Resolver) searchers(o Options) []Searcher {
+	if rv.chain == nil {
+		return searchers(o)
+	}
+	return rv.chain(o)
+}
+
+// WithFallbackSearcher returns a copy of the Resolver that searches s after
+// the searchers of its chain. The Resolver itself is unchanged, so it can
+// be shared and extended in different ways:
+//
+//	base := project.NewHermetic(env)
+//	r := base.WithFallbackSearcher(stub)
+func (rv *Resolver) WithFallbackSearcher(s Searcher) *Resolver {
+	searchers := rv.searchers
+	c := *rv
+	c.chain = func(o Options) []Searcher {
+		ss := searchers(o)
+		return append(append(make([]Searcher, 0, len(ss)+1), ss...), s)
+	}
+	return &c
+}
+
+// WithPrimarySearcher returns a copy of the Resolver that searches s before
+// the searchers of its chain. The Resolver itself is unchanged.
+func (rv *Resolver) WithPrimarySearcher(s Searcher) *Resolver {
+	searchers := rv.searchers
+	c := *rv
+	c.chain = func(o Options) []Searcher {
+		ss := searchers(o)
+		return append(append(make([]Searcher, 0, len(ss)+1), s), ss...)
+	}
+	return &c
+}
+
+// WithOptions returns a copy of the Resolver that resolves with the
+// options o. The Resolver itself is unchanged:
+//
+//	r := project.NewHermetic(env).WithOptions(project.Options{
+//		Timeout: time.Second,
+//		Strict:  true,
+//	})
+//
+// The Searchers option, if set, replaces the search chain of the Resolver.
+func (rv *Resolver) WithOptions(o Options) *Resolver {
+	c := *rv
+	c.opts = getOptions(o)
+	return &c
+}
+
+// ID retrieves the project ID like IDContext, with the Resolver's search
+// chain and options.
+func (rv *Resolver) ID(ctx context.Context) (string, error) {
+	r := rv.resolve(ctx, rv.opts)
+	return r.ID, r.Err
+}
+
+// TryID retrieves the project ID like TryID, with the Resolver's search
+// chain and options, reporting whether one was found.
+func (rv *Resolver) TryID(ctx context.Context) (string, bool) {
+	r := rv.resolve(ctx, rv.opts)
+	return r.ID, r.Found
+}
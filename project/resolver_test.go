@@ -0,0 +1,175 @@
+package project
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHermetic(t *testing.T) {
+	t.Run("Searches the searchers in order", func(t *testing.T) {
+		r := NewHermetic(
+			newNamedSearcherMock("env", ""),
+			newNamedSearcherMock("credentials", "gcp-id-test"),
+			newNamedSearcherMock("gcloud", "gcp-id-gcloud"),
+		)
+
+		id, err := r.ID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", id)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		r := NewHermetic(newNamedSearcherMock("env", ""))
+
+		id, ok := r.TryID(context.Background())
+
+		assert.False(t, ok)
+		assert.Empty(t, id)
+	})
+
+	t.Run("No searchers", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "gcp-id-default")}
+		})
+		r := NewHermetic()
+
+		id, ok := r.TryID(context.Background())
+
+		assert.False(t, ok)
+		assert.Empty(t, id)
+	})
+
+	t.Run("Search error", func(t *testing.T) {
+		r := NewHermetic(newSearcherMock(false, true))
+
+		_, err := r.ID(context.Background())
+
+		require.ErrorIs(t, err, errTest)
+	})
+
+	t.Run("Ignores the default searchers", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "gcp-id-default")}
+		})
+		r := NewHermetic(newNamedSearcherMock("env", "gcp-id-test"))
+
+		id, ok := r.TryID(context.Background())
+
+		assert.True(t, ok)
+		assert.Equal(t, "gcp-id-test", id)
+		assert.Equal(t, "gcp-id-default", ID())
+	})
+
+	t.Run("Ignores the frozen project ID", func(t *testing.T) {
+		Freeze("gcp-id-frozen")
+		t.Cleanup(Unfreeze)
+		r := NewHermetic(newNamedSearcherMock("env", "gcp-id-test"))
+
+		id, err := r.ID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", id)
+	})
+
+	t.Run("Doesn't notify", func(t *testing.T) {
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newNamedSearcherMock("env", "gcp-id-default")}
+		})
+		ID()
+		sub := Subscribe()
+		t.Cleanup(func() { Unsubscribe(sub) })
+		r := NewHermetic(newNamedSearcherMock("env", "gcp-id-test"))
+
+		_, _ = r.ID(context.Background())
+
+		assert.Empty(t, sub)
+	})
+
+	t.Run("Uses the context", func(t *testing.T) {
+		r := NewHermetic(newNamedSearcherMock("env", "gcp-id-test"))
+		ctx := WithProjectID(context.Background(), "gcp-id-context")
+
+		id, err := r.ID(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-context", id)
+	})
+}
+
+func TestResolver_WithSearchers(t *testing.T) {
+	t.Run("Fallback", func(t *testing.T) {
+		r := NewHermetic(newNamedSearcherMock("env", "")).
+			WithFallbackSearcher(newNamedSearcherMock("gcloud", "gcp-id-test"))
+
+		id, err := r.ID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", id)
+	})
+
+	t.Run("Order", func(t *testing.T) {
+		var got []string
+		r := NewHermetic(newNamedSearcherMock("env", "")).
+			WithFallbackSearcher(newNamedSearcherMock("gcloud", "")).
+			WithPrimarySearcher(newNamedSearcherMock("metadata", "")).
+			WithPrimarySearcher(newNamedSearcherMock("credentials", ""))
+		r.opts.OnSearch = func(step SearchStep) { got = append(got, step.Source) }
+
+		_, ok := r.TryID(context.Background())
+
+		assert.False(t, ok)
+		assert.Equal(t, []string{"credentials", "metadata", "env", "gcloud"}, got)
+	})
+
+	t.Run("Primary first", func(t *testing.T) {
+		r := NewHermetic(newNamedSearcherMock("env", "gcp-id-env")).
+			WithPrimarySearcher(newNamedSearcherMock("credentials", "gcp-id-test"))
+
+		id, err := r.ID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", id)
+	})
+
+	t.Run("Immutable", func(t *testing.T) {
+		base := NewHermetic(newNamedSearcherMock("env", ""))
+		a := base.WithFallbackSearcher(newNamedSearcherMock("gcloud", "gcp-id-a"))
+		b := base.WithFallbackSearcher(newNamedSearcherMock("gcloud", "gcp-id-b"))
+		c := base.WithPrimarySearcher(newNamedSearcherMock("credentials", "gcp-id-c"))
+
+		_, ok := base.TryID(context.Background())
+		assert.False(t, ok)
+		for want, r := range map[string]*Resolver{"gcp-id-a": a, "gcp-id-b": b, "gcp-id-c": c} {
+			id, err := r.ID(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, want, id)
+		}
+	})
+
+	t.Run("Keeps the hermetic isolation", func(t *testing.T) {
+		Freeze("gcp-id-frozen")
+		t.Cleanup(Unfreeze)
+		r := NewHermetic().WithFallbackSearcher(newNamedSearcherMock("env", "gcp-id-test"))
+
+		id, err := r.ID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", id)
+	})
+}
+
+func TestResolver_WithOptions(t *testing.T) {
+	base := NewHermetic(newNamedSearcherMock("env", ""))
+	strict := base.WithOptions(Options{Strict: true})
+
+	_, err := strict.ID(context.Background())
+	require.ErrorIs(t, err, ErrProjectIDNotFound)
+
+	// The base Resolver is unchanged.
+	_, err = base.ID(context.Background())
+	require.NoError(t, err)
+}
@@ -0,0 +1,49 @@
+package project
+
+import (
+	"context"
+	"time"
+)
+
+// defaultChainRetryDelay is the delay between the retries of the chain when
+// the ChainRetryDelay option is zero.
+const defaultChainRetryDelay = 100 * time.Millisecond
+
+// Seam for the retries.
+var wait = waitContext
+
+// waitContext waits for d, or until ctx is done, in which case it returns
+// the error of ctx.
+func waitContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// searchChain runs defaultProjectID and, as set by the ChainRetries option,
+// runs it again after a delay while it finds nothing. The retries stop when
+// ctx is done, with the outcome of the last search.
+func (rv *Resolver) searchChain(ctx context.Context, o Options) (
+	id string, winner Searcher, misses []error, err error,
+) {
+	id, winner, misses, err = rv.defaultProjectID(ctx, o)
+	delay := o.ChainRetryDelay
+	if delay <= 0 {
+		delay = defaultChainRetryDelay
+	}
+	for i := 0; i < o.ChainRetries && id == ""; i++ {
+		if err != nil && (!o.ChainRetryOnError || ctx.Err() != nil) {
+			break
+		}
+		if wait(ctx, delay) != nil {
+			break
+		}
+		id, winner, misses, err = rv.defaultProjectID(ctx, o)
+	}
+	return id, winner, misses, err
+}
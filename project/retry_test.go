@@ -0,0 +1,165 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestID_ChainRetries(t *testing.T) {
+	errSearch := errors.New("search error")
+	tests := []struct {
+		name      string
+		results   []string
+		errs      []error
+		retries   int
+		onError   bool
+		want      string
+		wantErr   error
+		wantCalls int
+		wantWaits []time.Duration
+	}{
+		{
+			name:      "Empty then populated",
+			results:   []string{"", "", "gcp-id-test"},
+			retries:   3,
+			want:      "gcp-id-test",
+			wantCalls: 3,
+			wantWaits: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			name:      "Found at once",
+			results:   []string{"gcp-id-test"},
+			retries:   3,
+			want:      "gcp-id-test",
+			wantCalls: 1,
+		},
+		{
+			name:      "Retries exhausted",
+			results:   []string{"", "", "", "gcp-id-test"},
+			retries:   2,
+			want:      "",
+			wantCalls: 3,
+			wantWaits: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			name:      "No retries",
+			results:   []string{"", "gcp-id-test"},
+			retries:   0,
+			want:      "",
+			wantCalls: 1,
+		},
+		{
+			name:      "Error not retried",
+			results:   []string{"", "gcp-id-test"},
+			errs:      []error{errSearch},
+			retries:   3,
+			wantErr:   errSearch,
+			wantCalls: 1,
+		},
+		{
+			name:      "Error retried",
+			results:   []string{"", "gcp-id-test"},
+			errs:      []error{errSearch},
+			retries:   3,
+			onError:   true,
+			want:      "gcp-id-test",
+			wantCalls: 2,
+			wantWaits: []time.Duration{100 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := useWait(t)
+			s := &sequenceSearcherMock{results: tt.results, errs: tt.errs}
+			useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+
+			got, err := IDContext(context.Background(), Options{
+				Timeout:           time.Second,
+				ChainRetries:      tt.retries,
+				ChainRetryOnError: tt.onError,
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantCalls, s.calls)
+			assert.Equal(t, tt.wantWaits, *waits)
+		})
+	}
+}
+
+func TestID_ChainRetryDelay(t *testing.T) {
+	waits := useWait(t)
+	s := &sequenceSearcherMock{results: []string{"", "gcp-id-test"}}
+	useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+
+	got := ID(Options{
+		Timeout:         time.Second,
+		ChainRetries:    1,
+		ChainRetryDelay: 5 * time.Millisecond,
+	})
+
+	assert.Equal(t, "gcp-id-test", got)
+	assert.Equal(t, []time.Duration{5 * time.Millisecond}, *waits)
+}
+
+func TestID_ChainRetriesTimeout(t *testing.T) {
+	s := &sequenceSearcherMock{results: []string{"", "", "", "gcp-id-test"}}
+	useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+
+	got, err := IDContext(context.Background(), Options{
+		Timeout:         50 * time.Millisecond,
+		ChainRetries:    3,
+		ChainRetryDelay: time.Hour,
+	})
+
+	// The chain was searched in full: nothing found is the outcome.
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Equal(t, 1, s.calls)
+}
+
+// useWait makes the retries advance the clock instead of waiting, for the
+// duration of the test, and returns the waits recorded.
+func useWait(t *testing.T) *[]time.Duration {
+	t.Helper()
+	clock := time.Now()
+	var waits []time.Duration
+	replace(t, &now, func() time.Time { return clock })
+	replace(t, &wait, func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock = clock.Add(d)
+		return ctx.Err()
+	})
+	return &waits
+}
+
+// sequenceSearcherMock returns its results, and errors, in sequence, one
+// per call, and then the last one.
+type sequenceSearcherMock struct {
+	results []string
+	errs    []error
+	calls   int
+}
+
+var _ Searcher = (*sequenceSearcherMock)(nil)
+
+func (s *sequenceSearcherMock) ProjectID(context.Context, ...string) (
+	string, error,
+) {
+	i := s.calls
+	s.calls++
+	var err error
+	if i < len(s.errs) {
+		err = s.errs[i]
+	}
+	return s.results[min(i, len(s.results)-1)], err
+}
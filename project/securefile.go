@@ -0,0 +1,63 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ErrInsecureFile is returned (wrapped) when the SecureFilesOnly option is
+// set and a source is backed by a file that other users can write to.
+var ErrInsecureFile = errors.New("insecure file")
+
+// checkSecureFile returns an error wrapping ErrInsecureFile if the file is
+// group or world writable, so its content could have been tampered with.
+// Missing files are left to the searcher. Windows doesn't expose these
+// permission bits, so the check is skipped there.
+func checkSecureFile(file string) error {
+	if file == "" || goos == "windows" {
+		return nil
+	}
+	info, err := stat(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("check file permissions: %w", err)
+	}
+	if perm := info.Mode().Perm(); perm&0o022 != 0 {
+		return fmt.Errorf("%w: %s is writable by other users (mode %#o)",
+			ErrInsecureFile, file, perm)
+	}
+	return nil
+}
+
+// filesSource is implemented by searchers reading several files, like all
+// the boto configuration files. It tells them all, while backingFile only
+// tells the main one.
+type filesSource interface {
+	backingFiles() []string
+}
+
+// searcherFiles returns the files the searcher s reads, if any.
+func searcherFiles(s Searcher) []string {
+	var files []string
+	if f, ok := s.(fileSource); ok {
+		files = append(files, f.backingFile())
+	}
+	if f, ok := s.(filesSource); ok {
+		files = append(files, f.backingFiles()...)
+	}
+	return files
+}
+
+// checkSecureSearcher runs checkSecureFile on each of the files the
+// searcher s reads.
+func checkSecureSearcher(s Searcher) error {
+	for _, file := range searcherFiles(s) {
+		if err := checkSecureFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package project
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkSecureFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not checked on Windows")
+	}
+	tests := []struct {
+		name    string
+		perm    os.FileMode
+		wantErr bool
+	}{
+		{name: "Owner only", perm: 0o600},
+		{name: "Read only", perm: 0o400},
+		{name: "World readable", perm: 0o644},
+		{name: "Group writable", perm: 0o660, wantErr: true},
+		{name: "World writable", perm: 0o606, wantErr: true},
+		{name: "Writable by all", perm: 0o666, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeFileMode(t, "gcp-id-test", tt.perm)
+
+			err := checkSecureFile(file)
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInsecureFile)
+				assert.Contains(t, err.Error(), file)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("Missing file", func(t *testing.T) {
+		err := checkSecureFile(filepath.Join(t.TempDir(), "missing"))
+
+		require.NoError(t, err)
+	})
+
+	t.Run("Windows", func(t *testing.T) {
+		replace(t, &goos, "windows")
+		file := writeFileMode(t, "gcp-id-test", 0o666)
+
+		err := checkSecureFile(file)
+
+		require.NoError(t, err)
+	})
+}
+
+func TestResolve_SecureFilesOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not checked on Windows")
+	}
+	searchersFor := map[string]func(t *testing.T, file string) Searcher{
+		"env-file": func(t *testing.T, file string) Searcher {
+			return newEnvFileSearcher(file, "GCP_PROJECT")
+		},
+		"gcloud-config": func(t *testing.T, file string) Searcher {
+			dir := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "configurations"), 0o700))
+			target := filepath.Join(dir, "configurations", "config_default")
+			require.NoError(t, os.Rename(file, target))
+			t.Setenv("CLOUDSDK_CONFIG", dir)
+			return newGCloudConfigSearcher("")
+		},
+		"credentials-file": func(t *testing.T, file string) Searcher {
+			t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+			return newCredentialsFileSearcher("")
+		},
+		"boto": func(t *testing.T, file string) Searcher {
+			t.Setenv("BOTO_CONFIG", file)
+			return newBotoSearcher()
+		},
+		"gcloud-config:inferred": func(t *testing.T, file string) Searcher {
+			dir := t.TempDir()
+			require.NoError(t, os.Mkdir(filepath.Join(dir, "configurations"), 0o700))
+			target := filepath.Join(dir, "configurations", "config_other")
+			require.NoError(t, os.Rename(file, target))
+			t.Setenv("CLOUDSDK_CONFIG", dir)
+			return newGCloudConfigScanSearcher()
+		},
+	}
+	contents := map[string]string{
+		"env-file":               "GCP_PROJECT=gcp-id-test\n",
+		"gcloud-config":          "[core]\nproject = gcp-id-test\n",
+		"credentials-file":       `{"type":"service_account","project_id":"gcp-id-test"}`,
+		"boto":                   "[GSUtil]\ndefault_project_id = gcp-id-test\n",
+		"gcloud-config:inferred": "[core]\nproject = gcp-id-test\n",
+	}
+	for source, newSearcher := range searchersFor {
+		t.Run(source, func(t *testing.T) {
+			unsetEnv(t, impersonateServiceAccountKey, "CLOUDSDK_ACTIVE_CONFIG_NAME")
+			for _, perm := range []os.FileMode{0o600, 0o666} {
+				file := writeFileMode(t, contents[source], perm)
+				s := newSearcher(t, file)
+				useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+
+				insecure, err := Resolve(context.Background(), Options{Timeout: time.Second})
+				require.NoError(t, err)
+				assert.Equal(t, "gcp-id-test", insecure.ID)
+
+				secure, err := Resolve(context.Background(), Options{
+					Timeout:         time.Second,
+					SecureFilesOnly: true,
+				})
+				if perm&0o022 != 0 {
+					require.ErrorIs(t, err, ErrInsecureFile)
+					assert.Contains(t, err.Error(), source)
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, "gcp-id-test", secure.ID)
+			}
+		})
+	}
+}
+
+func TestResolve_SecureFilesOnly_GCloudActiveConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not checked on Windows")
+	}
+	unsetEnv(t, impersonateServiceAccountKey, "CLOUDSDK_ACTIVE_CONFIG_NAME")
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "configurations"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "configurations", "config_other"),
+		[]byte("[core]\nproject = gcp-id-test\n"), 0o600))
+	active := filepath.Join(dir, "active_config")
+	require.NoError(t, os.WriteFile(active, nil, 0o600))
+	require.NoError(t, os.Chmod(active, 0o666))
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{newGCloudConfigScanSearcher()}
+	})
+
+	_, err := Resolve(context.Background(), Options{
+		Timeout:         time.Second,
+		SecureFilesOnly: true,
+	})
+
+	require.ErrorIs(t, err, ErrInsecureFile)
+	assert.Contains(t, err.Error(), active)
+}
+
+// writeFileMode writes content to a file of the test's temporary directory,
+// with the given permissions regardless of the umask, and returns its path.
+func writeFileMode(t *testing.T, content string, perm os.FileMode) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, []byte(content), perm))
+	require.NoError(t, os.Chmod(file, perm))
+	return file
+}
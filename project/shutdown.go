@@ -0,0 +1,35 @@
+package project
+
+import "context"
+
+// Shutdown releases the resources the package holds for the process, for a
+// clean teardown in tests and graceful shutdowns. It:
+//   - clears the project ID cached with the CacheTTL option,
+//   - forgets the source remembered with the StickySource option,
+//   - forgets the project ID of the last search, so the next one isn't
+//     notified to the Subscribe channels as a change, which stay
+//     subscribed,
+//   - forgets the verdicts cached about the metadata server, like the one
+//     of OnGCP, and
+//   - closes the idle connections of the metadata server client.
+//
+// The DiskCache file is kept, as it's meant to outlive the process, and so
+// is the Frozen project ID, which Unfreeze clears.
+//
+// Nothing is stopped for good: later calls work as usual and allocate the
+// resources again. It's safe to call multiple times, concurrently and when
+// nothing was used. It returns ctx.Err() if ctx is done before the teardown
+// completes.
+func Shutdown(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cache.clear()
+	sticky.clear()
+	resetChanges()
+	resetMetadata()
+	if c, ok := metadataClient.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
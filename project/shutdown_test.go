@@ -0,0 +1,103 @@
+package project
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	useCache(t)
+	s := &countingSearcherMock{projectID: "gcp-id-test"}
+	useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+	var probes int
+	useMetadataServer(t, func(w http.ResponseWriter, _ *http.Request) {
+		probes++
+		w.Header().Set("Metadata-Flavor", "Google")
+	})
+	client := &closingDoerMock{httpDoer: metadataClient}
+	replace[httpDoer](t, &metadataClient, client)
+	opts := Options{Timeout: time.Second, CacheTTL: time.Hour}
+
+	ID(opts)
+	OnGCP(context.Background())
+	require.NoError(t, Shutdown(context.Background()))
+	ID(opts)
+	OnGCP(context.Background())
+
+	assert.Equal(t, 2, s.calls, "cache not cleared")
+	assert.Equal(t, 2, probes, "OnGCP verdict not cleared")
+	assert.Equal(t, 1, client.closed)
+}
+
+func TestShutdown_StickySource(t *testing.T) {
+	env := newNamedSearcherMock("env", "")
+	config := newNamedSearcherMock("gcloud-config", "gcp-id-config")
+	SetSearchersForTest(t, env, config)
+	opts := Options{Timeout: time.Second, StickySource: true}
+
+	assert.Equal(t, "gcp-id-config", ID(opts))
+	env.projectID = "gcp-id-test"
+	require.NoError(t, Shutdown(context.Background()))
+
+	assert.Equal(t, "gcp-id-test", ID(opts), "sticky source not cleared")
+}
+
+func TestShutdown_Changes(t *testing.T) {
+	useChanges(t)
+	ch := Subscribe()
+	t.Cleanup(func() { Unsubscribe(ch) })
+
+	observe("gcp-id-first")
+	require.NoError(t, Shutdown(context.Background()))
+	observe("gcp-id-second")
+
+	select {
+	case id := <-ch:
+		t.Errorf("last project ID not cleared: notified %q", id)
+	default:
+	}
+	observe("gcp-id-third")
+	assert.Equal(t, "gcp-id-third", receive(t, ch), "still subscribed")
+}
+
+func TestShutdown_DiskCache(t *testing.T) {
+	useCache(t)
+	s := &countingSearcherMock{projectID: "gcp-id-test"}
+	useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+	opts := diskCacheOptions(t)
+
+	ID(opts)
+	require.NoError(t, Shutdown(context.Background()))
+	ID(opts)
+
+	assert.Equal(t, 1, s.calls, "disk cache not kept")
+}
+
+func TestShutdown_Repeated(t *testing.T) {
+	useCache(t)
+
+	for range 3 {
+		require.NoError(t, Shutdown(context.Background()))
+	}
+}
+
+func TestShutdown_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Shutdown(ctx)
+
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+type closingDoerMock struct {
+	httpDoer
+	closed int
+}
+
+func (c *closingDoerMock) CloseIdleConnections() { c.closed++ }
@@ -0,0 +1,87 @@
+package project
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// SearchStep reports the outcome of one search strategy, as passed to the
+// OnSearch option.
+type SearchStep struct {
+	// Source is the name of the source searched, like "env" or "gcloud".
+	Source string
+
+	// ID is the project ID found, or empty if none was found.
+	ID string
+
+	// Err is the error returned by the search, if any.
+	Err error
+
+	// Duration is how long the search took.
+	Duration time.Duration
+
+	// Stderr is the standard error output of the subprocesses that failed
+	// during the search, like `gcloud`, one per line prefixed by the
+	// command. It's empty when they succeeded or printed nothing.
+	Stderr string
+
+	// Warning, if set, flags a problem that didn't fail the search, like
+	// resolving the project ID with the `gcloud` CLI, or its configuration
+	// files, when WarnOnGCloud is set, or every gcloud installation found
+	// failing to run.
+	Warning string
+
+	// Attempts are the commands run during the search, in order, like the
+	// `gcloud` executables tried.
+	Attempts []Attempt
+}
+
+// Attempt is the outcome of running a command during a search.
+type Attempt struct {
+	// Command is the command line run.
+	Command string
+
+	// Err is the error the command failed with, or nil if it ran
+	// successfully, even if it printed no project ID.
+	Err error
+}
+
+type stepKey struct{}
+
+// withStep returns a copy of ctx carrying the step being searched, so the
+// searcher can add details to it.
+func withStep(ctx context.Context, step *SearchStep) context.Context {
+	return context.WithValue(ctx, stepKey{}, step)
+}
+
+// stepFromContext returns the step being searched, or nil if the search is
+// not being reported.
+func stepFromContext(ctx context.Context) *SearchStep {
+	step, _ := ctx.Value(stepKey{}).(*SearchStep)
+	return step
+}
+
+// addStderr records the stderr output of a failed command, if any.
+func (s *SearchStep) addStderr(command string, stderr []byte) {
+	text := strings.TrimSpace(string(stderr))
+	if s == nil || text == "" {
+		return
+	}
+	if s.Stderr != "" {
+		s.Stderr += "\n"
+	}
+	s.Stderr += command + ": " + text
+}
+
+// addAttempt records the outcome of the command run with the args.
+func (s *SearchStep) addAttempt(command, args []string, err error) {
+	if s == nil {
+		return
+	}
+	line := strings.Join(command, " ")
+	if len(args) > 0 {
+		line += " " + strings.Join(args, " ")
+	}
+	s.Attempts = append(s.Attempts, Attempt{Command: line, Err: err})
+}
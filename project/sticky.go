@@ -0,0 +1,67 @@
+package project
+
+import (
+	"context"
+	"sync"
+)
+
+// sticky holds the source of the first project ID found with the
+// StickySource option. It is shared by all calls in the process.
+var sticky stickySource
+
+type stickySource struct {
+	mu     sync.Mutex
+	source string
+}
+
+func (s *stickySource) get() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source
+}
+
+// set remembers source, unless one is remembered already.
+func (s *stickySource) set(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.source == "" {
+		s.source = source
+	}
+}
+
+func (s *stickySource) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source = ""
+}
+
+// stickyProjectID searches the searchers like firstProjectID, with the
+// StickySource option: the searcher of the remembered source alone, when
+// it's in ss, then, if it finds nothing, the others.
+func stickyProjectID(ctx context.Context, o Options, ss []Searcher) (
+	id string, winner Searcher, misses []error, err error,
+) {
+	source := sticky.get()
+	for i, s := range ss {
+		if source == "" || sourceOf(s) != source {
+			continue
+		}
+		id, winner, misses, err = firstProjectID(ctx, o, ss[i:i+1])
+		if winner != nil || err != nil {
+			return id, winner, misses, err
+		}
+		missed := misses
+		rest := append(append([]Searcher(nil), ss[:i]...), ss[i+1:]...)
+		id, winner, misses, err = firstProjectID(ctx, o, rest)
+		if winner == nil && err == nil {
+			misses = append(missed, misses...)
+		}
+		return id, winner, misses, err
+	}
+
+	id, winner, misses, err = firstProjectID(ctx, o, ss)
+	if winner != nil {
+		sticky.set(sourceOf(winner))
+	}
+	return id, winner, misses, err
+}
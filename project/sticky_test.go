@@ -0,0 +1,130 @@
+package project
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve_StickySource(t *testing.T) {
+	env := newNamedSearcherMock("env", "")
+	config := newNamedSearcherMock("gcloud-config", "config-id")
+	SetSearchersForTest(t, env, config)
+	o := Options{StickySource: true}
+
+	steps := []struct {
+		name       string
+		env        string
+		config     string
+		wantID     string
+		wantSource string
+	}{
+		{
+			name:       "First resolution",
+			config:     "config-id",
+			wantID:     "config-id",
+			wantSource: "gcloud-config",
+		},
+		{
+			name:       "Earlier source appears",
+			env:        "env-id",
+			config:     "config-id",
+			wantID:     "config-id",
+			wantSource: "gcloud-config",
+		},
+		{
+			name:       "Value changes",
+			env:        "env-id",
+			config:     "config-id-2",
+			wantID:     "config-id-2",
+			wantSource: "gcloud-config",
+		},
+		{
+			name:       "Source disappears",
+			env:        "env-id",
+			wantID:     "env-id",
+			wantSource: "env",
+		},
+		{
+			name:       "Source reappears",
+			env:        "env-id",
+			config:     "config-id",
+			wantID:     "config-id",
+			wantSource: "gcloud-config",
+		},
+	}
+	for _, step := range steps {
+		env.projectID, config.projectID = step.env, step.config
+
+		r := resolve(context.Background(), o)
+
+		require.NoError(t, r.Err, step.name)
+		assert.Equal(t, step.wantID, r.ID, step.name)
+		assert.Equal(t, step.wantSource, r.Source, step.name)
+	}
+}
+
+func TestResolve_StickySource_Disabled(t *testing.T) {
+	env := newNamedSearcherMock("env", "")
+	config := newNamedSearcherMock("gcloud-config", "config-id")
+	SetSearchersForTest(t, env, config)
+
+	r := resolve(context.Background(), Options{})
+	require.NoError(t, r.Err)
+	assert.Equal(t, "config-id", r.ID)
+
+	env.projectID = "env-id"
+	r = resolve(context.Background(), Options{})
+	require.NoError(t, r.Err)
+	assert.Equal(t, "env-id", r.ID)
+}
+
+func TestResolve_StickySource_NotFound(t *testing.T) {
+	env := newNamedSearcherMock("env", "env-id")
+	config := newNamedSearcherMock("gcloud-config", "")
+	SetSearchersForTest(t, env, config)
+	o := Options{StickySource: true, Strict: true}
+
+	r := resolve(context.Background(), o)
+	require.NoError(t, r.Err)
+	assert.Equal(t, "env-id", r.ID)
+
+	env.projectID = ""
+	r = resolve(context.Background(), o)
+	assert.ErrorIs(t, r.Err, ErrProjectIDNotFound)
+	assert.Equal(t, "", r.ID)
+}
+
+func TestResolve_StickySource_Error(t *testing.T) {
+	config := newNamedSearcherMock("gcloud-config", "config-id")
+	other := newNamedSearcherMock("gcloud", "gcloud-id")
+	SetSearchersForTest(t, config, other)
+	o := Options{StickySource: true}
+
+	r := resolve(context.Background(), o)
+	require.NoError(t, r.Err)
+	assert.Equal(t, "config-id", r.ID)
+
+	config.wantError = true
+	r = resolve(context.Background(), o)
+	assert.ErrorIs(t, r.Err, errTest)
+}
+
+func TestResolver_StickySource_Hermetic(t *testing.T) {
+	env := newNamedSearcherMock("env", "")
+	config := newNamedSearcherMock("gcloud-config", "config-id")
+	rv := NewHermetic(env, config)
+	rv.opts.StickySource = true
+
+	id, err := rv.ID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "config-id", id)
+
+	env.projectID = "env-id"
+	id, err = rv.ID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "env-id", id)
+	assert.Equal(t, "", sticky.get())
+}
@@ -0,0 +1,27 @@
+package project
+
+import "testing"
+
+// SetSearchersForTest replaces the default search strategies with ss for
+// the duration of the test, so code calling ID and the other entry points
+// can be tested without Google Cloud access, and clears the cache and the
+// StickySource. The defaults are restored, and both cleared again, when
+// the test ends.
+//
+// The Searchers option still takes precedence. It changes package state,
+// so it must not be used in parallel tests; a Resolver from NewHermetic
+// can be used there instead.
+func SetSearchersForTest(tb testing.TB, ss ...Searcher) {
+	tb.Helper()
+	old := searchers
+	searchers = func(Options) []Searcher {
+		return append([]Searcher(nil), ss...)
+	}
+	cache.clear()
+	sticky.clear()
+	tb.Cleanup(func() {
+		searchers = old
+		cache.clear()
+		sticky.clear()
+	})
+}
@@ -0,0 +1,43 @@
+package project
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetSearchersForTest(t *testing.T) {
+	t.Run("Stubbed", func(t *testing.T) {
+		SetSearchersForTest(t, newNamedSearcherMock("stub", "gcp-id-test"))
+
+		r, err := Resolve(context.Background(), Options{Timeout: time.Second})
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", r.ID)
+		assert.Equal(t, "stub", r.Source)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		SetSearchersForTest(t)
+
+		_, err := IDContext(context.Background(), Options{Strict: true})
+
+		require.ErrorIs(t, err, ErrProjectIDNotFound)
+	})
+
+	t.Run("Cache cleared", func(t *testing.T) {
+		SetSearchersForTest(t, newNamedSearcherMock("stub", "gcp-id-first"))
+		opts := Options{Timeout: time.Second, CacheTTL: time.Hour}
+		require.Equal(t, "gcp-id-first", ID(opts))
+
+		SetSearchersForTest(t, newNamedSearcherMock("stub", "gcp-id-second"))
+
+		assert.Equal(t, "gcp-id-second", ID(opts))
+	})
+
+	ss := searchers(Options{})
+	assert.Equal(t, len(DefaultSearchers(Options{})), len(ss), "restored")
+}
@@ -0,0 +1,33 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultUniverseDomain is the universe domain of the public Google Cloud,
+// assumed when a source doesn't tell its own.
+const defaultUniverseDomain = "googleapis.com"
+
+// ErrUniverseDomainMismatch is returned (wrapped) when the UniverseDomain
+// option is set and a source that provides the project ID belongs to a
+// different universe.
+var ErrUniverseDomainMismatch = errors.New("universe domain mismatch")
+
+// checkUniverseDomain returns an error wrapping ErrUniverseDomainMismatch
+// if got, the universe domain of source, isn't want. An empty want accepts
+// any universe, and an empty got is the default universe.
+func checkUniverseDomain(want, got, source string) error {
+	if want == "" {
+		return nil
+	}
+	if got = strings.TrimSpace(got); got == "" {
+		got = defaultUniverseDomain
+	}
+	if !strings.EqualFold(got, strings.TrimSpace(want)) {
+		return fmt.Errorf("%w: %s is in %q, expected %q",
+			ErrUniverseDomainMismatch, source, got, want)
+	}
+	return nil
+}
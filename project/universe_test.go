@@ -0,0 +1,240 @@
+package project
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func Test_checkUniverseDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		got     string
+		wantErr bool
+	}{
+		{name: "Not checked", want: "", got: "example-cloud.goog"},
+		{name: "Match", want: "example-cloud.goog", got: "example-cloud.goog"},
+		{name: "Case-insensitive", want: "Example-Cloud.goog", got: "example-cloud.goog"},
+		{name: "Default", want: "googleapis.com", got: ""},
+		{name: "Mismatch", want: "example-cloud.goog", got: "googleapis.com", wantErr: true},
+		{name: "Default mismatch", want: "example-cloud.goog", got: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkUniverseDomain(tt.want, tt.got, "test")
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrUniverseDomainMismatch)
+				assert.Contains(t, err.Error(), tt.want)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_credentialsFileSearcher_ProjectID_UniverseDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		universe string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "Match",
+			content:  `{"project_id":"gcp-id-test","universe_domain":"example-cloud.goog"}`,
+			universe: "example-cloud.goog",
+			want:     "gcp-id-test",
+		},
+		{
+			name:     "Mismatch",
+			content:  `{"project_id":"gcp-id-test","universe_domain":"googleapis.com"}`,
+			universe: "example-cloud.goog",
+			wantErr:  true,
+		},
+		{
+			name:     "Default universe",
+			content:  `{"project_id":"gcp-id-test"}`,
+			universe: "googleapis.com",
+			want:     "gcp-id-test",
+		},
+		{
+			name:     "Not checked",
+			content:  `{"project_id":"gcp-id-test","universe_domain":"example-cloud.goog"}`,
+			universe: "",
+			want:     "gcp-id-test",
+		},
+		{
+			name:     "No project ID",
+			content:  `{"universe_domain":"googleapis.com"}`,
+			universe: "example-cloud.goog",
+			want:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "sa.json")
+			require.NoError(t, os.WriteFile(file, []byte(tt.content), 0o600))
+			t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+
+			got, err := newCredentialsFileSearcher(tt.universe).ProjectID(
+				context.Background())
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrUniverseDomainMismatch)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_credentialsSearcher_ProjectID_UniverseDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		provided string
+		universe string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Match", provided: "example-cloud.goog", universe: "example-cloud.goog", want: "gcp-id-test"},
+		{name: "Mismatch", provided: "googleapis.com", universe: "example-cloud.goog", wantErr: true},
+		{name: "Default universe", provided: "", universe: "googleapis.com", want: "gcp-id-test"},
+		{name: "Not checked", provided: "example-cloud.goog", universe: "", want: "gcp-id-test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCredentialsSearcher(func(context.Context, ...string) (
+				*google.Credentials, error,
+			) {
+				return &google.Credentials{
+					ProjectID: "gcp-id-test",
+					UniverseDomainProvider: func() (string, error) {
+						return tt.provided, nil
+					},
+				}, nil
+			}, tt.universe)
+
+			got, err := s.ProjectID(context.Background())
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrUniverseDomainMismatch)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_metadataSearcher_ProjectID_UniverseDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    int
+		provided string
+		universe string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "Match",
+			entry:    http.StatusOK,
+			provided: "example-cloud.goog",
+			universe: "example-cloud.goog",
+			want:     "gcp-id-test",
+		},
+		{
+			name:     "Mismatch",
+			entry:    http.StatusOK,
+			provided: "googleapis.com",
+			universe: "example-cloud.goog",
+			wantErr:  true,
+		},
+		{
+			name:     "No entry",
+			entry:    http.StatusNotFound,
+			universe: "googleapis.com",
+			want:     "gcp-id-test",
+		},
+		{
+			name:     "No entry mismatch",
+			entry:    http.StatusNotFound,
+			universe: "example-cloud.goog",
+			wantErr:  true,
+		},
+		{
+			name:     "Server error",
+			entry:    http.StatusInternalServerError,
+			universe: "example-cloud.goog",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/computeMetadata/v1/project/project-id":
+					_, _ = w.Write([]byte("gcp-id-test"))
+				case "/computeMetadata/v1/universe/universe-domain":
+					w.WriteHeader(tt.entry)
+					_, _ = w.Write([]byte(tt.provided))
+				default:
+					http.NotFound(w, r)
+				}
+			})
+
+			got, err := newMetadataSearcher(Options{UniverseDomain: tt.universe}).ProjectID(
+				context.Background())
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Not checked", func(t *testing.T) {
+		var paths []string
+		useMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			_, _ = w.Write([]byte("gcp-id-test"))
+		})
+
+		got, err := newMetadataSearcher(Options{}).ProjectID(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-test", got)
+		assert.Equal(t, []string{"/computeMetadata/v1/project/project-id"}, paths)
+	})
+}
+
+func TestResolve_UniverseDomainMismatch(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	t.Setenv("GOOGLE_CREDENTIALS_BASE64", base64.StdEncoding.EncodeToString(
+		[]byte(`{"project_id":"gcp-id-test","universe_domain":"googleapis.com"}`),
+	))
+	opts := Options{
+		Timeout:              time.Second,
+		CredentialsBase64Env: "GOOGLE_CREDENTIALS_BASE64",
+		UniverseDomain:       "example-cloud.goog",
+	}
+
+	_, err := Resolve(context.Background(), opts)
+
+	require.ErrorIs(t, err, ErrUniverseDomainMismatch)
+	assert.Contains(t, err.Error(), "GOOGLE_CREDENTIALS_BASE64")
+}
@@ -0,0 +1,52 @@
+package project
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// decodeWindowsOutput returns the output of a command decoded to UTF-8
+// when it's UTF-16, as on some Windows setups, like PowerShell redirecting
+// the output of gcloud.cmd. UTF-16 is told by its byte order mark or, for
+// ASCII text without one, by every other byte being zero. Other output is
+// returned as is. It's only for Windows, where the UTF-16 output happens:
+// elsewhere, zero bytes are left for the validation to reject.
+func decodeWindowsOutput(b []byte) []byte {
+	if goos != "windows" || len(b) < 2 || len(b)%2 != 0 {
+		return b
+	}
+	var order binary.ByteOrder
+	switch {
+	case b[0] == 0xFF && b[1] == 0xFE:
+		order, b = binary.LittleEndian, b[2:]
+	case b[0] == 0xFE && b[1] == 0xFF:
+		order, b = binary.BigEndian, b[2:]
+	case zeroEvery(b, 1):
+		order = binary.LittleEndian
+	case zeroEvery(b, 0):
+		order = binary.BigEndian
+	default:
+		return b
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = order.Uint16(b[2*i:])
+	}
+	var out bytes.Buffer
+	for _, r := range utf16.Decode(u) {
+		out.WriteRune(r)
+	}
+	return out.Bytes()
+}
+
+// zeroEvery reports whether the bytes of b at offset in each pair are all
+// zero, while the others aren't.
+func zeroEvery(b []byte, offset int) bool {
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i+offset] != 0 || b[i+1-offset] == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package project
+
+import (
+	"context"
+	"encoding/binary"
+	"os/exec"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_decodeWindowsOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		goos string
+		in   []byte
+		want string
+	}{
+		{
+			name: "UTF-16LE with BOM",
+			goos: "windows",
+			in:   encodeUTF16(binary.LittleEndian, "\ufeffgcp-id-test\r\n"),
+			want: "gcp-id-test\r\n",
+		},
+		{
+			name: "UTF-16BE with BOM",
+			goos: "windows",
+			in:   encodeUTF16(binary.BigEndian, "\ufeffgcp-id-test\r\n"),
+			want: "gcp-id-test\r\n",
+		},
+		{
+			name: "UTF-16LE without BOM",
+			goos: "windows",
+			in:   encodeUTF16(binary.LittleEndian, "gcp-id-test\r\n"),
+			want: "gcp-id-test\r\n",
+		},
+		{
+			name: "UTF-16BE without BOM",
+			goos: "windows",
+			in:   encodeUTF16(binary.BigEndian, "gcp-id-test"),
+			want: "gcp-id-test",
+		},
+		{
+			name: "UTF-8",
+			goos: "windows",
+			in:   []byte("gcp-id-test\r\n"),
+			want: "gcp-id-test\r\n",
+		},
+		{
+			name: "Odd length",
+			goos: "windows",
+			in:   []byte("gcp-id-test"),
+			want: "gcp-id-test",
+		},
+		{
+			name: "Empty",
+			goos: "windows",
+			in:   nil,
+			want: "",
+		},
+		{
+			name: "Not on Windows",
+			goos: "linux",
+			in:   encodeUTF16(binary.LittleEndian, "gcp-id-test"),
+			want: string(encodeUTF16(binary.LittleEndian, "gcp-id-test")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replace(t, &goos, tt.goos)
+
+			got := decodeWindowsOutput(tt.in)
+
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_gcloudSearcher_ProjectID_UTF16(t *testing.T) {
+	replace(t, &goos, "windows")
+	s := &gcloudSearcher{
+		executables: []string{"gcloud"},
+		output: func(*exec.Cmd, int) ([]byte, error) {
+			return encodeUTF16(binary.LittleEndian, "\ufeffgcp-id-test\r\n"), nil
+		},
+	}
+
+	got, err := s.ProjectID(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", got)
+}
+
+// encodeUTF16 encodes s to UTF-16 in the byte order.
+func encodeUTF16(order binary.ByteOrder, s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, v := range u {
+		order.PutUint16(b[2*i:], v)
+	}
+	return b
+}
@@ -0,0 +1,168 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidProjectID is returned (wrapped) by ValidateProjectID when the
+// value is not a well-formed project ID.
+var ErrInvalidProjectID = errors.New("invalid project ID")
+
+// ErrDomainScopedID is returned (wrapped), along with ErrInvalidProjectID,
+// by ValidateProjectID when the value is a legacy domain-scoped project ID,
+// like "example.com:my-project", which some APIs reject. Use
+// SplitDomainScopedID, or the StripDomain option, to get its project part.
+var ErrDomainScopedID = errors.New("domain-scoped project ID")
+
+// ErrInvalidProjectNumber is returned (wrapped) by ValidateProjectNumber
+// when the value is not a canonical project number.
+var ErrInvalidProjectNumber = errors.New("invalid project number")
+
+// maxValueLen bounds the length of values accepted from untrusted sources,
+// like the `gcloud` output or environment variables.
+const maxValueLen = 128
+
+var projectIDPattern = regexp.MustCompile(`^[a-z][-a-z0-9]{4,28}[a-z0-9]$`)
+
+// ValidateProjectID reports whether id is a well-formed Google Cloud project
+// ID: 6 to 30 lowercase letters, digits or hyphens, starting with a letter
+// and not ending with a hyphen. The returned error wraps
+// ErrInvalidProjectID and, for legacy domain-scoped project IDs whose
+// project part is well-formed, ErrDomainScopedID.
+func ValidateProjectID(id string) error {
+	if domain, project := SplitDomainScopedID(id); domain != "" &&
+		projectIDPattern.MatchString(project) {
+		return fmt.Errorf("%w: %w: %q",
+			ErrInvalidProjectID, ErrDomainScopedID, truncate(id))
+	}
+	if !projectIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidProjectID, truncate(id))
+	}
+	return nil
+}
+
+// ValidateProjectNumber reports whether s is a canonical Google Cloud
+// project number: a positive decimal integer that fits an int64, without
+// sign, leading zeros or spaces, as returned in Result.Number. The returned
+// error wraps ErrInvalidProjectNumber.
+func ValidateProjectNumber(s string) error {
+	if canonicalProjectNumber(s) != s || s == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidProjectNumber, truncate(s))
+	}
+	return nil
+}
+
+// canonicalProjectNumber returns the project number in v as a decimal
+// string with no leading zeros, so the sources agree on its format whether
+// they provide a bare string, like the metadata server, or a JSON number or
+// string. It returns an empty string if v is not a positive number.
+func canonicalProjectNumber(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+	if v == "" || strings.TrimLeft(v, "0123456789") != "" {
+		return ""
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// SplitDomainScopedID splits a legacy domain-scoped project ID, like
+// "example.com:my-project", into its domain and project parts. For IDs that
+// are not domain-scoped, domain is empty and project is the whole id.
+func SplitDomainScopedID(id string) (domain, project string) {
+	domain, project, ok := strings.Cut(id, ":")
+	if !ok {
+		return "", id
+	}
+	return domain, project
+}
+
+// resourcePrefix starts the resource names of projects.
+const resourcePrefix = "projects/"
+
+// StripResourcePrefix returns the project ID or number of a project
+// resource name, like "my-project" for "projects/my-project" or "123456"
+// for "projects/123456/locations/global". Values without the leading
+// "projects/" prefix are returned unchanged.
+func StripResourcePrefix(s string) string {
+	rest, ok := strings.CutPrefix(s, resourcePrefix)
+	if !ok {
+		return s
+	}
+	project, _, _ := strings.Cut(rest, "/")
+	return project
+}
+
+// gcloudUnset is what `gcloud config get-value` prints for unset properties.
+const gcloudUnset = "(unset)"
+
+// parseGCloudOutput extracts the project ID from the `gcloud` output. It
+// uses the last non-empty line, since gcloud may print notices before the
+// value, and discards values that can't possibly be a project ID, as well
+// as the "(unset)" marker.
+func parseGCloudOutput(b []byte) string {
+	lines := strings.Split(string(b), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if strings.EqualFold(line, gcloudUnset) {
+			return ""
+		}
+		if line != "" {
+			return sanitizeValue(line)
+		}
+	}
+	return ""
+}
+
+// parseGCloudOutputStrict extracts the project ID from the `gcloud` output
+// like parseGCloudOutput, but only accepts output that is a single line
+// holding a valid project ID. Anything else, like notices printed along
+// with the value, yields an empty string.
+func parseGCloudOutputStrict(b []byte) string {
+	line := strings.TrimSpace(string(b))
+	if strings.ContainsAny(line, "\r\n") || ValidateProjectID(line) != nil {
+		return ""
+	}
+	return line
+}
+
+// normalizeEnvValue trims whitespace and a pair of matching quotes, as left
+// by some env file loaders, and discards values that can't possibly be a
+// project ID.
+func normalizeEnvValue(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		v = strings.TrimSpace(v[1 : len(v)-1])
+	}
+	return sanitizeValue(v)
+}
+
+// sanitizeValue returns v if it only has printable, non-space ASCII
+// characters and is not too long. Otherwise, it returns an empty string.
+func sanitizeValue(v string) string {
+	if len(v) > maxValueLen {
+		return ""
+	}
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c <= ' ' || c > '~' {
+			return ""
+		}
+	}
+	return v
+}
+
+func truncate(s string) string {
+	if len(s) > maxValueLen {
+		return s[:maxValueLen] + "..."
+	}
+	return s
+}
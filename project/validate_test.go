@@ -0,0 +1,533 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{name: "Valid", id: "gcp-id-test", wantErr: false},
+		{name: "Valid with digits", id: "project-123", wantErr: false},
+		{name: "Minimum length", id: "abcdef", wantErr: false},
+		{name: "Maximum length", id: "a" + strings.Repeat("b", 29), wantErr: false},
+		{name: "Empty", id: "", wantErr: true},
+		{name: "Too short", id: "abcde", wantErr: true},
+		{name: "Too long", id: "a" + strings.Repeat("b", 30), wantErr: true},
+		{name: "Starts with digit", id: "1project", wantErr: true},
+		{name: "Ends with hyphen", id: "project-", wantErr: true},
+		{name: "Uppercase", id: "My-Project", wantErr: true},
+		{name: "Underscore", id: "my_project", wantErr: true},
+		{name: "Whitespace", id: " my-project", wantErr: true},
+		{name: "Domain-scoped", id: "example.com:gcp-id-test", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProjectID(tt.id)
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidProjectID)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestValidateProjectID_DomainScoped(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           string
+		domainScoped bool
+	}{
+		{name: "Domain-scoped", id: "example.com:gcp-id-test", domainScoped: true},
+		{name: "Invalid project part", id: "example.com:Not Valid", domainScoped: false},
+		{name: "Empty domain", id: ":gcp-id-test", domainScoped: false},
+		{name: "Not domain-scoped", id: "Not Valid", domainScoped: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProjectID(tt.id)
+
+			require.ErrorIs(t, err, ErrInvalidProjectID)
+			assert.Equal(t, tt.domainScoped, errors.Is(err, ErrDomainScopedID))
+		})
+	}
+}
+
+func TestID_ValidateDomainScoped(t *testing.T) {
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{newNamedSearcherMock("gcloud", "example.com:gcp-id-test")}
+	})
+
+	_, err := IDContext(context.Background(), Options{
+		Timeout:  time.Second,
+		Validate: true,
+	})
+
+	require.ErrorIs(t, err, ErrDomainScopedID)
+	assert.Contains(t, err.Error(), "example.com:gcp-id-test")
+
+	_, project := SplitDomainScopedID("example.com:gcp-id-test")
+	id, err := IDContext(context.Background(), Options{
+		Timeout:  time.Second,
+		Validate: true,
+		Explicit: project,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-id-test", id)
+}
+
+func TestValidateProjectNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		wantErr bool
+	}{
+		{name: "Valid", number: "123456789012", wantErr: false},
+		{name: "Single digit", number: "1", wantErr: false},
+		{name: "Empty", number: "", wantErr: true},
+		{name: "Zero", number: "0", wantErr: true},
+		{name: "Leading zeros", number: "0123", wantErr: true},
+		{name: "Sign", number: "+123", wantErr: true},
+		{name: "Negative", number: "-123", wantErr: true},
+		{name: "Whitespace", number: " 123", wantErr: true},
+		{name: "Quoted", number: `"123"`, wantErr: true},
+		{name: "Letters", number: "12a", wantErr: true},
+		{name: "Overflow", number: "9223372036854775808", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProjectNumber(tt.number)
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidProjectNumber)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_canonicalProjectNumber(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Canonical", value: "123", want: "123"},
+		{name: "Metadata server", value: "123\n", want: "123"},
+		{name: "JSON string", value: `"123"`, want: "123"},
+		{name: "Leading zeros", value: "000123", want: "123"},
+		{name: "Zero", value: "000", want: ""},
+		{name: "Sign", value: "+123", want: ""},
+		{name: "Not a number", value: "gcp-id-test", want: ""},
+		{name: "Fraction", value: "123.0", want: ""},
+		{name: "Empty", value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := canonicalProjectNumber(tt.value)
+
+			assert.Equal(t, tt.want, got)
+			if got != "" {
+				assert.NoError(t, ValidateProjectNumber(got))
+			}
+		})
+	}
+}
+
+func TestResolve_NumberCanonical(t *testing.T) {
+	unsetEnv(t, gcloudProjectPropertyKey)
+	unsetEnv(t, defaultEnvKeys...)
+	sources := []numberSearcherMock{
+		{projectID: "gcp-id-test", number: "0123"},
+		{projectID: "gcp-id-test", number: `"123"`},
+		{projectID: "gcp-id-test", number: "123\n"},
+	}
+	for _, s := range sources {
+		useSearchers(t, func(Options) []Searcher { return []Searcher{s} })
+
+		r, err := Resolve(context.Background(), Options{Timeout: time.Second})
+
+		require.NoError(t, err)
+		assert.Equal(t, "123", r.Number, "number %q", s.number)
+	}
+}
+
+type numberSearcherMock struct {
+	projectID string
+	number    string
+}
+
+var _ Searcher = numberSearcherMock{}
+
+func (s numberSearcherMock) ProjectID(context.Context, ...string) (string, error) {
+	return s.projectID, nil
+}
+
+func (s numberSearcherMock) projectNumber() string { return s.number }
+
+func TestID_Validate(t *testing.T) {
+	useSearchers(t, func(Options) []Searcher {
+		return []Searcher{&searcherMock{projectID: "Not A Project"}}
+	})
+
+	assert.NotPanics(t, func() { ID(Options{}) })
+	assert.Panics(t, func() { ID(Options{Validate: true}) })
+}
+
+func TestID_Validator(t *testing.T) {
+	errPrefix := errors.New("missing team prefix")
+	teamPrefix := func(id string) error {
+		if !strings.HasPrefix(id, "team-") {
+			return errPrefix
+		}
+		return nil
+	}
+	tests := []struct {
+		name    string
+		id      string
+		opts    Options
+		wantErr error
+	}{
+		{
+			name: "Accepted",
+			id:   "team-gcp-id-test",
+			opts: Options{Validator: teamPrefix},
+		},
+		{
+			name:    "Rejected",
+			id:      "gcp-id-test",
+			opts:    Options{Validator: teamPrefix},
+			wantErr: errPrefix,
+		},
+		{
+			name:    "Validate runs first",
+			id:      "Not_Valid",
+			opts:    Options{Validate: true, Validator: teamPrefix},
+			wantErr: ErrInvalidProjectID,
+		},
+		{
+			name:    "Both pass, then the validator rejects",
+			id:      "other-gcp-id",
+			opts:    Options{Validate: true, Validator: teamPrefix},
+			wantErr: errPrefix,
+		},
+		{
+			name: "Not called without a project ID",
+			id:   "",
+			opts: Options{Validator: func(string) error {
+				t.Error("validator called")
+				return nil
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{&searcherMock{projectID: tt.id}}
+			})
+			tt.opts.Timeout = time.Second
+
+			got, err := IDContext(context.Background(), tt.opts)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				assert.Panics(t, func() { ID(tt.opts) })
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, got)
+		})
+	}
+}
+
+func TestSplitDomainScopedID(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		wantDomain  string
+		wantProject string
+	}{
+		{
+			name:        "Domain-scoped",
+			id:          "example.com:gcp-id-test",
+			wantDomain:  "example.com",
+			wantProject: "gcp-id-test",
+		},
+		{
+			name:        "Plain",
+			id:          "gcp-id-test",
+			wantDomain:  "",
+			wantProject: "gcp-id-test",
+		},
+		{
+			name:        "Empty",
+			id:          "",
+			wantDomain:  "",
+			wantProject: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, project := SplitDomainScopedID(tt.id)
+
+			assert.Equal(t, tt.wantDomain, domain)
+			assert.Equal(t, tt.wantProject, project)
+		})
+	}
+}
+
+func TestID_StripDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		stripDomain bool
+		want        string
+	}{
+		{
+			name:        "Domain-scoped",
+			id:          "example.com:gcp-id-test",
+			stripDomain: true,
+			want:        "gcp-id-test",
+		},
+		{
+			name:        "Domain-scoped, option disabled",
+			id:          "example.com:gcp-id-test",
+			stripDomain: false,
+			want:        "example.com:gcp-id-test",
+		},
+		{
+			name:        "Plain",
+			id:          "gcp-id-test",
+			stripDomain: true,
+			want:        "gcp-id-test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{&searcherMock{projectID: tt.id}}
+			})
+
+			got := ID(Options{StripDomain: tt.stripDomain})
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStripResourcePrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Prefixed ID", value: "projects/gcp-id-test", want: "gcp-id-test"},
+		{name: "Prefixed number", value: "projects/123456", want: "123456"},
+		{name: "Resource name", value: "projects/gcp-id-test/locations/global", want: "gcp-id-test"},
+		{name: "Plain ID", value: "gcp-id-test", want: "gcp-id-test"},
+		{name: "Plain number", value: "123456", want: "123456"},
+		{name: "Other prefix", value: "folders/123456", want: "folders/123456"},
+		{name: "Prefix only", value: "projects/", want: ""},
+		{name: "Empty", value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, StripResourcePrefix(tt.value))
+		})
+	}
+}
+
+func TestID_StripResourcePrefix(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		stripPrefix bool
+		stripDomain bool
+		want        string
+	}{
+		{
+			name:        "Prefixed ID",
+			id:          "projects/gcp-id-test",
+			stripPrefix: true,
+			want:        "gcp-id-test",
+		},
+		{
+			name:        "Prefixed number",
+			id:          "projects/123456",
+			stripPrefix: true,
+			want:        "123456",
+		},
+		{
+			name:        "Prefixed ID, option disabled",
+			id:          "projects/gcp-id-test",
+			stripPrefix: false,
+			want:        "projects/gcp-id-test",
+		},
+		{
+			name:        "Prefixed domain-scoped",
+			id:          "projects/example.com:gcp-id-test",
+			stripPrefix: true,
+			stripDomain: true,
+			want:        "gcp-id-test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useSearchers(t, func(Options) []Searcher {
+				return []Searcher{&searcherMock{projectID: tt.id}}
+			})
+
+			got := ID(Options{
+				StripResourcePrefix: tt.stripPrefix,
+				StripDomain:         tt.stripDomain,
+			})
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseGCloudOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "Plain", output: "gcp-id-test", want: "gcp-id-test"},
+		{name: "Trailing newline", output: "gcp-id-test\n", want: "gcp-id-test"},
+		{name: "CRLF", output: "gcp-id-test\r\n", want: "gcp-id-test"},
+		{
+			name:   "Notice before the value",
+			output: "Updates are available.\ngcp-id-test\n\n",
+			want:   "gcp-id-test",
+		},
+		{name: "Empty", output: "", want: ""},
+		{name: "Unset", output: "(unset)\n", want: ""},
+		{name: "Unset, uppercase", output: "(UNSET)", want: ""},
+		{name: "Unset, padded", output: "  (Unset) \r\n\n", want: ""},
+		{name: "Only whitespace", output: " \n\t\n", want: ""},
+		{name: "Spaces inside the value", output: "not a project\n", want: ""},
+		{name: "Control characters", output: "gcp\x00id", want: ""},
+		{name: "Too long", output: strings.Repeat("a", maxValueLen+1), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGCloudOutput([]byte(tt.output))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseGCloudOutputStrict(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "Plain", output: "gcp-id-test", want: "gcp-id-test"},
+		{name: "Trailing newline", output: "gcp-id-test\n", want: "gcp-id-test"},
+		{name: "CRLF", output: "gcp-id-test\r\n", want: "gcp-id-test"},
+		{
+			name:   "Notice before the value",
+			output: "Updates are available.\ngcp-id-test\n",
+			want:   "",
+		},
+		{
+			name:   "Survey after the value",
+			output: "gcp-id-test\n\nTo take a quick anonymous survey, run:\n",
+			want:   "",
+		},
+		{name: "Empty", output: "", want: ""},
+		{name: "Unset", output: "(unset)\n", want: ""},
+		{name: "Not a project ID", output: "My_Project\n", want: ""},
+		{name: "Domain-scoped", output: "example.com:gcp-id-test\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGCloudOutputStrict([]byte(tt.output))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_normalizeEnvValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Plain", value: "gcp-id-test", want: "gcp-id-test"},
+		{name: "Whitespace", value: "  gcp-id-test\n", want: "gcp-id-test"},
+		{name: "Double quotes", value: `"gcp-id-test"`, want: "gcp-id-test"},
+		{name: "Single quotes", value: `'gcp-id-test'`, want: "gcp-id-test"},
+		{name: "Mismatched quotes", value: `"gcp-id-test'`, want: `"gcp-id-test'`},
+		{name: "Only quotes", value: `""`, want: ""},
+		{name: "Spaces inside the value", value: "gcp id", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeEnvValue(tt.value)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func FuzzResolveInputs(f *testing.F) {
+	seeds := []string{
+		"",
+		"gcp-id-test",
+		"gcp-id-test\n",
+		"Updates are available.\ngcp-id-test\n",
+		`"gcp-id-test"`,
+		"example.com:gcp-id-test",
+		"(unset)",
+		"\x00\xff\r\n",
+		strings.Repeat("a", 1024),
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s := string(b)
+
+		values := []string{
+			parseGCloudOutput(b), parseGCloudOutputStrict(b), normalizeEnvValue(s),
+		}
+		for _, v := range values {
+			if v == "" {
+				continue
+			}
+			if len(v) > maxValueLen {
+				t.Fatalf("value too long: %d bytes", len(v))
+			}
+			if !utf8.ValidString(v) || strings.ContainsAny(v, " \t\r\n") {
+				t.Fatalf("unsanitized value: %q", v)
+			}
+		}
+
+		if err := ValidateProjectID(s); err == nil {
+			if got := parseGCloudOutput(append(b, '\n')); got != s {
+				t.Fatalf("valid project ID %q parsed as %q", s, got)
+			}
+			if got := normalizeEnvValue(s); got != s {
+				t.Fatalf("valid project ID %q normalized as %q", s, got)
+			}
+		}
+	})
+}
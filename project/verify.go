@@ -0,0 +1,39 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/oauth2/google"
+)
+
+// ErrProjectMismatch is returned (wrapped) when the VerifyAgainstCredentials
+// option is set and the project ID found is not the project of the
+// application default credentials.
+var ErrProjectMismatch = errors.New("project ID mismatch")
+
+// verifyAgainstCredentials checks that id, found by source, is the project
+// of the application default credentials, as recorded in found when the
+// credentials searcher ran, or found again otherwise. Credentials without
+// a project, like user credentials, can't disagree.
+func verifyAgainstCredentials(
+	ctx context.Context, o Options, found *foundCredentials, id, source string,
+) error {
+	c := found.get()
+	if c == nil {
+		find := o.FindCredentials
+		if find == nil {
+			find = google.FindDefaultCredentials
+		}
+		var err error
+		if c, err = find(ctx, o.Scopes...); err != nil {
+			return fmt.Errorf("verify against credentials: find credentials: %w", err)
+		}
+	}
+	if c.ProjectID == "" || c.ProjectID == id {
+		return nil
+	}
+	return fmt.Errorf("%w: %s has %q, the credentials have %q",
+		ErrProjectMismatch, source, id, c.ProjectID)
+}
@@ -0,0 +1,137 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/google"
+)
+
+func TestResolve_VerifyAgainstCredentials(t *testing.T) {
+	errFind := errors.New("find error")
+	tests := []struct {
+		name      string
+		env       string
+		credsID   string
+		findErr   error
+		want      string
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "Match",
+			env:       "gcp-id-test",
+			credsID:   "gcp-id-test",
+			want:      "gcp-id-test",
+			wantCalls: 1,
+		},
+		{
+			name:      "Mismatch",
+			env:       "gcp-id-env",
+			credsID:   "gcp-id-credentials",
+			wantErr:   ErrProjectMismatch,
+			wantCalls: 1,
+		},
+		{
+			name:      "Credentials without a project",
+			env:       "gcp-id-env",
+			credsID:   "",
+			want:      "gcp-id-env",
+			wantCalls: 1,
+		},
+		{
+			name:      "From the credentials",
+			env:       "",
+			credsID:   "gcp-id-credentials",
+			want:      "gcp-id-credentials",
+			wantCalls: 1,
+		},
+		{
+			name:      "No credentials",
+			env:       "gcp-id-env",
+			findErr:   errFind,
+			wantErr:   errFind,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("__GCP_PROJECT_ID_TEST__", tt.env)
+			var calls int
+			find := func(_ context.Context, scopes ...string) (*google.Credentials, error) {
+				calls++
+				assert.Equal(t, []string{"scope-a"}, scopes)
+				if tt.findErr != nil {
+					return nil, tt.findErr
+				}
+				return &google.Credentials{ProjectID: tt.credsID}, nil
+			}
+			useSearchers(t, func(o Options) []Searcher {
+				return []Searcher{
+					newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__"),
+					newCredentialsSearcher(o.FindCredentials, ""),
+				}
+			})
+
+			r, err := Resolve(context.Background(), Options{
+				Timeout:                  time.Second,
+				Scopes:                   []string{"scope-a"},
+				FindCredentials:          find,
+				VerifyAgainstCredentials: true,
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, r.ID)
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+
+	t.Run("Mismatch names both", func(t *testing.T) {
+		t.Setenv("__GCP_PROJECT_ID_TEST__", "gcp-id-env")
+		find := func(context.Context, ...string) (*google.Credentials, error) {
+			return &google.Credentials{ProjectID: "gcp-id-credentials"}, nil
+		}
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__")}
+		})
+
+		_, err := Resolve(context.Background(), Options{
+			Timeout:                  time.Second,
+			FindCredentials:          find,
+			VerifyAgainstCredentials: true,
+		})
+
+		require.ErrorIs(t, err, ErrProjectMismatch)
+		assert.ErrorContains(t, err, `"gcp-id-env"`)
+		assert.ErrorContains(t, err, `"gcp-id-credentials"`)
+	})
+
+	t.Run("Not verified by default", func(t *testing.T) {
+		t.Setenv("__GCP_PROJECT_ID_TEST__", "gcp-id-env")
+		var calls int
+		find := func(context.Context, ...string) (*google.Credentials, error) {
+			calls++
+			return &google.Credentials{ProjectID: "gcp-id-credentials"}, nil
+		}
+		useSearchers(t, func(Options) []Searcher {
+			return []Searcher{newEnvironmentSearcher("__GCP_PROJECT_ID_TEST__")}
+		})
+
+		r, err := Resolve(context.Background(), Options{
+			Timeout:         time.Second,
+			FindCredentials: find,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "gcp-id-env", r.ID)
+		assert.Zero(t, calls)
+	})
+}